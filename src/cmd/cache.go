@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package cmd contains the CLI commands for maru.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/defenseunicorns/maru-runner/src/config/lang"
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// cleanOlderThan limits maru cache clean to entries that have not been used for this long
+var cleanOlderThan time.Duration
+
+var cacheCmd = &cobra.Command{
+	Use: "cache COMMAND",
+	PersistentPreRun: func(_ *cobra.Command, _ []string) {
+		exitOnInterrupt()
+		cliSetup()
+	},
+	Short: lang.CmdCacheShort,
+	Run: func(cmd *cobra.Command, _ []string) {
+		_, _ = fmt.Fprintln(os.Stderr)
+		err := cmd.Help()
+		if err != nil {
+			message.Fatalf(err, "error calling help command")
+		}
+	},
+}
+
+var cacheLsCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	PersistentPreRun: func(_ *cobra.Command, _ []string) {
+		exitOnInterrupt()
+		cliSetup()
+	},
+	Short: lang.CmdCacheLsShort,
+	Args:  cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		entries, err := utils.ListCache()
+		if err != nil {
+			message.Fatalf(err, "Unable to list the cache: %s", err.Error())
+		}
+
+		if len(entries) == 0 {
+			dir, _ := utils.CacheDir()
+			message.SLog.Info(fmt.Sprintf(lang.CmdCacheEmpty, dir))
+			return
+		}
+
+		var total int64
+		rows := [][]string{{"Kind", "URL", "Size", "Last Used"}}
+		for _, entry := range entries {
+			total += entry.Size
+			rows = append(rows, []string{entry.Kind, entry.URL, utils.FormatByteSize(entry.Size), entry.LastUsed.Format(time.RFC3339)})
+		}
+		rows = append(rows, []string{"", "Total", utils.FormatByteSize(total), ""})
+
+		err = pterm.DefaultTable.WithHasHeader().WithData(rows).Render()
+		if err != nil {
+			message.Fatalf(err, "Unable to render the cache table: %s", err.Error())
+		}
+	},
+}
+
+var cacheCleanCmd = &cobra.Command{
+	Use: "clean",
+	PersistentPreRun: func(_ *cobra.Command, _ []string) {
+		exitOnInterrupt()
+		cliSetup()
+	},
+	Short: lang.CmdCacheCleanShort,
+	Args:  cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		removed, freed, err := utils.CleanCache(cleanOlderThan)
+		if err != nil {
+			message.Fatalf(err, "Unable to clean the cache: %s", err.Error())
+		}
+		message.SLog.Info(fmt.Sprintf(lang.CmdCacheCleanSuccess, removed, utils.FormatByteSize(freed)))
+	},
+}
+
+func init() {
+	initViper()
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheLsCmd)
+	cacheCmd.AddCommand(cacheCleanCmd)
+	cacheCleanCmd.Flags().DurationVar(&cleanOlderThan, "older-than", 0, lang.CmdCacheCleanOlderFlag)
+}
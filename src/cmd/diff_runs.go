@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package cmd contains the CLI commands for maru.
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/defenseunicorns/maru-runner/src/config/lang"
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/runner"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var diffRunsCmd = &cobra.Command{
+	Use: "diff-runs [RUN_ID RUN_ID]",
+	PersistentPreRun: func(_ *cobra.Command, _ []string) {
+		exitOnInterrupt()
+		cliSetup()
+	},
+	Short: lang.CmdDiffRunsShort,
+	Long:  lang.CmdDiffRunsLong,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 && len(args) != 2 {
+			return fmt.Errorf(lang.CmdDiffRunsErrArgs, len(args))
+		}
+		return nil
+	},
+	Run: func(_ *cobra.Command, args []string) {
+		if len(args) == 0 {
+			listRuns()
+			return
+		}
+
+		runA, err := runner.LoadRun(args[0])
+		if err != nil {
+			message.Fatalf(err, "Unable to load run: %s", err.Error())
+		}
+		runB, err := runner.LoadRun(args[1])
+		if err != nil {
+			message.Fatalf(err, "Unable to load run: %s", err.Error())
+		}
+
+		printRunDiff(runner.DiffRuns(runA, runB))
+	},
+}
+
+// listRuns prints the runs in the run history, most recent first
+func listRuns() {
+	ids, err := runner.ListRuns()
+	if err != nil {
+		message.Fatalf(err, "Unable to list runs: %s", err.Error())
+	}
+	if len(ids) == 0 {
+		message.SLog.Info(lang.CmdDiffRunsNoRuns)
+		return
+	}
+
+	rows := [][]string{{"Run ID", "Task", "Status", "Actions"}}
+	for i := len(ids) - 1; i >= 0; i-- {
+		run, err := runner.LoadRun(ids[i])
+		if err != nil {
+			message.SLog.Warn(err.Error())
+			continue
+		}
+		rows = append(rows, []string{run.RunID, run.Task, string(run.Status), fmt.Sprint(len(run.Actions))})
+	}
+	if err := pterm.DefaultTable.WithHasHeader().WithData(rows).Render(); err != nil {
+		message.Fatalf(err, "Unable to render the runs table: %s", err.Error())
+	}
+}
+
+// printRunDiff prints the actions and variables that differ between two runs
+func printRunDiff(diff runner.RunDiff) {
+	for i, run := range diff.Runs {
+		message.SLog.Info(fmt.Sprintf("Run %c: %s (task %s) %s", 'A'+i, run.RunID, run.Task, run.Status))
+	}
+
+	rows := [][]string{{"Task", "Action", "Name", "Status", "Duration", "Changes"}}
+	changed := []runner.ActionDiff{}
+	for _, action := range diff.Actions {
+		changes := []string{}
+		if action.StatusChanged() {
+			changes = append(changes, "status")
+		}
+		if action.OutputChanged() {
+			changes = append(changes, "output")
+		}
+		if len(changes) > 0 {
+			changed = append(changed, action)
+		}
+		rows = append(rows, []string{
+			action.Task,
+			fmt.Sprint(action.Action),
+			action.Name,
+			compareValues(runStatus(action.Status[0]), runStatus(action.Status[1])),
+			compareValues(runDuration(action.Status[0], action.Duration[0]), runDuration(action.Status[1], action.Duration[1])),
+			strings.Join(changes, ", "),
+		})
+	}
+	if err := pterm.DefaultTable.WithHasHeader().WithData(rows).Render(); err != nil {
+		message.Fatalf(err, "Unable to render the actions table: %s", err.Error())
+	}
+
+	for _, action := range changed {
+		pterm.Println()
+		message.SLog.Info(fmt.Sprintf("%s[%d] %s", action.Task, action.Action, action.Name))
+		for i := range diff.Runs {
+			detail := runStatus(action.Status[i])
+			if action.Reason[i] != "" {
+				detail = fmt.Sprintf("%s: %s", detail, action.Reason[i])
+			}
+			pterm.Printfln("Run %c (%s):", 'A'+i, detail)
+			if action.Output[i] != "" {
+				pterm.Println(action.Output[i])
+			}
+		}
+	}
+
+	if len(diff.Variables) == 0 {
+		return
+	}
+	pterm.Println()
+	rows = [][]string{{"Variable", "Run A", "Run B"}}
+	for _, variable := range diff.Variables {
+		values := [2]string{}
+		for i := range values {
+			values[i] = variable.Value[i]
+			if !variable.Set[i] {
+				values[i] = "(not set)"
+			}
+		}
+		rows = append(rows, []string{variable.Name, values[0], values[1]})
+	}
+	if err := pterm.DefaultTable.WithHasHeader().WithData(rows).Render(); err != nil {
+		message.Fatalf(err, "Unable to render the variables table: %s", err.Error())
+	}
+}
+
+// compareValues shows a value that is the same in both runs once, or both values if they differ
+func compareValues(a, b string) string {
+	if a == b {
+		return a
+	}
+	return fmt.Sprintf("%s -> %s", a, b)
+}
+
+func runStatus(status runner.ActionStatus) string {
+	if status == "" {
+		return "not reached"
+	}
+	return string(status)
+}
+
+func runDuration(status runner.ActionStatus, seconds float64) string {
+	if status == "" {
+		return "-"
+	}
+	return fmt.Sprintf("%.1fs", seconds)
+}
+
+func init() {
+	initViper()
+	rootCmd.AddCommand(diffRunsCmd)
+}
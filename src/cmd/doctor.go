@@ -0,0 +1,186 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package cmd contains the CLI commands for maru.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/config/lang"
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/runner"
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
+	"github.com/defenseunicorns/pkg/helpers/v2"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// knownOptions are the options that can be set in a maru-config.yaml
+var knownOptions = []string{V_LOG_LEVEL, V_ARCHITECTURE, V_NO_PROGRESS, V_NO_LOG_FILE, V_TMP_DIR, V_AUTH, V_CACHE_DIR, V_CACHE_MAX_SIZE, V_RUN_HISTORY, V_ARTIFACTS_DIR, V_ON_CHANGE, V_OFFLINE, V_SHELL_STRICT_PROLOGUE, V_SHELL_STRICT_PROLOGUE_POWERSHELL}
+
+var doctorCmd = &cobra.Command{
+	Use: "doctor",
+	PersistentPreRun: func(_ *cobra.Command, _ []string) {
+		exitOnInterrupt()
+		cliSetup()
+	},
+	Short: lang.CmdDoctorShort,
+	Long:  lang.CmdDoctorLong,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		checks := configChecks()
+		if tasksFile, err := loadTasksFile(); err != nil {
+			checks = append(checks, runner.DoctorCheck{Name: "tasks file", Status: runner.DoctorFail, Detail: err.Error(), Fix: lang.CmdDoctorNoTasksFile})
+		} else {
+			checks = append(checks, runner.Doctor(cmd.Context(), tasksFile, setRunnerVariables, v.GetStringMapString(V_AUTH))...)
+		}
+
+		rows := [][]string{{"Check", "Status", "Detail"}}
+		fixes := []string{}
+		failed, warned := 0, 0
+		for _, check := range checks {
+			status := pterm.Green(check.Status)
+			switch check.Status {
+			case runner.DoctorFail:
+				failed++
+				status = pterm.Red(check.Status)
+			case runner.DoctorWarn:
+				warned++
+				status = pterm.Yellow(check.Status)
+			}
+			rows = append(rows, []string{check.Name, status, check.Detail})
+			if check.Fix != "" {
+				fixes = append(fixes, fmt.Sprintf("%s: %s", check.Name, check.Fix))
+			}
+		}
+
+		err := pterm.DefaultTable.WithHasHeader().WithData(rows).Render()
+		if err != nil {
+			message.Fatalf(err, "Unable to render the doctor table: %s", err.Error())
+		}
+
+		if len(fixes) > 0 {
+			message.SLog.Info(lang.CmdDoctorFixes)
+			for _, fix := range fixes {
+				message.SLog.Info(fmt.Sprintf("  - %s", fix))
+			}
+		}
+
+		switch {
+		case failed > 0:
+			message.Fatalf(nil, lang.CmdDoctorErrFailed, failed, len(checks))
+		case warned > 0:
+			message.SLog.Warn(fmt.Sprintf(lang.CmdDoctorWarnings, len(checks)-warned, warned))
+		default:
+			message.SLog.Info(fmt.Sprintf(lang.CmdDoctorSuccess, len(checks)))
+		}
+	},
+}
+
+// configChecks checks that the maru config was loaded and that its options are valid
+func configChecks() []runner.DoctorCheck {
+	checks := []runner.DoctorCheck{}
+
+	configCheck := runner.DoctorCheck{Name: "config", Status: runner.DoctorOK, Detail: "no maru-config.yaml found, using the defaults"}
+	if vConfigError != nil {
+		if _, ok := vConfigError.(viper.ConfigFileNotFoundError); !ok {
+			configCheck.Status = runner.DoctorFail
+			configCheck.Detail = vConfigError.Error()
+			configCheck.Fix = "fix the config file (or point MARU_CONFIG at a different one)"
+		}
+	} else {
+		configCheck.Detail = fmt.Sprintf("using %s", v.ConfigFileUsed())
+		for _, key := range v.AllKeys() {
+			if !slices.Contains(knownOptions, key) && !strings.HasPrefix(key, V_AUTH+".") {
+				configCheck.Status = runner.DoctorWarn
+				configCheck.Detail = fmt.Sprintf("%s has an unknown option %s", v.ConfigFileUsed(), key)
+				configCheck.Fix = fmt.Sprintf("remove or correct the option (the known options are %s)", strings.Join(knownOptions, ", "))
+				break
+			}
+		}
+	}
+	checks = append(checks, configCheck)
+
+	if !slices.Contains([]string{"error", "warn", "info", "debug", "trace"}, logLevelString) {
+		checks = append(checks, runner.DoctorCheck{
+			Name:   "log level",
+			Status: runner.DoctorWarn,
+			Detail: fmt.Sprintf("%q is not a log level", logLevelString),
+			Fix:    "set options.log_level (or --log-level) to one of error, warn, info, debug or trace",
+		})
+	}
+
+	if _, err := utils.ParseByteSize(v.GetString(V_CACHE_MAX_SIZE)); err != nil {
+		checks = append(checks, runner.DoctorCheck{
+			Name:   "cache max size",
+			Status: runner.DoctorWarn,
+			Detail: err.Error(),
+			Fix:    "set options.cache_max_size (or MARU_CACHE_MAX_SIZE) to a size such as 500MB or 1GiB",
+		})
+	}
+
+	if config.RunHistory < 0 {
+		checks = append(checks, runner.DoctorCheck{
+			Name:   "run history",
+			Status: runner.DoctorWarn,
+			Detail: fmt.Sprintf("%d is negative so runs are not recorded", config.RunHistory),
+			Fix:    "set options.run_history (or --run-history) to the number of runs to record or to 0 (the default) to not record runs",
+		})
+	}
+
+	if config.TempDirectory != "" {
+		if err := checkWritableDir(config.TempDirectory, false); err != nil {
+			checks = append(checks, runner.DoctorCheck{
+				Name:   "temp directory",
+				Status: runner.DoctorFail,
+				Detail: err.Error(),
+				Fix:    "create the directory or set options.tmp_dir (or --tmpdir) to a writable directory",
+			})
+		}
+	}
+
+	cacheDir, err := utils.CacheDir()
+	if err == nil {
+		err = checkWritableDir(cacheDir, true)
+	}
+	if err != nil {
+		checks = append(checks, runner.DoctorCheck{
+			Name:   "cache directory",
+			Status: runner.DoctorWarn,
+			Detail: err.Error(),
+			Fix:    "set options.cache_dir (or --cache-dir) to a writable directory so remote includes can be cached",
+		})
+	} else {
+		checks = append(checks, runner.DoctorCheck{Name: "cache directory", Status: runner.DoctorOK, Detail: cacheDir})
+	}
+	return checks
+}
+
+// checkWritableDir checks that files can be written to a directory (creating it first if create is true)
+func checkWritableDir(dir string, create bool) error {
+	if create {
+		if err := helpers.CreateDirectory(dir, helpers.ReadWriteExecuteUser); err != nil {
+			return err
+		}
+	}
+	file, err := os.CreateTemp(dir, "maru-doctor-")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+func init() {
+	initViper()
+	rootCmd.AddCommand(doctorCmd)
+	doctorFlags := doctorCmd.Flags()
+	doctorFlags.StringVarP(&config.TaskFileLocation, "file", "f", config.TasksYAML, lang.CmdRunFlag)
+	doctorFlags.StringToStringVar(&setRunnerVariables, "set", nil, lang.CmdRunSetVarFlag)
+}
@@ -31,6 +31,10 @@ var configTasksSchemaCmd = &cobra.Command{
 	},
 	Run: func(_ *cobra.Command, _ []string) {
 		schema := jsonschema.Reflect(&types.TasksFile{})
+		// The value of a patch operation is not omitted when empty (so it can be null) but only add, replace and test use it
+		if op, ok := schema.Definitions["JSONPatchOperation"]; ok {
+			op.Required = []string{"op", "path"}
+		}
 		output, err := json.MarshalIndent(schema, "", "  ")
 		if err != nil {
 			message.Fatalf(err, "%s", lang.CmdInternalConfigSchemaErr)
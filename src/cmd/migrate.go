@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package cmd contains the CLI commands for maru.
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/config/lang"
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/migrate"
+	"github.com/spf13/cobra"
+)
+
+var migrateCheck bool
+
+var migrateCmd = &cobra.Command{
+	Use: "migrate",
+	PersistentPreRun: func(_ *cobra.Command, _ []string) {
+		exitOnInterrupt()
+		cliSetup()
+	},
+	Short: lang.CmdMigrateShort,
+	Long:  lang.CmdMigrateLong,
+	Args:  cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		changes, err := migrate.File(config.TaskFileLocation, migrateCheck)
+		if err != nil {
+			message.Fatalf(err, "Unable to migrate %s: %s", config.TaskFileLocation, err.Error())
+		}
+		for _, change := range changes {
+			message.SLog.Info(change.String())
+		}
+
+		switch {
+		case len(changes) == 0:
+			message.SLog.Info(fmt.Sprintf(lang.CmdMigrateNothing, config.TaskFileLocation))
+		case migrateCheck:
+			message.Fatalf(nil, lang.CmdMigrateErrCheck, len(changes), config.TaskFileLocation, config.TaskFileLocation)
+		default:
+			message.SLog.Info(fmt.Sprintf(lang.CmdMigrateSuccess, len(changes), config.TaskFileLocation))
+		}
+	},
+}
+
+func init() {
+	initViper()
+	rootCmd.AddCommand(migrateCmd)
+	migrateFlags := migrateCmd.Flags()
+	migrateFlags.StringVarP(&config.TaskFileLocation, "file", "f", config.TasksYAML, lang.CmdRunFlag)
+	migrateFlags.BoolVar(&migrateCheck, "check", false, lang.CmdMigrateCheckFlag)
+}
@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package cmd contains the CLI commands for maru.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/config/lang"
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/scaffold"
+	"github.com/spf13/cobra"
+)
+
+var (
+	taskLibName       string
+	wrapperVersion    string
+	wrapperReleaseURL string
+)
+
+var newCmd = &cobra.Command{
+	Use: "new COMMAND",
+	PersistentPreRun: func(_ *cobra.Command, _ []string) {
+		exitOnInterrupt()
+		cliSetup()
+	},
+	Short: lang.CmdNewShort,
+	Run: func(cmd *cobra.Command, _ []string) {
+		_, _ = fmt.Fprintln(os.Stderr)
+		err := cmd.Help()
+		if err != nil {
+			message.Fatalf(err, "error calling help command")
+		}
+	},
+}
+
+var newTaskLibCmd = &cobra.Command{
+	Use: "task-lib [DIRECTORY]",
+	PersistentPreRun: func(_ *cobra.Command, _ []string) {
+		exitOnInterrupt()
+		cliSetup()
+	},
+	Short: lang.CmdNewTaskLibShort,
+	Long:  lang.CmdNewTaskLibLong,
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+
+		name := taskLibName
+		if name == "" {
+			abs, err := filepath.Abs(dir)
+			if err != nil {
+				message.Fatalf(err, "Unable to create the task library: %s", err.Error())
+			}
+			name = strings.ToLower(filepath.Base(abs))
+		}
+
+		created, err := scaffold.NewTaskLib(dir, name)
+		if err != nil {
+			message.Fatalf(err, "Unable to create the task library: %s", err.Error())
+		}
+		for _, file := range created {
+			message.SLog.Debug(fmt.Sprintf("Created %s", file))
+		}
+		message.SLog.Info(fmt.Sprintf(lang.CmdNewTaskLibSuccess, name, filepath.Join(dir, "tests", "tasks.yaml")))
+	},
+}
+
+var newWrapperCmd = &cobra.Command{
+	Use: "wrapper [DIRECTORY]",
+	PersistentPreRun: func(_ *cobra.Command, _ []string) {
+		exitOnInterrupt()
+		cliSetup()
+	},
+	Short: lang.CmdNewWrapperShort,
+	Long:  lang.CmdNewWrapperLong,
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+
+		created, err := scaffold.NewWrapper(cmd.Context(), dir, wrapperVersion, wrapperReleaseURL)
+		if err != nil {
+			message.Fatalf(err, "Unable to create the wrapper: %s", err.Error())
+		}
+		for _, file := range created {
+			message.SLog.Debug(fmt.Sprintf("Wrote %s", file))
+		}
+		version := wrapperVersion
+		if version == "" {
+			version = config.CLIVersion
+		}
+		message.SLog.Info(fmt.Sprintf(lang.CmdNewWrapperSuccess, version, filepath.Join(dir, scaffold.WrapperScript)))
+	},
+}
+
+func init() {
+	initViper()
+	rootCmd.AddCommand(newCmd)
+	newCmd.AddCommand(newTaskLibCmd)
+	newTaskLibCmd.Flags().StringVar(&taskLibName, "name", "", lang.CmdNewTaskLibNameFlag)
+
+	newCmd.AddCommand(newWrapperCmd)
+	newWrapperCmd.Flags().StringVar(&wrapperVersion, "version", "", lang.CmdNewWrapperVersionFlag)
+	newWrapperCmd.Flags().StringVar(&wrapperReleaseURL, "release-url", scaffold.DefaultReleaseURL, lang.CmdNewWrapperReleaseURLFlag)
+}
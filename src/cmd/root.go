@@ -5,6 +5,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
@@ -44,7 +45,9 @@ var rootCmd = &cobra.Command{
 
 // Execute is the entrypoint for the CLI.
 func Execute() {
-	cobra.CheckErr(rootCmd.Execute())
+	ctx, cancel := cancelOnInterrupt(context.Background())
+	defer cancel(nil)
+	cobra.CheckErr(rootCmd.ExecuteContext(ctx))
 }
 
 // RootCmd returns the root command.
@@ -59,11 +62,22 @@ func init() {
 	v.SetDefault(V_ARCHITECTURE, "")
 	v.SetDefault(V_NO_LOG_FILE, true)
 	v.SetDefault(V_TMP_DIR, "")
+	v.SetDefault(V_CACHE_DIR, "")
+	v.SetDefault(V_CACHE_MAX_SIZE, "1GiB")
+	v.SetDefault(V_RUN_HISTORY, 0)
+	v.SetDefault(V_OFFLINE, false)
 
 	rootCmd.PersistentFlags().StringVarP(&logLevelString, "log-level", "l", v.GetString(V_LOG_LEVEL), lang.RootCmdFlagLogLevel)
 	rootCmd.PersistentFlags().BoolVar(&message.NoProgress, "no-progress", v.GetBool(V_NO_PROGRESS), lang.RootCmdFlagNoProgress)
 	rootCmd.PersistentFlags().BoolVar(&skipLogFile, "no-log-file", v.GetBool(V_NO_LOG_FILE), lang.RootCmdFlagSkipLogFile)
 	rootCmd.PersistentFlags().StringVar(&config.TempDirectory, "tmpdir", v.GetString(V_TMP_DIR), lang.RootCmdFlagTempDir)
+	rootCmd.PersistentFlags().StringVar(&config.CacheDirectory, "cache-dir", v.GetString(V_CACHE_DIR), lang.RootCmdFlagCacheDir)
+	rootCmd.PersistentFlags().IntVar(&config.RunHistory, "run-history", v.GetInt(V_RUN_HISTORY), lang.RootCmdFlagRunHistory)
+	rootCmd.PersistentFlags().BoolVar(&config.Offline, "offline", v.GetBool(V_OFFLINE), lang.RootCmdFlagOffline)
+
+	// The shellStrict prologues are only set in the config file (or environment) as they are too unwieldy for a flag
+	config.ShellStrictPrologue = v.GetString(V_SHELL_STRICT_PROLOGUE)
+	config.ShellStrictPowerShellPrologue = v.GetString(V_SHELL_STRICT_PROLOGUE_POWERSHELL)
 }
 
 func cliSetup() {
@@ -101,6 +115,29 @@ func cliSetup() {
 	if os.Getenv("CI") == "true" {
 		message.NoProgress = true
 	}
+
+	cacheMaxBytes, err := utils.ParseByteSize(v.GetString(V_CACHE_MAX_SIZE))
+	if err != nil {
+		message.SLog.Warn(fmt.Sprintf(lang.RootCmdErrCacheMaxSize, err.Error()))
+	}
+	config.CacheMaxBytes = cacheMaxBytes
+}
+
+// cancelOnInterrupt returns a context that is cancelled with lang.ErrInterrupt when an interrupt is caught, allowing
+// commands that honor the context to stop gracefully (a second interrupt falls back to the default behavior)
+func cancelOnInterrupt(parent context.Context) (context.Context, context.CancelCauseFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-c:
+			cancel(lang.ErrInterrupt)
+		case <-ctx.Done():
+		}
+		signal.Stop(c)
+	}()
+	return ctx, cancel
 }
 
 // exitOnInterrupt catches an interrupt and exits with fatal error
@@ -5,11 +5,13 @@
 package cmd
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/defenseunicorns/maru-runner/src/config"
 	"github.com/defenseunicorns/maru-runner/src/config/lang"
@@ -58,39 +60,24 @@ var dryRun bool
 // setRunnerVariables provides a map of set variables from the command line
 var setRunnerVariables map[string]string
 
+// runTimeout is the overall time budget for a run (0 means no timeout)
+var runTimeout time.Duration
+
 var runCmd = &cobra.Command{
 	Use: "run",
 	PersistentPreRun: func(_ *cobra.Command, _ []string) {
-		exitOnInterrupt()
+		// interrupts are handled through the command context so running actions can be stopped gracefully
 		cliSetup()
 	},
 	Short:             lang.CmdRunShort,
 	ValidArgsFunction: ListAutoCompleteTasks,
 	Args:              cobra.MaximumNArgs(1),
-	Run: func(_ *cobra.Command, args []string) {
-		var tasksFile types.TasksFile
-
-		err := utils.ReadYaml(config.TaskFileLocation, &tasksFile)
+	Run: func(cmd *cobra.Command, args []string) {
+		tasksFile, err := loadTasksFile()
 		if err != nil {
 			message.Fatalf(err, "Failed to open file: %s", err.Error())
 		}
 
-		// ensure vars are uppercase
-		setRunnerVariables = helpers.TransformMapKeys(setRunnerVariables, strings.ToUpper)
-
-		// set any env vars that come from the environment (taking MARU_ over VENDOR_)
-		for _, variable := range tasksFile.Variables {
-			if _, ok := setRunnerVariables[variable.Name]; !ok {
-				if value := os.Getenv(fmt.Sprintf("%s_%s", strings.ToUpper(config.EnvPrefix), variable.Name)); value != "" {
-					setRunnerVariables[variable.Name] = value
-				} else if config.VendorPrefix != "" {
-					if value := os.Getenv(fmt.Sprintf("%s_%s", strings.ToUpper(config.VendorPrefix), variable.Name)); value != "" {
-						setRunnerVariables[variable.Name] = value
-					}
-				}
-			}
-		}
-
 		auth := v.GetStringMapString(V_AUTH)
 
 		listFormat := listTasks
@@ -101,7 +88,7 @@ var runCmd = &cobra.Command{
 		if listFormat != listOff {
 			rows := [][]string{}
 			for _, task := range tasksFile.Tasks {
-				rows = append(rows, []string{task.Name, task.Description})
+				rows = append(rows, taskRow(task.Name, task))
 			}
 
 			// If ListAllTasks, add tasks from included files
@@ -112,18 +99,32 @@ var runCmd = &cobra.Command{
 				}
 			}
 
+			// Only show the owner and links columns when a task has them
+			header := []string{"Name", "Description", "Owner", "Links"}
+			columns := 2
+			for _, row := range rows {
+				if row[2] != "" || row[3] != "" {
+					columns = len(header)
+				}
+			}
+
 			switch listFormat {
 			case listMd:
-				fmt.Println("| Name | Description |")
-				fmt.Println("|------|-------------|")
+				fmt.Printf("| %s |\n", strings.Join(header[:columns], " | "))
+				dividers := []string{}
+				for _, name := range header[:columns] {
+					dividers = append(dividers, strings.Repeat("-", len(name)+2))
+				}
+				fmt.Printf("|%s|\n", strings.Join(dividers, "|"))
 				for _, row := range rows {
-					if len(row) == 2 {
-						fmt.Printf("| **%s** | %s |\n", row[0], row[1])
-					}
+					fmt.Printf("| **%s** | %s |\n", row[0], strings.Join(row[1:columns], " | "))
 				}
 			default:
-				rows = append([][]string{{"Name", "Description"}}, rows...)
-				err := pterm.DefaultTable.WithHasHeader().WithData(rows).Render()
+				data := [][]string{header[:columns]}
+				for _, row := range rows {
+					data = append(data, row[:columns])
+				}
+				err := pterm.DefaultTable.WithHasHeader().WithData(data).Render()
 				if err != nil {
 					message.Fatalf(err, "Error listing tasks: %s", err.Error())
 				}
@@ -136,24 +137,62 @@ var runCmd = &cobra.Command{
 		if len(args) > 0 {
 			taskName = args[0]
 		}
-		if err := runner.Run(tasksFile, taskName, setRunnerVariables, dryRun, auth); err != nil {
+		ctx := cmd.Context()
+		if runTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeoutCause(ctx, runTimeout, fmt.Errorf("run timed out after %s", runTimeout))
+			defer cancel()
+		}
+		if err := runner.RunWithContext(ctx, tasksFile, taskName, setRunnerVariables, dryRun, auth); err != nil {
 			message.Fatalf(err, "Failed to run action: %s", err.Error())
 		}
 	},
 }
 
-// ListAutoCompleteTasks returns a list of all of the available tasks that can be run
-func ListAutoCompleteTasks(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+// loadTasksFile reads the tasks file (merged with any embedded tasks) and populates setRunnerVariables with any
+// variables set in the environment
+func loadTasksFile() (types.TasksFile, error) {
 	var tasksFile types.TasksFile
 
-	if _, err := os.Stat(config.TaskFileLocation); os.IsNotExist(err) {
-		return []string{}, cobra.ShellCompDirectiveNoFileComp
+	// An application with embedded tasks can run them without a tasks file on disk
+	if _, err := os.Stat(config.TaskFileLocation); !os.IsNotExist(err) || !runner.HasEmbeddedTasks() {
+		if err := utils.ReadYaml(config.TaskFileLocation, &tasksFile); err != nil {
+			return tasksFile, err
+		}
+	}
+	tasksFile = runner.MergeEmbeddedTasks(tasksFile)
+
+	// ensure vars are uppercase
+	setRunnerVariables = helpers.TransformMapKeys(setRunnerVariables, strings.ToUpper)
+
+	// set any env vars that come from the environment (taking MARU_ over VENDOR_)
+	for _, variable := range tasksFile.Variables {
+		if _, ok := setRunnerVariables[variable.Name]; !ok {
+			if value := os.Getenv(fmt.Sprintf("%s_%s", strings.ToUpper(config.EnvPrefix), variable.Name)); value != "" {
+				setRunnerVariables[variable.Name] = value
+			} else if config.VendorPrefix != "" {
+				if value := os.Getenv(fmt.Sprintf("%s_%s", strings.ToUpper(config.VendorPrefix), variable.Name)); value != "" {
+					setRunnerVariables[variable.Name] = value
+				}
+			}
+		}
 	}
 
-	err := utils.ReadYaml(config.TaskFileLocation, &tasksFile)
-	if err != nil {
+	return tasksFile, nil
+}
+
+// ListAutoCompleteTasks returns a list of all of the available tasks that can be run
+func ListAutoCompleteTasks(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	var tasksFile types.TasksFile
+
+	if _, err := os.Stat(config.TaskFileLocation); os.IsNotExist(err) && !runner.HasEmbeddedTasks() {
 		return []string{}, cobra.ShellCompDirectiveNoFileComp
+	} else if err == nil {
+		if err := utils.ReadYaml(config.TaskFileLocation, &tasksFile); err != nil {
+			return []string{}, cobra.ShellCompDirectiveNoFileComp
+		}
 	}
+	tasksFile = runner.MergeEmbeddedTasks(tasksFile)
 
 	var taskNames []string
 	for _, task := range tasksFile.Tasks {
@@ -162,6 +201,11 @@ func ListAutoCompleteTasks(_ *cobra.Command, _ []string, _ string) ([]string, co
 	return taskNames, cobra.ShellCompDirectiveNoFileComp
 }
 
+// taskRow returns the name, description, owner and links of a task for listing
+func taskRow(name string, task types.Task) []string {
+	return []string{name, task.Description, runner.TaskOwner(task), runner.TaskLinks(task)}
+}
+
 func listTasksFromIncludes(rows *[][]string, tasksFile types.TasksFile, auth map[string]string) error {
 	variableConfig := runner.GetMaruVariableConfig()
 	err := variableConfig.PopulateVariables(tasksFile.Variables, setRunnerVariables)
@@ -185,7 +229,7 @@ func listTasksFromIncludes(rows *[][]string, tasksFile types.TasksFile, auth map
 			}
 
 			for _, task := range includedTasksFile.Tasks {
-				*rows = append(*rows, []string{fmt.Sprintf("%s:%s", includeName, task.Name), task.Description})
+				*rows = append(*rows, taskRow(fmt.Sprintf("%s:%s", includeName, task.Name), task))
 			}
 		}
 	}
@@ -199,6 +243,13 @@ func init() {
 	runFlags := runCmd.Flags()
 	runFlags.StringVarP(&config.TaskFileLocation, "file", "f", config.TasksYAML, lang.CmdRunFlag)
 	runFlags.BoolVar(&dryRun, "dry-run", false, lang.CmdRunDryRun)
+	runFlags.DurationVar(&runTimeout, "timeout", 0, lang.CmdRunTimeoutFlag)
+	runFlags.StringVar(&config.ResultsFile, "results-file", "", lang.CmdRunResultsFlag)
+	runFlags.StringVar(&config.TraceFile, "trace-file", "", lang.CmdRunTraceFlag)
+	runFlags.StringVar(&config.Session, "session", "", lang.CmdRunSessionFlag)
+	runFlags.BoolVar(&config.Step, "step", false, lang.CmdRunStepFlag)
+	runFlags.StringVar(&config.ArtifactsDirectory, "artifacts-dir", v.GetString(V_ARTIFACTS_DIR), lang.CmdRunArtifactsDirFlag)
+	runFlags.StringVar(&config.OnChange, "on-change", v.GetString(V_ON_CHANGE), lang.CmdRunOnChangeFlag)
 
 	// Setup the --list flag
 	flag.Var(&listTasks, "list", lang.CmdRunList)
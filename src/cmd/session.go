@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package cmd contains the CLI commands for maru.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/defenseunicorns/maru-runner/src/config/lang"
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/runner"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var sessionCmd = &cobra.Command{
+	Use: "session COMMAND",
+	PersistentPreRun: func(_ *cobra.Command, _ []string) {
+		exitOnInterrupt()
+		cliSetup()
+	},
+	Short: lang.CmdSessionShort,
+	Run: func(cmd *cobra.Command, _ []string) {
+		_, _ = fmt.Fprintln(os.Stderr)
+		err := cmd.Help()
+		if err != nil {
+			message.Fatalf(err, "error calling help command")
+		}
+	},
+}
+
+var sessionLsCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	PersistentPreRun: func(_ *cobra.Command, _ []string) {
+		exitOnInterrupt()
+		cliSetup()
+	},
+	Short: lang.CmdSessionLsShort,
+	Args:  cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		names, err := runner.ListSessions()
+		if err != nil {
+			message.Fatalf(err, "Unable to list sessions: %s", err.Error())
+		}
+		if len(names) == 0 {
+			message.SLog.Info(lang.CmdSessionNone)
+			return
+		}
+
+		rows := [][]string{{"Session", "Variables"}}
+		for _, name := range names {
+			session, err := runner.LoadSession(name)
+			if err != nil {
+				message.SLog.Warn(err.Error())
+				continue
+			}
+			rows = append(rows, []string{name, strings.Join(sortedKeys(session), ", ")})
+		}
+		err = pterm.DefaultTable.WithHasHeader().WithData(rows).Render()
+		if err != nil {
+			message.Fatalf(err, "Unable to render the sessions table: %s", err.Error())
+		}
+	},
+}
+
+var sessionShowCmd = &cobra.Command{
+	Use: "show SESSION",
+	PersistentPreRun: func(_ *cobra.Command, _ []string) {
+		exitOnInterrupt()
+		cliSetup()
+	},
+	Short: lang.CmdSessionShowShort,
+	Args:  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		session, err := runner.LoadSession(args[0])
+		if err != nil {
+			message.Fatalf(err, "Unable to load session: %s", err.Error())
+		}
+		if len(session) == 0 {
+			message.SLog.Info(fmt.Sprintf(lang.CmdSessionEmpty, args[0]))
+			return
+		}
+
+		rows := [][]string{{"Variable", "Value"}}
+		for _, name := range sortedKeys(session) {
+			rows = append(rows, []string{name, session[name]})
+		}
+		err = pterm.DefaultTable.WithHasHeader().WithData(rows).Render()
+		if err != nil {
+			message.Fatalf(err, "Unable to render the session table: %s", err.Error())
+		}
+	},
+}
+
+var sessionRmCmd = &cobra.Command{
+	Use:     "rm SESSION",
+	Aliases: []string{"remove"},
+	PersistentPreRun: func(_ *cobra.Command, _ []string) {
+		exitOnInterrupt()
+		cliSetup()
+	},
+	Short: lang.CmdSessionRmShort,
+	Args:  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		if err := runner.RemoveSession(args[0]); err != nil {
+			message.Fatalf(err, "Unable to remove session: %s", err.Error())
+		}
+		message.SLog.Info(fmt.Sprintf(lang.CmdSessionRmSuccess, args[0]))
+	},
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func init() {
+	initViper()
+	rootCmd.AddCommand(sessionCmd)
+	sessionCmd.AddCommand(sessionLsCmd)
+	sessionCmd.AddCommand(sessionShowCmd)
+	sessionCmd.AddCommand(sessionRmCmd)
+}
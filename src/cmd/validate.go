@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package cmd contains the CLI commands for maru.
+package cmd
+
+import (
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/config/lang"
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+var unusedIncludes string
+
+var validateCmd = &cobra.Command{
+	Use: "validate",
+	PersistentPreRun: func(_ *cobra.Command, _ []string) {
+		exitOnInterrupt()
+		cliSetup()
+	},
+	Short: lang.CmdValidateShort,
+	Long:  lang.CmdValidateLong,
+	Args:  cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		tasksFile, err := loadTasksFile()
+		if err != nil {
+			message.Fatalf(err, "Failed to open file: %s", err.Error())
+		}
+
+		err = runner.Validate(tasksFile, setRunnerVariables, v.GetStringMapString(V_AUTH), runner.UnusedIncludes(unusedIncludes))
+		if err == nil {
+			message.SLog.Info(lang.CmdValidateSuccess)
+			return
+		}
+
+		// Report every problem (rather than only the first) so they can all be fixed in one pass
+		problems := []error{err}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			problems = joined.Unwrap()
+		}
+		for _, problem := range problems {
+			message.SLog.Error(problem.Error())
+		}
+		message.Fatalf(nil, lang.CmdValidateErrProblems, len(problems))
+	},
+}
+
+func init() {
+	initViper()
+	rootCmd.AddCommand(validateCmd)
+	validateFlags := validateCmd.Flags()
+	validateFlags.StringVarP(&config.TaskFileLocation, "file", "f", config.TasksYAML, lang.CmdRunFlag)
+	validateFlags.StringToStringVar(&setRunnerVariables, "set", nil, lang.CmdRunSetVarFlag)
+	validateFlags.StringVar(&unusedIncludes, "unused-includes", string(runner.UnusedIncludesWarn), lang.CmdValidateUnusedIncludesFlag)
+}
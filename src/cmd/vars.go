@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package cmd contains the CLI commands for maru.
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/config/lang"
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/runner"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var varsCmd = &cobra.Command{
+	Use: "vars",
+	PersistentPreRun: func(_ *cobra.Command, _ []string) {
+		exitOnInterrupt()
+		cliSetup()
+	},
+	Short: lang.CmdVarsShort,
+	Long:  lang.CmdVarsLong,
+	Args:  cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		tasksFile, err := loadTasksFile()
+		if err != nil {
+			message.Fatalf(err, "Failed to open file: %s", err.Error())
+		}
+
+		usages, err := runner.AnalyzeVariables(tasksFile, setRunnerVariables, v.GetStringMapString(V_AUTH))
+		if err != nil {
+			message.Fatalf(err, "Unable to analyze variables: %s", err.Error())
+		}
+
+		if len(usages) == 0 {
+			message.SLog.Info(lang.CmdVarsNone)
+			return
+		}
+
+		rows := [][]string{{"Name", "Kind", "Defined", "Defaulted", "Set", "Consumed", "Status"}}
+		problems := []string{}
+		for _, usage := range usages {
+			kind := string(usage.Kind)
+			description := fmt.Sprintf("variable %q", usage.Name)
+			if usage.Kind == runner.VariableKindInput {
+				kind = fmt.Sprintf("input of %s", usage.Task)
+				description = fmt.Sprintf("input %q of task %s", usage.Name, usage.Task)
+			}
+
+			status := "ok"
+			if usage.Undefined() {
+				status = "undefined"
+				problems = append(problems, fmt.Sprintf(lang.CmdVarsUndefined, description, strings.Join(append(usage.Set, usage.Consumed...), ", ")))
+			} else if usage.Unused() {
+				status = "unused"
+				problems = append(problems, fmt.Sprintf(lang.CmdVarsUnused, description))
+			}
+
+			rows = append(rows, []string{
+				usage.Name,
+				kind,
+				strings.Join(usage.Defined, "\n"),
+				strings.Join(usage.Defaulted, "\n"),
+				strings.Join(usage.Set, "\n"),
+				strings.Join(usage.Consumed, "\n"),
+				status,
+			})
+		}
+
+		err = pterm.DefaultTable.WithHasHeader().WithData(rows).Render()
+		if err != nil {
+			message.Fatalf(err, "Unable to render the variables table: %s", err.Error())
+		}
+
+		for _, problem := range problems {
+			message.SLog.Warn(problem)
+		}
+	},
+}
+
+func init() {
+	initViper()
+	rootCmd.AddCommand(varsCmd)
+	varsFlags := varsCmd.Flags()
+	varsFlags.StringVarP(&config.TaskFileLocation, "file", "f", config.TasksYAML, lang.CmdRunFlag)
+	varsFlags.StringToStringVar(&setRunnerVariables, "set", nil, lang.CmdRunSetVarFlag)
+}
@@ -17,12 +17,21 @@ import (
 
 const (
 	// Root config keys
-	V_LOG_LEVEL    = "options.log_level"
-	V_ARCHITECTURE = "options.architecture"
-	V_NO_PROGRESS  = "options.no_progress"
-	V_NO_LOG_FILE  = "options.no_log_file"
-	V_TMP_DIR      = "options.tmp_dir"
-	V_AUTH         = "options.auth"
+	V_LOG_LEVEL      = "options.log_level"
+	V_ARCHITECTURE   = "options.architecture"
+	V_NO_PROGRESS    = "options.no_progress"
+	V_NO_LOG_FILE    = "options.no_log_file"
+	V_TMP_DIR        = "options.tmp_dir"
+	V_AUTH           = "options.auth"
+	V_CACHE_DIR      = "options.cache_dir"
+	V_CACHE_MAX_SIZE = "options.cache_max_size"
+	V_RUN_HISTORY    = "options.run_history"
+	V_ARTIFACTS_DIR  = "options.artifacts_dir"
+	V_ON_CHANGE      = "options.on_change"
+	V_OFFLINE        = "options.offline"
+
+	V_SHELL_STRICT_PROLOGUE            = "options.shell_strict_prologue"
+	V_SHELL_STRICT_PROLOGUE_POWERSHELL = "options.shell_strict_prologue_powershell"
 )
 
 var (
@@ -29,6 +29,46 @@ var (
 	// TempDirectory is the directory to store temporary files
 	TempDirectory string
 
+	// CacheDirectory is the directory to cache remote files in (defaults to $HOME/.maru/cache)
+	CacheDirectory string
+
+	// CacheMaxBytes is the size the cache is kept under by evicting the least recently used entries (0 for no limit)
+	CacheMaxBytes int64
+
+	// ResultsFile is the file to write the JSON results of a run to (if set)
+	ResultsFile string
+
+	// TraceFile is the file to write a Chrome trace event (chrome://tracing) trace of a run to (if set)
+	TraceFile string
+
+	// ArtifactsDirectory is the directory to collect the artifacts of failed actions in (defaults to maru-artifacts)
+	ArtifactsDirectory string
+
+	// PolicyFile is the admin-level command policy that commands are checked against (defaults to /etc/maru/policy.yaml)
+	PolicyFile string
+
+	// OnChange is what to do when a tasks file changes during a run, warn (the default) or fail
+	OnChange string
+
+	// Step pauses before every action in a run so its variables can be inspected and changed
+	Step bool
+
+	// Session is the name of the session to load variables from and remember session variables in (if set)
+	Session string
+
+	// Offline stops maru making network requests of its own that would otherwise hang without network access (remote
+	// includes only come from the cache and waits for hosts that can't be resolved fail right away)
+	Offline bool
+
+	// ShellStrictPrologue replaces the prologue that shellStrict runs POSIX shell commands with (if set)
+	ShellStrictPrologue string
+
+	// ShellStrictPowerShellPrologue replaces the prologue that shellStrict runs PowerShell commands with (if set)
+	ShellStrictPowerShellPrologue string
+
+	// RunHistory is the number of runs to keep in the run history for maru diff-runs (0 to not record runs)
+	RunHistory int
+
 	// VendorPrefix is the prefix for environment variables that an application vendoring Maru wants to use
 	VendorPrefix string
 
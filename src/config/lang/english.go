@@ -23,6 +23,10 @@ const (
 	RootCmdErrInvalidLogLevel = "Invalid log level. Valid options are: error, warn, info, debug, trace."
 	RootCmdFlagArch           = "Architecture for the runner"
 	RootCmdFlagTempDir        = "Specify the temporary directory to use for intermediate files"
+	RootCmdFlagCacheDir       = "Specify the directory to cache remote files in (default $HOME/.maru/cache)"
+	RootCmdErrCacheMaxSize    = "Invalid cache max size, the cache will not be limited: %s"
+	RootCmdFlagRunHistory     = "Number of recent runs to record for maru diff-runs (defaults to 0, which does not record runs)"
+	RootCmdFlagOffline        = "Don't make network requests that may hang without network access: remote includes only come from the cache and waits for hosts that can't be resolved fail right away"
 )
 
 // Version
@@ -53,6 +57,86 @@ const (
 	CmdRunList        = "List available tasks in a task file"
 	CmdRunListAll     = "List all available tasks in a task file, including tasks from included files"
 	CmdRunDryRun      = "Validate the task without actually running any commands"
+	CmdRunTimeoutFlag = "Maximum duration for the whole run, e.g. 30m (default 0, no timeout)"
+	CmdRunResultsFlag = "Write the status (succeeded, skipped or failed) of every action in the run to the given JSON file"
+	CmdRunTraceFlag   = "Write a trace of every task and action in the run to the given JSON file in the Chrome trace event format (open it in chrome://tracing or ui.perfetto.dev)"
+	CmdRunSessionFlag = "Load variables from (and remember variables marked with 'session: true' in) the given named session"
+
+	CmdRunStepFlag         = "Pause before every action to inspect and change variables (print them with 'v', change them with 'set NAME=value')"
+	CmdRunArtifactsDirFlag = "Specify the directory to collect the artifacts of failed actions in (default maru-artifacts)"
+	CmdRunOnChangeFlag     = "What to do when the tasks file (or a local include) changes during the run: 'warn' and keep running the definitions the run started with, or 'fail' before the next task starts (default warn)"
+)
+
+// Validate
+const (
+	CmdValidateShort              = "Validates the task references in a task file and its includes"
+	CmdValidateLong               = "Loads a task file along with all of its includes and checks that every referenced task exists and that every 'with' key matches a declared input of the referenced task."
+	CmdValidateSuccess            = "No problems found"
+	CmdValidateErrProblems        = "Found %d problems in the task file"
+	CmdValidateUnusedIncludesFlag = "How to treat includes that no task references: warn, error or ignore"
+)
+
+// Migrate
+const (
+	CmdMigrateShort     = "Updates a task file that uses deprecated syntax"
+	CmdMigrateLong      = "Rewrites the lines of a task file that use deprecated syntax (i.e. an 'if' of the string \"false\" rather than the boolean false), keeping its comments and formatting. Includes are not migrated, run this against each local include that maru warns about."
+	CmdMigrateCheckFlag = "Only report the lines that would change, failing if there are any (i.e. in CI)"
+	CmdMigrateSuccess   = "Migrated %d lines of %s"
+	CmdMigrateNothing   = "Nothing to migrate in %s"
+	CmdMigrateErrCheck  = "Found %d lines to migrate in %s, run 'maru migrate -f %s' to update them"
+)
+
+// Vars
+const (
+	CmdVarsShort     = "Lists where the variables and task inputs in a task file are defined, set and used"
+	CmdVarsLong      = "Loads a task file along with all of its includes and, without running anything, lists every variable and task input along with where it is defined, defaulted, set and consumed, flagging any that are unused or undefined."
+	CmdVarsNone      = "No variables or task inputs found"
+	CmdVarsUndefined = "%s is never defined (referenced by %s)"
+	CmdVarsUnused    = "%s is never used"
+)
+
+// Doctor
+const (
+	CmdDoctorShort       = "Checks that this machine is set up to run the tasks in a task file"
+	CmdDoctorLong        = "Checks the maru config, that the task file is valid, that its remote includes can be reached, that the shells and tools on the PATH that its tasks use are installed and that the Kubernetes cluster can be reached, printing how to fix any problem it finds. Exits with an error if any check fails."
+	CmdDoctorFixes       = "How to fix the problems found:"
+	CmdDoctorErrFailed   = "%d of %d checks failed"
+	CmdDoctorSuccess     = "All %d checks passed"
+	CmdDoctorWarnings    = "%d checks passed with %d warnings"
+	CmdDoctorNoTasksFile = "create one (or run 'maru new task-lib' for a task library) or point to it with --file"
+)
+
+// New
+const (
+	CmdNewShort                 = "Creates the starting layout of new maru projects"
+	CmdNewTaskLibShort          = "Creates the layout of a shared task library (tasks, tests, docs, a release workflow and a lockfile)"
+	CmdNewTaskLibLong           = "Creates a shared task library in DIRECTORY (default the current directory) with a tasks.yaml holding the tasks it publishes, tests/tasks.yaml with a test for each task, a README.md, a maru.lock.yaml pinning the maru release the library is tested with and a GitHub workflow that tests the library and releases tasks.yaml along with its digest when a v* tag is pushed. Existing files are never overwritten."
+	CmdNewTaskLibNameFlag       = "The name of the library (default the name of the directory)"
+	CmdNewTaskLibSuccess        = "Created task library %s, run its tests with 'maru run -f %s'"
+	CmdNewWrapperShort          = "Creates (or updates) a maruw script that runs the maru version pinned by the project"
+	CmdNewWrapperLong           = "Creates a maruw script in DIRECTORY (default the current directory) along with a .maru-wrapper file pinning a maru version and the checksums of its release. Commit both so that everyone working on the project runs ./maruw, which downloads the pinned maru (verifying its checksum) the first time it is needed and runs it. Run this again with a new --version to update the pin."
+	CmdNewWrapperVersionFlag    = "The maru version to pin (default the version of this maru)"
+	CmdNewWrapperReleaseURLFlag = "The URL that maru releases are downloaded from (i.e. a mirror of the GitHub releases)"
+	CmdNewWrapperSuccess        = "Pinned maru %s, run it with '%s'"
+)
+
+// Session
+const (
+	CmdSessionShort     = "Commands for inspecting and removing the named sessions used with 'maru run --session'"
+	CmdSessionLsShort   = "Lists the sessions and the variables they remember"
+	CmdSessionShowShort = "Shows the values of the variables a session remembers"
+	CmdSessionRmShort   = "Removes a session"
+	CmdSessionNone      = "No sessions have been saved yet"
+	CmdSessionEmpty     = "Session %s does not remember any variables"
+	CmdSessionRmSuccess = "Removed session %s"
+)
+
+// Diff Runs
+const (
+	CmdDiffRunsShort   = "Compares two recorded runs (or lists the recorded runs when no runs are given)"
+	CmdDiffRunsLong    = "Compares the status, duration and output of every action and the final value of every variable between two runs. Runs are given by their ID (or a unique prefix of it) in the run history or as the path to a file written with 'maru run --results-file'."
+	CmdDiffRunsNoRuns  = "No runs have been recorded yet"
+	CmdDiffRunsErrArgs = "accepts either 0 or 2 runs, received %d"
 )
 
 // Auth
@@ -64,7 +148,18 @@ const (
 	CmdLogoutShort         = "[beta] Removes a token for a given host from your keyring"
 )
 
+// Cache
+const (
+	CmdCacheShort          = "Commands for inspecting and cleaning the cache of remote files"
+	CmdCacheLsShort        = "Lists the entries in the cache, most recently used first"
+	CmdCacheCleanShort     = "Removes entries from the cache"
+	CmdCacheCleanOlderFlag = "Only remove entries that have not been used for this long, e.g. 720h (default 0, remove everything)"
+	CmdCacheCleanSuccess   = "Removed %d cache entries (%s)"
+	CmdCacheEmpty          = "The cache at %s is empty"
+)
+
 // Common Errors
 var (
 	ErrInterrupt = errors.New("execution cancelled due to an interrupt")
+	ErrOffline   = errors.New("maru is offline (--offline)")
 )
@@ -20,6 +20,9 @@ const (
 
 // Fatalf prints a fatal error message and exits with a 1 with a given format.
 func Fatalf(err any, format string, a ...any) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
 	message := paragraph(format, a...)
 	debugPrinter(2, err)
 	errorPrinter(2).Println(message)
@@ -63,14 +66,14 @@ func infof(format string, a ...any) {
 
 // paragraph formats text into a paragraph matching the TermWidth
 func paragraph(format string, a ...any) string {
-	return pterm.DefaultParagraph.WithMaxWidth(termWidth).Sprintf(format, a...)
+	return pterm.DefaultParagraph.WithMaxWidth(termWidth).Sprint(Mask(fmt.Sprintf(format, a...)))
 }
 
 func debugPrinter(offset int, a ...any) {
 	printer := pterm.Debug.WithShowLineNumber(logLevel <= TraceLevel).WithLineNumberOffset(offset)
 	now := time.Now().Format(time.RFC3339)
 	// prepend to a
-	a = append([]any{now, " - "}, a...)
+	a = []any{now, " - ", Mask(fmt.Sprint(a...))}
 
 	printer.Println(a...)
 
@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package message provides a rich set of functions for displaying messages to the user.
+package message
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// SanitizedValue replaces sensitive values in everything that is printed (and in run results)
+const SanitizedValue = "**sanitized**"
+
+// minSensitiveLineLength is the length a sensitive value (or a line of a multi-line one) needs to be masked, so that
+// short values (i.e. a lone brace or a file holding "true") don't mask unrelated output
+const minSensitiveLineLength = 8
+
+var (
+	sensitiveMu sync.RWMutex
+	// sensitive are the values masked in everything that is printed, longest first so a value that contains another is
+	// masked whole
+	sensitive []string
+)
+
+// AddSensitive marks a value (i.e. the contents of a secret file) as sensitive so it is masked in everything that is
+// printed from then on. Each line of a multi-line value is also masked on its own since output is printed a line at a
+// time. Values shorter than 8 characters are not masked.
+func AddSensitive(value string) {
+	values := []string{strings.TrimSpace(value)}
+	if len(values[0]) < minSensitiveLineLength {
+		return
+	}
+	if strings.Contains(values[0], "\n") {
+		for _, line := range strings.Split(values[0], "\n") {
+			if line = strings.TrimSpace(line); len(line) >= minSensitiveLineLength {
+				values = append(values, line)
+			}
+		}
+	}
+
+	sensitiveMu.Lock()
+	defer sensitiveMu.Unlock()
+	for _, value := range values {
+		if !slices.Contains(sensitive, value) {
+			sensitive = append(sensitive, value)
+		}
+	}
+	slices.SortStableFunc(sensitive, func(a, b string) int { return len(b) - len(a) })
+}
+
+// ClearSensitive forgets every value marked as sensitive
+func ClearSensitive() {
+	sensitiveMu.Lock()
+	defer sensitiveMu.Unlock()
+	sensitive = nil
+}
+
+// Mask replaces every sensitive value in text with SanitizedValue
+func Mask(text string) string {
+	sensitiveMu.RLock()
+	defer sensitiveMu.RUnlock()
+	for _, value := range sensitive {
+		text = strings.ReplaceAll(text, value, SanitizedValue)
+	}
+	return text
+}
@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package message provides a rich set of functions for displaying messages to the user.
+package message
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/pterm/pterm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMask(t *testing.T) {
+	t.Cleanup(ClearSensitive)
+
+	require.Equal(t, "nothing to hide", Mask("nothing to hide"))
+
+	AddSensitive("hunter22\n")
+	AddSensitive("   ")
+	AddSensitive("maru\n")
+	AddSensitive("-----BEGIN KEY-----\nMIIEvQIBADANBgkqhkiG9w0BAQEFAASC\n}\n-----END KEY-----\n")
+	require.Equal(t, "password=**sanitized** user=maru", Mask("password=hunter22 user=maru"))
+	// Multi-line values are masked whole as well as a (long enough) line at a time
+	require.Equal(t, "key: **sanitized**", Mask("key: -----BEGIN KEY-----\nMIIEvQIBADANBgkqhkiG9w0BAQEFAASC\n}\n-----END KEY-----"))
+	require.Equal(t, "**sanitized**", Mask("MIIEvQIBADANBgkqhkiG9w0BAQEFAASC"))
+	require.Equal(t, "}", Mask("}"))
+	// Values that are too short to be secrets aren't masked as they would mask unrelated output
+	require.Equal(t, "user=maru", Mask("user=maru"))
+
+	ClearSensitive()
+	require.Equal(t, "password=hunter22", Mask("password=hunter22"))
+}
+
+func TestMask_printed(t *testing.T) {
+	t.Cleanup(ClearSensitive)
+	var outBuf bytes.Buffer
+	pterm.SetDefaultOutput(&outBuf)
+	pterm.DisableStyling()
+	previous := GetLogLevel()
+	SetLogLevel(InfoLevel)
+	t.Cleanup(func() {
+		pterm.SetDefaultOutput(os.Stdout)
+		pterm.EnableStyling()
+		SetLogLevel(previous)
+	})
+
+	AddSensitive("hunter22")
+	spinner := NewProgressSpinner("Logging in with %s", "hunter22")
+	_, err := spinner.Write([]byte("token hunter22 accepted\n"))
+	require.NoError(t, err)
+	spinner.Successf("Logged in with %s", "hunter22")
+
+	require.NotContains(t, outBuf.String(), "hunter22")
+	require.Contains(t, outBuf.String(), "token **sanitized** accepted")
+}
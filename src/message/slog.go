@@ -48,8 +48,14 @@ func (z MaruHandler) WithGroup(_ string) slog.Handler {
 // Handle prints the respective logging function in Maru
 // This function ignores any key pairs passed through the record
 func (z MaruHandler) Handle(_ context.Context, record slog.Record) error {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
 	level := record.Level
 	message := record.Message
+	if prefix != "" {
+		message = prefix + " " + message
+	}
 
 	switch level {
 	case slog.LevelDebug:
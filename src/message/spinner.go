@@ -7,116 +7,273 @@ package message
 import (
 	"bufio"
 	"bytes"
-	"fmt"
 	"os"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/defenseunicorns/pkg/helpers/v2"
 	"github.com/pterm/pterm"
 )
 
-var activeSpinner *Spinner
+// activeSpinners are the spinners that have not been closed yet, in the order they were started. A single spinner is
+// animated on the current line while more than one (i.e. actions that run at the same time) print each of their
+// updates as a line of its own so they are never drawn over each other.
+var activeSpinners []*Spinner
+
+// outputMu serializes everything written to the terminal so that messages and the output of commands that run at the
+// same time are never interleaved within a line
+var outputMu sync.Mutex
 
 var sequence = []string{" ⬒ ", " ⬔ ", " ◨ ", " ◪ ", " ⬓ ", " ⬕ ", " ◧ ", " ◩ "}
 
+// prefix is printed before the status of the progress spinner and every log message (i.e. to say which include the
+// running action came from)
+var prefix string
+
 // NoProgress sets whether the default spinners and progress bars should use fancy animations
 var NoProgress bool
 
-// Spinner is a wrapper around pterm.SpinnerPrinter.
+// maxPartialLine bounds how much output is held back waiting for the end of its line, so output that never ends a line
+// (i.e. a progress bar redrawn with \r) is still printed
+const maxPartialLine = 64 * 1024
+
+// Spinner shows the status of a single action (and the output of its command) until it is closed.
 type Spinner struct {
-	spinner   *pterm.SpinnerPrinter
-	termWidth int
+	// prefix is the prefix when the spinner was started, so it is kept even if another action changes the prefix
+	prefix string
+	text   string
+	frame  int
+	closed bool
+
+	// done is closed when the spinner is closed to stop its animation, which closes stopped once it has drawn its last
+	// frame
+	done    chan struct{}
+	stopped chan struct{}
+
+	// partial holds the end of the output written to the spinner until the rest of its line is written, so that a
+	// sensitive value split across writes is still masked
+	partial []byte
+}
+
+// SetPrefix sets the text printed before the status of the progress spinner and every log message ("" for none),
+// returning the previous prefix so it can be restored
+func SetPrefix(p string) string {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	previous := prefix
+	prefix = p
+	return previous
+}
+
+// withPrefix returns a format string that starts with the prefix (if there is one)
+func withPrefix(format string) string {
+	return withGivenPrefix(prefix, format)
+}
+
+// withGivenPrefix returns a format string that starts with the given prefix (if there is one)
+func withGivenPrefix(prefix, format string) string {
+	if prefix == "" {
+		return format
+	}
+	return strings.ReplaceAll(prefix, "%", "%%") + " " + format
 }
 
 // NewProgressSpinner creates a new progress spinner.
 var NewProgressSpinner = func(format string, a ...any) helpers.ProgressWriter {
-	if activeSpinner != nil {
-		activeSpinner.Updatef(format, a...)
-		debugPrinter(2, "Active spinner already exists")
-		return activeSpinner
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	p := &Spinner{
+		prefix:  prefix,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
 	}
+	p.text = p.sprintf(format, a...)
+
+	// The spinner that was animated until now is left on a line of its own as the spinners now print their updates
+	if !NoProgress && len(activeSpinners) == 1 {
+		activeSpinners[0].clearLine()
+		infof("%s", activeSpinners[0].text)
+	}
+	activeSpinners = append(activeSpinners, p)
 
-	var spinner *pterm.SpinnerPrinter
 	if NoProgress {
-		infof(format, a...)
+		close(p.stopped)
+		infof("%s", p.text)
+		return p
+	}
+	if p.animated() {
+		p.draw()
 	} else {
-		text := pterm.Sprintf(format, a...)
-		spinner, _ = pterm.DefaultSpinner.
-			WithRemoveWhenDone(false).
-			// Src: https://github.com/gernest/wow/blob/master/spin/spinners.go#L335
-			WithSequence(sequence...).
-			Start(text)
+		infof("%s", p.text)
 	}
+	go p.animate()
+
+	return p
+}
+
+// sprintf formats and masks text with the prefix of the spinner
+func (p *Spinner) sprintf(format string, a ...any) string {
+	return Mask(pterm.Sprintf(withGivenPrefix(p.prefix, format), a...))
+}
 
-	activeSpinner = &Spinner{
-		spinner:   spinner,
-		termWidth: pterm.GetTerminalWidth(),
+// animated returns whether the spinner is drawn (and animated) on the current line, which is only the case when it is
+// the only active spinner (the caller must hold outputMu)
+func (p *Spinner) animated() bool {
+	return !NoProgress && len(activeSpinners) == 1 && activeSpinners[0] == p
+}
+
+// animate draws the next frame of the spinner until it is closed
+func (p *Spinner) animate() {
+	defer close(p.stopped)
+
+	ticker := time.NewTicker(pterm.DefaultSpinner.Delay)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			outputMu.Lock()
+			p.frame++
+			if p.animated() {
+				p.draw()
+			}
+			outputMu.Unlock()
+		}
 	}
+}
+
+// draw draws the current frame of the spinner over the current line (the caller must hold outputMu)
+func (p *Spinner) draw() {
+	seq := sequence[p.frame%len(sequence)]
+	pterm.Fprinto(nil, "\033[K"+pterm.DefaultSpinner.Style.Sprint(seq)+" "+pterm.DefaultSpinner.MessageStyle.Sprint(p.text))
+}
 
-	return activeSpinner
+// clearLine clears the current line (the caller must hold outputMu)
+func (p *Spinner) clearLine() {
+	pterm.Fprinto(nil, "\033[K")
 }
 
 // Write the given text to the spinner.
 func (p *Spinner) Write(raw []byte) (int, error) {
-	size := len(raw)
-	if NoProgress {
-		os.Stderr.Write(raw)
+	outputMu.Lock()
+	defer outputMu.Unlock()
 
-		return size, nil
+	// Only whole lines are masked and printed, holding back the end of the text until the rest of its line is written
+	p.partial = append(p.partial, raw...)
+	if idx := bytes.LastIndexByte(p.partial, '\n'); idx >= 0 {
+		p.print(p.partial[:idx+1])
+		p.partial = append([]byte{}, p.partial[idx+1:]...)
+	}
+	if len(p.partial) > maxPartialLine {
+		p.flush()
 	}
 
-	// Split the text into lines and update the spinner for each line.
+	return len(raw), nil
+}
+
+// flush prints whatever output is held back waiting for the end of its line (the caller must hold outputMu)
+func (p *Spinner) flush() {
+	if len(p.partial) > 0 {
+		p.print(p.partial)
+		p.partial = nil
+	}
+}
+
+// print masks and prints output written to the spinner (the caller must hold outputMu)
+func (p *Spinner) print(raw []byte) {
+	if NoProgress {
+		os.Stderr.Write([]byte(Mask(string(raw))))
+		return
+	}
+
+	// Print each line above the spinner (which is drawn again below them)
 	scanner := bufio.NewScanner(bytes.NewReader(raw))
 	scanner.Split(bufio.ScanLines)
 	for scanner.Scan() {
-		text := pterm.Sprintf("     %s", scanner.Text())
-		// Clear the current line with the ANSI escape code
-		pterm.Fprinto(p.spinner.Writer, "\033[K")
-		pterm.Fprintln(p.spinner.Writer, text)
+		p.clearLine()
+		pterm.Println(pterm.Sprintf("     %s", Mask(scanner.Text())))
+	}
+	if p.animated() {
+		p.draw()
 	}
-
-	return size, nil
 }
 
 // Updatef updates the spinner text.
 func (p *Spinner) Updatef(format string, a ...any) {
-	if NoProgress {
-		debugPrinter(2, fmt.Sprintf(format, a...))
-		return
-	}
+	outputMu.Lock()
+	defer outputMu.Unlock()
 
-	pterm.Fprinto(p.spinner.Writer, strings.Repeat(" ", pterm.GetTerminalWidth()))
-	text := pterm.Sprintf(format, a...)
-	p.spinner.UpdateText(text)
+	p.text = p.sprintf(format, a...)
+	switch {
+	case NoProgress:
+		debugPrinter(2, p.text)
+	case p.animated():
+		p.draw()
+	default:
+		infof("%s", p.text)
+	}
 }
 
 // Close stops the spinner.
 func (p *Spinner) Close() error {
-	if p.spinner != nil && p.spinner.IsActive {
-		return p.spinner.Stop()
+	outputMu.Lock()
+	p.flush()
+	if p.animated() {
+		// leave the last status of the spinner on its line
+		pterm.Println()
 	}
-	activeSpinner = nil
+	p.stop()
+	outputMu.Unlock()
+
+	<-p.stopped
 	return nil
 }
 
+// stop marks the spinner as closed, stopping its animation (the caller must hold outputMu)
+func (p *Spinner) stop() {
+	if p.closed {
+		return
+	}
+	p.closed = true
+	close(p.done)
+	activeSpinners = slices.DeleteFunc(activeSpinners, func(s *Spinner) bool { return s == p })
+}
+
 // Successf prints a success message with the spinner and stops it.
 func (p *Spinner) Successf(format string, a ...any) {
-	if p.spinner != nil {
-		text := pterm.Sprintf(format, a...)
-		p.spinner.Success(text)
+	outputMu.Lock()
+	p.flush()
+	text := p.sprintf(format, a...)
+	if NoProgress {
+		successf("%s", text)
 	} else {
-		successf(format, a...)
+		p.clearLine()
+		pterm.Success.Println(text)
 	}
-	p.Close()
+	p.stop()
+	outputMu.Unlock()
+
+	<-p.stopped
 }
 
-// Failf prints an error message with the spinner.
+// Failf prints an error message with the spinner and stops it.
 func (p *Spinner) Failf(format string, a ...any) {
-	if p.spinner != nil {
-		text := pterm.Sprintf(format, a...)
-		p.spinner.Fail(text)
+	outputMu.Lock()
+	p.flush()
+	text := p.sprintf(format, a...)
+	if NoProgress {
+		errorf("%s", text)
 	} else {
-		errorf(format, a...)
+		p.clearLine()
+		pterm.Error.Println(text)
 	}
-	p.Close()
+	p.stop()
+	outputMu.Unlock()
+
+	<-p.stopped
 }
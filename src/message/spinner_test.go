@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package message provides a rich set of functions for displaying messages to the user.
+package message
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/defenseunicorns/pkg/helpers/v2"
+	"github.com/pterm/pterm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpinner_Write(t *testing.T) {
+	t.Cleanup(ClearSensitive)
+	var outBuf bytes.Buffer
+	pterm.SetDefaultOutput(&outBuf)
+	pterm.DisableStyling()
+	t.Cleanup(func() {
+		pterm.SetDefaultOutput(os.Stdout)
+		pterm.EnableStyling()
+	})
+
+	// Write the output a few bytes at a time so lines (and the sensitive value) are split across writes
+	AddSensitive("hunter22")
+	spinner := NewProgressSpinner("Logging in")
+	output := []byte("token hunter22 accepted\nline two\nno newline hunter22")
+	for len(output) > 0 {
+		n := min(3, len(output))
+		_, err := spinner.Write(output[:n])
+		require.NoError(t, err)
+		output = output[n:]
+	}
+	spinner.Successf("Logged in")
+
+	require.NotContains(t, outBuf.String(), "hunter22")
+	lines := []string{}
+	// The spinner is drawn over its line with \r so that is a line break here too
+	for _, line := range strings.FieldsFunc(outBuf.String(), func(r rune) bool { return r == '\n' || r == '\r' }) {
+		if line = strings.TrimSpace(strings.ReplaceAll(line, "\033[K", "")); line != "" && !strings.Contains(line, "Logg") {
+			lines = append(lines, line)
+		}
+	}
+	// Every line is printed whole, including the end of the output that did not end in a newline
+	require.Equal(t, []string{"token **sanitized** accepted", "line two", "no newline **sanitized**"}, lines)
+}
+
+func TestSpinner_concurrent(t *testing.T) {
+	var outBuf bytes.Buffer
+	pterm.SetDefaultOutput(&outBuf)
+	pterm.DisableStyling()
+	t.Cleanup(func() {
+		pterm.SetDefaultOutput(os.Stdout)
+		pterm.EnableStyling()
+	})
+
+	// Every action gets a spinner of its own, even while another one is running
+	first := NewProgressSpinner("Running %q", "first")
+	second := NewProgressSpinner("Running %q", "second")
+	require.NotSame(t, first, second)
+
+	var wg sync.WaitGroup
+	for _, spinner := range []helpers.ProgressWriter{first, second} {
+		wg.Add(1)
+		go func(spinner helpers.ProgressWriter) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				_, err := spinner.Write([]byte(fmt.Sprintf("%p line %d\n", spinner, i)))
+				require.NoError(t, err)
+			}
+			spinner.Successf("Completed %p", spinner)
+		}(spinner)
+	}
+	wg.Wait()
+
+	output := outBuf.String()
+	// Both running actions are reported as lines of their own rather than drawn over each other
+	require.Contains(t, output, `Running "first"`)
+	require.Contains(t, output, `Running "second"`)
+	for _, spinner := range []helpers.ProgressWriter{first, second} {
+		require.Contains(t, output, fmt.Sprintf("Completed %p", spinner))
+		for i := 0; i < 50; i++ {
+			// Every line of output is printed whole
+			require.Contains(t, output, fmt.Sprintf("     %p line %d\n", spinner, i))
+		}
+	}
+}
+
+func TestSetPrefix(t *testing.T) {
+	var outBuf bytes.Buffer
+	pterm.SetDefaultOutput(&outBuf)
+	pterm.DisableStyling()
+	noProgress, level := NoProgress, GetLogLevel()
+	NoProgress = true
+	SetLogLevel(InfoLevel)
+	t.Cleanup(func() {
+		pterm.SetDefaultOutput(os.Stdout)
+		pterm.EnableStyling()
+		NoProgress = noProgress
+		SetLogLevel(level)
+		SetPrefix("")
+	})
+
+	previous := SetPrefix("[lint: lint%20v2.yaml]")
+	require.Equal(t, "", previous)
+	spinner := NewProgressSpinner("Running %q", "yamllint .")
+	SLog.Warn("something to look at")
+	spinner.Failf("Failed %q", "yamllint .")
+
+	require.Equal(t, "[lint: lint%20v2.yaml]", SetPrefix(previous))
+	SLog.Info("back at the root")
+
+	output := outBuf.String()
+	require.Contains(t, output, `[lint: lint%20v2.yaml] Running "yamllint ."`)
+	require.Contains(t, output, "[lint: lint%20v2.yaml] something to look at")
+	require.Contains(t, output, `[lint: lint%20v2.yaml] Failed "yamllint ."`)
+	require.NotContains(t, output, "] back at the root")
+}
@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package migrate updates tasks files that use deprecated syntax
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Rule rewrites a line of a tasks file that uses deprecated syntax. Rules work on the text of a file (rather than
+// unmarshalling and marshalling it) so the comments and formatting of the file are kept.
+type Rule struct {
+	// Name identifies the rule
+	Name string
+	// Description explains what the rule changes and why
+	Description string
+	// migrate returns the migrated line and true if the line uses the deprecated syntax
+	migrate func(line string) (string, bool)
+}
+
+// Change is a line of a tasks file that a rule migrated
+type Change struct {
+	File   string
+	Line   int
+	Rule   string
+	Before string
+	After  string
+}
+
+// String describes the change as file:line
+func (c Change) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s -> %s", c.File, c.Line, c.Rule, strings.TrimSpace(c.Before), strings.TrimSpace(c.After))
+}
+
+// stringBooleanIf matches an `if` that is the string "true" or "false" (rather than a boolean), keeping any comment
+var stringBooleanIf = regexp.MustCompile(`^(\s*(?:-\s+)?if:\s*)(["'])(true|false)(["'])(\s*(?:#.*)?)$`)
+
+// Rules are the migrations that are applied, in order
+var Rules = []Rule{
+	{
+		Name:        "string-boolean-if",
+		Description: `an 'if' of the string "true" or "false" is deprecated in favor of a boolean (if: false)`,
+		migrate: func(line string) (string, bool) {
+			match := stringBooleanIf.FindStringSubmatch(line)
+			if match == nil || match[2] != match[4] {
+				return line, false
+			}
+			return match[1] + match[3] + match[5], true
+		},
+	},
+}
+
+// Migrate applies every rule to the contents of a tasks file, returning the migrated contents and the lines that changed
+func Migrate(file string, contents []byte) ([]byte, []Change) {
+	changes := []Change{}
+	lines := strings.Split(string(contents), "\n")
+	for i, line := range lines {
+		for _, rule := range Rules {
+			migrated, ok := rule.migrate(strings.TrimSuffix(line, "\r"))
+			if !ok {
+				continue
+			}
+			if strings.HasSuffix(line, "\r") {
+				migrated += "\r"
+			}
+			changes = append(changes, Change{File: file, Line: i + 1, Rule: rule.Name, Before: line, After: migrated})
+			line = migrated
+		}
+		lines[i] = line
+	}
+	return []byte(strings.Join(lines, "\n")), changes
+}
+
+// File applies every rule to a tasks file, writing the migrated file back unless check is set, and returns the lines
+// that changed (or would change)
+func File(path string, check bool) ([]Change, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	migrated, changes := Migrate(path, contents)
+	if check || len(changes) == 0 {
+		return changes, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return changes, os.WriteFile(path, migrated, info.Mode().Perm())
+}
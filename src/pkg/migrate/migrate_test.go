@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrate(t *testing.T) {
+	contents := `tasks:
+  - name: build
+    actions:
+      - cmd: echo skipped
+        if: "false" # turned off for now
+      - if: 'true'
+        cmd: echo runs
+      - cmd: echo typed
+        if: false
+      - cmd: echo templated
+        if: ${{ eq .variables.FOO "false" }}
+      - cmd: echo mismatched quotes
+        if: "false'
+      - cmd: echo "if: 'false'"
+`
+	migrated, changes := Migrate("tasks.yaml", []byte(contents))
+	require.Equal(t, `tasks:
+  - name: build
+    actions:
+      - cmd: echo skipped
+        if: false # turned off for now
+      - if: true
+        cmd: echo runs
+      - cmd: echo typed
+        if: false
+      - cmd: echo templated
+        if: ${{ eq .variables.FOO "false" }}
+      - cmd: echo mismatched quotes
+        if: "false'
+      - cmd: echo "if: 'false'"
+`, string(migrated))
+	require.Len(t, changes, 2)
+	require.Equal(t, `tasks.yaml:5: string-boolean-if: if: "false" # turned off for now -> if: false # turned off for now`, changes[0].String())
+	require.Equal(t, 6, changes[1].Line)
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("tasks:\r\n  - name: a\r\n    actions:\r\n      - cmd: echo a\r\n        if: \"false\"\r\n"), 0640))
+
+	// Checking does not change the file
+	changes, err := File(path, true)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(contents), `if: "false"`)
+
+	changes, err = File(path, false)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	contents, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "tasks:\r\n  - name: a\r\n    actions:\r\n      - cmd: echo a\r\n        if: false\r\n", string(contents))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	// A migrated file has nothing left to migrate
+	changes, err = File(path, false)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
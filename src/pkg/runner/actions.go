@@ -6,9 +6,14 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,28 +27,49 @@ import (
 	"github.com/defenseunicorns/maru-runner/src/types"
 )
 
-func (r *Runner) performAction(action types.Action, withs map[string]string, inputs map[string]types.InputParameter) error {
+// performAction runs a single action, returning whether it was skipped because its `if` condition was false along with
+// the output of the action (if it ran a command)
+func (r *Runner) performAction(ctx context.Context, action types.Action, withs map[string]string, inputs map[string]types.InputParameter, opts utils.TemplateOptions) (bool, string, error) {
 
 	message.SLog.Debug(fmt.Sprintf("Evaluating action conditional %s", action.If))
 
-	action, _ = utils.TemplateTaskAction(action, withs, inputs, r.variableConfig.GetSetVariables())
-	if action.If == "false" && action.TaskReference != "" {
+	condition := action.If
+	templated, err := utils.TemplateTaskActionWithOptions(action, withs, inputs, r.variableConfig.GetSetVariables(), opts)
+	// A file read by a template function that can't be read or an unknown platform stops the action (rather than running
+	// it untemplated like other template errors)
+	var pathErr *fs.PathError
+	var platformErr *utils.PlatformError
+	if errors.As(err, &pathErr) || errors.As(err, &platformErr) {
+		return false, "", fmt.Errorf("unable to template action %q: %w", message.Mask(actionName(action)), err)
+	}
+	action = templated
+	skip := condition != "" && !evaluateCondition(condition, action.If)
+	if skip && action.TaskReference != "" {
 		message.SLog.Info(fmt.Sprintf("Skipping action %s", action.TaskReference))
-		return nil
-	} else if action.If == "false" && action.Description != "" {
+		return true, "", nil
+	} else if skip && action.Description != "" {
 		message.SLog.Info(fmt.Sprintf("Skipping action %s", action.Description))
-		return nil
-	} else if action.If == "false" && action.Cmd != "" {
-		cmdEscaped := helpers.Truncate(action.Cmd, 60, false)
+		return true, "", nil
+	} else if skip && action.Cmd != "" {
+		cmdEscaped := helpers.Truncate(message.Mask(action.Cmd), 60, false)
 		message.SLog.Info(fmt.Sprintf("Skipping action %q", cmdEscaped))
-		return nil
+		return true, "", nil
+	} else if skip && action.Patch != nil {
+		message.SLog.Info(fmt.Sprintf("Skipping patch of %s", action.Patch.File))
+		return true, "", nil
+	} else if skip && action.Tunnel != nil {
+		message.SLog.Info(fmt.Sprintf("Skipping tunnel via %s", action.Tunnel.Via))
+		return true, "", nil
+	} else if skip && action.Pause != nil {
+		message.SLog.Info("Skipping pause")
+		return true, "", nil
 	}
 
 	if action.TaskReference != "" {
 		// todo: much of this logic is duplicated in Run, consider refactoring
 		referencedTask, err := r.getTask(action.TaskReference)
 		if err != nil {
-			return err
+			return false, "", err
 		}
 		for k, v := range action.With {
 			action.With[k] = utils.TemplateString(r.variableConfig.GetSetVariables(), v)
@@ -53,36 +79,81 @@ func (r *Runner) performAction(action types.Action, withs map[string]string, inp
 			withEnv = append(withEnv, utils.FormatEnvVar(name, action.With[name]))
 		}
 		if err := validateActionableTaskCall(referencedTask.Name, referencedTask.Inputs, action.With); err != nil {
-			return err
+			return false, "", err
 		}
 		for _, a := range referencedTask.Actions {
 			a.Env = utils.MergeEnv(withEnv, a.Env)
 		}
 
-		if err := r.executeTask(referencedTask, action.With); err != nil {
-			return err
+		if err := r.executeTask(ctx, referencedTask, action.With); err != nil {
+			return false, "", err
+		}
+	} else if action.Patch != nil {
+		if err := r.patchFile(action); err != nil {
+			return false, "", err
+		}
+	} else if action.Tunnel != nil {
+		if err := r.openTunnel(ctx, action); err != nil {
+			return false, "", err
+		}
+	} else if action.Pause != nil {
+		if err := r.pause(ctx, action); err != nil {
+			return false, "", err
 		}
 	} else {
-		err := RunAction(action.BaseAction, r.envFilePath, r.variableConfig, r.dryRun)
-		if err != nil {
-			return err
+		output, err := runAction(ctx, action.BaseAction, r.envFilePath, r.variableConfig, r.dryRun)
+		return false, output, err
+	}
+	return false, "", nil
+}
+
+// patchFile applies a patch action to its file, resolving the file (and output) relative to the action's dir
+func (r *Runner) patchFile(action types.Action) error {
+	patch := *action.Patch
+	setVariables := r.variableConfig.GetSetVariables()
+	patch.File = utils.TemplateString(setVariables, patch.File)
+	patch.Output = utils.TemplateString(setVariables, patch.Output)
+	if action.BaseAction != nil && action.Dir != nil {
+		dir := utils.TemplateString(setVariables, *action.Dir)
+		if !filepath.IsAbs(patch.File) {
+			patch.File = filepath.Join(dir, patch.File)
+		}
+		if patch.Output != "" && !filepath.IsAbs(patch.Output) {
+			patch.Output = filepath.Join(dir, patch.Output)
 		}
 	}
+
+	description := fmt.Sprintf("Patch %s", patch.File)
+	if action.BaseAction != nil && action.Description != "" {
+		description = action.Description
+	}
+
+	if r.dryRun {
+		message.SLog.Info(fmt.Sprintf("Dry-running %q", description))
+		return nil
+	}
+
+	spinner := message.NewProgressSpinner("Running %q", description)
+	if err := utils.PatchFile(patch.File, patch.Output, patch.Merge, patch.Ops); err != nil {
+		spinner.Failf("Failed %q", description)
+		return err
+	}
+	spinner.Successf("Completed %q", description)
 	return nil
 }
 
 // processAction checks if action needs to be processed for a given task
 func (r *Runner) processAction(task types.Task, action types.Action) bool {
 
-	taskReferenceName := strings.Split(task.Name, ":")[0]
-	actionReferenceName := strings.Split(action.TaskReference, ":")[0]
+	taskReferenceName := referenceScope(task.Name)
+	actionReferenceName := referenceScope(action.TaskReference)
 	// don't need to process if the action.TaskReference is empty or if the task and action references are the same since
 	// that indicates the task and task in the action are in the same file
 	if action.TaskReference != "" && (taskReferenceName != actionReferenceName) {
 		for _, task := range r.tasksFile.Tasks {
 			// check if TasksFile.Tasks already includes tasks with given reference name, which indicates that the
 			// reference has already been processed.
-			if strings.Contains(task.Name, taskReferenceName+":") || strings.Contains(task.Name, actionReferenceName+":") {
+			if include, _ := splitTaskReference(task.Name); include != "" && (include == taskReferenceName || include == actionReferenceName) {
 				return false
 			}
 		}
@@ -91,6 +162,15 @@ func (r *Runner) processAction(task types.Task, action types.Action) bool {
 	return false
 }
 
+// referenceScope returns the include a reference to a task is in, or the task's name if it is in the same file
+func referenceScope(reference string) string {
+	include, task := splitTaskReference(reference)
+	if include == "" {
+		return task
+	}
+	return include
+}
+
 func getUniqueTaskActions(actions []types.Action) []types.Action {
 	uniqueMap := make(map[string]bool)
 	var uniqueArray []types.Action
@@ -106,9 +186,19 @@ func getUniqueTaskActions(actions []types.Action) []types.Action {
 
 // RunAction executes a specific action command, either wait or cmd. It handles variable loading environment variables and manages retries and timeouts
 func RunAction[T any](action *types.BaseAction[T], envFilePath string, variableConfig *variables.VariableConfig[T], dryRun bool) error {
+	return RunActionWithContext(context.Background(), action, envFilePath, variableConfig, dryRun)
+}
+
+// RunActionWithContext executes an action like RunAction. The action's timeout is applied on top of the given context,
+// so any deadline or cancellation on ctx (i.e. task timeouts, run budgets or interrupts) also stops the action.
+func RunActionWithContext[T any](ctx context.Context, action *types.BaseAction[T], envFilePath string, variableConfig *variables.VariableConfig[T], dryRun bool) error {
+	_, err := runAction(ctx, action, envFilePath, variableConfig, dryRun)
+	return err
+}
+
+// runAction runs an action like RunAction, also returning the output of its last attempt (empty if the action is muted)
+func runAction[T any](ctx context.Context, action *types.BaseAction[T], envFilePath string, variableConfig *variables.VariableConfig[T], dryRun bool) (string, error) {
 	var (
-		ctx        context.Context
-		cancel     context.CancelFunc
 		cmdEscaped string
 		out        string
 		err        error
@@ -116,6 +206,18 @@ func RunAction[T any](action *types.BaseAction[T], envFilePath string, variableC
 		cmd = action.Cmd
 	)
 
+	// Offline, a wait for a host that can't be resolved fails now rather than when it times out
+	if action.Wait != nil && action.Wait.Network != nil && !dryRun {
+		if err := checkOfflineWait(ctx, *action.Wait.Network); err != nil {
+			return "", err
+		}
+	}
+
+	// HTTP waits with headers, an expected body or a backoff are performed by Maru itself
+	if isHTTPWait(action.Wait) {
+		return "", runHTTPWait(ctx, action, variableConfig, dryRun)
+	}
+
 	// If the action is a wait, convert it to a command.
 	if action.Wait != nil {
 		// If the wait has no timeout, set a default of 5 minutes.
@@ -126,7 +228,7 @@ func RunAction[T any](action *types.BaseAction[T], envFilePath string, variableC
 
 		// Convert the wait to a command.
 		if cmd, err = convertWaitToCmd(*action.Wait, action.MaxTotalSeconds); err != nil {
-			return err
+			return "", err
 		}
 
 		// Mute the output because it will be noisy.
@@ -147,27 +249,38 @@ func RunAction[T any](action *types.BaseAction[T], envFilePath string, variableC
 	if action.Description != "" {
 		cmdEscaped = action.Description
 	} else {
-		cmdEscaped = helpers.Truncate(cmd, 60, false)
+		cmdEscaped = helpers.Truncate(message.Mask(cmd), 60, false)
 	}
 
 	// if this is a dry run, print the command that would run and return
 	if dryRun {
 		message.SLog.Info(fmt.Sprintf("Dry-running %q", cmdEscaped))
-		fmt.Println(cmd)
-		return nil
+		fmt.Println(message.Mask(cmd))
+		return "", nil
 	}
 
-	// load the contents of the env file into the Action + the MARU_ARCH
+	// load the contents of the env file into the Action + the MARU_ARCH, remembering where variables came from in case
+	// the environment ends up too large
+	envSources := map[string]string{}
 	if envFilePath != "" {
 		envFilePath := filepath.Join(filepath.Dir(config.TaskFileLocation), envFilePath)
 		envFileContents, err := os.ReadFile(envFilePath)
 		if err != nil {
-			return err
+			return "", err
 		}
 		action.Env = append(action.Env, strings.Split(string(envFileContents), "\n")...)
+		for _, line := range strings.Split(string(envFileContents), "\n") {
+			name, _, _ := strings.Cut(line, "=")
+			envSources[name] = envSourceEnvFile
+		}
+	}
+	for name := range variableConfig.GetSetVariables() {
+		envSources[name] = envSourceVariables
 	}
 
 	spinner := message.NewProgressSpinner("Running %q", cmdEscaped)
+	// Paths that return without a success or failure message still close the spinner so it doesn't outlive the action
+	defer spinner.Close()
 
 	cfg := GetBaseActionCfg(types.ActionDefaults{}, *action, variableConfig.GetSetVariables())
 
@@ -176,6 +289,37 @@ func RunAction[T any](action *types.BaseAction[T], envFilePath string, variableC
 		spinner.Failf("Error mutating command: %q", cmdEscaped)
 	}
 
+	// Check the command against the command policy once (before it is instrumented) rather than on every retry
+	if err := checkCommandPolicy(cmd); err != nil {
+		spinner.Failf("Not allowed to run %q", cmdEscaped)
+		return "", err
+	}
+
+	// Record the line that multi-line scripts are on so a failure can be reported against the line that failed (unless
+	// the action turns it off)
+	shell, _ := exec.GetOSShell(cfg.Shell)
+	scriptLines := strings.Split(cmd, "\n")
+	lineFile := ""
+	reportLine := action.ReportLine == nil || *action.ReportLine
+	if reportLine && strings.Contains(strings.TrimSpace(cmd), "\n") && isPOSIXShell(shell) {
+		f, err := os.CreateTemp("", "maru-line-*")
+		if err != nil {
+			return "", err
+		}
+		lineFile = f.Name()
+		f.Close()
+		defer os.Remove(lineFile)
+		cmd = instrumentLines(cmd, lineFile)
+	}
+
+	// Wrap the command in the strict mode prologue of its shell (if enabled)
+	if cfg.ShellStrict {
+		if cmd, err = strictCommand(cmd, shell); err != nil {
+			spinner.Failf("Unable to run %q in strict mode", cmdEscaped)
+			return "", err
+		}
+	}
+
 	// Template dir string
 	cfg.Dir = utils.TemplateString(variableConfig.GetSetVariables(), cfg.Dir)
 
@@ -184,81 +328,146 @@ func RunAction[T any](action *types.BaseAction[T], envFilePath string, variableC
 		cfg.Env[idx] = utils.TemplateString(variableConfig.GetSetVariables(), cfg.Env[idx])
 	}
 
-	duration := time.Duration(cfg.MaxTotalSeconds) * time.Second
-	timeout := time.After(duration)
+	// Compile the output pattern to wait for (if any) before running the command
+	var untilOutput *regexp.Regexp
+	if action.UntilOutput != "" {
+		if untilOutput, err = regexp.Compile(action.UntilOutput); err != nil {
+			spinner.Failf("Invalid untilOutput pattern for %q", cmdEscaped)
+			return "", fmt.Errorf("invalid untilOutput pattern %q: %w", action.UntilOutput, err)
+		}
+	}
 
-	// Keep trying until the max retries is reached.
-retryLoop:
-	for remaining := cfg.MaxRetries + 1; remaining > 0; remaining-- {
+	// Compile the prompts to answer (if any) before running the command
+	expect := []expectRule{}
+	for _, e := range action.Expect {
+		pattern, err := regexp.Compile(e.Pattern)
+		if err != nil {
+			spinner.Failf("Invalid expect pattern for %q", cmdEscaped)
+			return "", fmt.Errorf("invalid expect pattern %q: %w", e.Pattern, err)
+		}
+		expect = append(expect, expectRule{pattern: pattern, send: utils.TemplateString(variableConfig.GetSetVariables(), e.Send)})
+	}
 
-		// Perform the action run.
-		tryCmd := func(ctx context.Context) error {
-			// Try running the command and continue the retry loop if it fails.
-			if out, err = ExecAction(ctx, cfg, cmd, cfg.Shell, spinner); err != nil {
+	// Apply the action timeout (if any) on top of the parent context so that the two compose
+	actionCtx := ctx
+	if cfg.MaxTotalSeconds > 0 {
+		var cancel context.CancelFunc
+		duration := time.Duration(cfg.MaxTotalSeconds) * time.Second
+		actionCtx, cancel = context.WithTimeoutCause(ctx, duration, fmt.Errorf("command \"%s\" timed out after %d seconds", cmdEscaped, cfg.MaxTotalSeconds))
+		defer cancel()
+		spinner.Updatef("Waiting for \"%s\" (timeout: %ds)", cmdEscaped, cfg.MaxTotalSeconds)
+	} else {
+		spinner.Updatef("Waiting for \"%s\" (no timeout)", cmdEscaped)
+	}
+
+	// Perform the action run.
+	var cause FailureCause
+	tryCmd := func(ctx context.Context, attempt int) error {
+		attemptCfg := cfg
+		attemptCfg.Env = append(slices.Clone(cfg.Env),
+			fmt.Sprintf("%s=%d", AttemptEnv, attempt),
+			fmt.Sprintf("%s=%d", MaxAttemptsEnv, cfg.MaxRetries+1),
+		)
+
+		// Template the attempt values into the dir and env strings (these change on every retry)
+		attemptVars := variables.SetVariableMap[T]{
+			AttemptEnv:     {Value: strconv.Itoa(attempt)},
+			MaxAttemptsEnv: {Value: strconv.Itoa(cfg.MaxRetries + 1)},
+		}
+		attemptCfg.Dir = utils.TemplateString(attemptVars, attemptCfg.Dir)
+		for idx := range attemptCfg.Env {
+			attemptCfg.Env[idx] = utils.TemplateString(attemptVars, attemptCfg.Env[idx])
+		}
+
+		// Clear the line recorded by the last attempt (if any)
+		if lineFile != "" {
+			if err := os.WriteFile(lineFile, nil, helpers.ReadWriteUser); err != nil {
 				return err
 			}
+		}
 
-			out = strings.TrimSpace(out)
+		// Try running the command and continue the retry loop if it fails (remembering why it likely failed).
+		var errOut string
+		if out, errOut, err = execActionOutput(ctx, attemptCfg, cmd, cfg.Shell, spinner, cmdOptions{untilOutput: untilOutput, expect: expect, envSources: envSources}); err != nil {
+			err = failedLine(err, lineFile, scriptLines)
+			cause = classifyFailure(ctx, err, out+"\n"+errOut)
+			return err
+		}
 
-			// If an output variable is defined, set it.
-			for _, v := range action.SetVariables {
-				variableConfig.SetVariable(v.Name, out, v.Pattern, v.Extra)
-				if err = variableConfig.CheckVariablePattern(v.Name); err != nil {
-					message.SLog.Debug(err.Error())
-					message.SLog.Warn(err.Error())
-					return err
-				}
-			}
+		out = strings.TrimSpace(out)
 
-			// If the action has a wait, change the spinner message to reflect that on success.
-			if action.Wait != nil {
-				spinner.Successf("Wait for %q succeeded", cmdEscaped)
-			} else {
-				spinner.Successf("Completed %q", cmdEscaped)
+		// If an output variable is defined, set it.
+		for _, v := range action.SetVariables {
+			variableConfig.SetVariable(v.Name, out, v.Pattern, v.Extra)
+			if err = variableConfig.CheckVariablePattern(v.Name); err != nil {
+				message.SLog.Debug(err.Error())
+				message.SLog.Warn(err.Error())
+				return err
 			}
+		}
 
-			// If the command ran successfully, continue to the next action.
-			return nil
+		// If the action has a wait, change the spinner message to reflect that on success.
+		if action.Wait != nil {
+			spinner.Successf("Wait for %q succeeded", cmdEscaped)
+		} else {
+			spinner.Successf("Completed %q", cmdEscaped)
 		}
 
-		// If no timeout is set, run the command and return or continue retrying.
-		if cfg.MaxTotalSeconds < 1 {
-			spinner.Updatef("Waiting for \"%s\" (no timeout)", cmdEscaped)
-			if err := tryCmd(context.TODO()); err != nil {
-				continue
-			}
+		// If the command ran successfully, continue to the next action.
+		return nil
+	}
 
-			return nil
+	// Muted output is kept out of the run results as it may hold sensitive values
+	recordedOutput := func() string {
+		if cfg.Mute {
+			return ""
 		}
+		return out
+	}
 
-		// Run the command on repeat until success or timeout.
-		spinner.Updatef("Waiting for \"%s\" (timeout: %ds)", cmdEscaped, cfg.MaxTotalSeconds)
-		select {
-		// On timeout break the loop to abort.
-		case <-timeout:
-			break retryLoop
-
-		// Otherwise, try running the command.
-		default:
-			ctx, cancel = context.WithTimeout(context.Background(), duration)
-			if err := tryCmd(ctx); err != nil {
-				cancel() // Directly cancel the context after an unsuccessful command attempt.
-				continue
-			}
-			cancel() // Also cancel the context after a successful command attempt.
-			return nil
+	// Keep trying until the max retries is reached or the context is done.
+	var lastErr error
+	attempts := []string{}
+	for attempt := 1; attempt <= cfg.MaxRetries+1; attempt++ {
+		cause = ""
+		if lastErr = tryCmd(actionCtx, attempt); lastErr == nil {
+			return recordedOutput(), nil
+		}
+		attempts = append(attempts, attemptSummary(attempt, lastErr, cause))
+
+		// Retrying won't make an environment that is too large fit
+		var envErr *EnvTooLargeError
+		if errors.As(lastErr, &envErr) {
+			spinner.Failf("Unable to start %q", cmdEscaped)
+			return "", fmt.Errorf("command \"%s\" could not be started: %w", cmdEscaped, lastErr)
+		}
+
+		if actionCtx.Err() != nil {
+			break
 		}
 	}
 
-	select {
-	case <-timeout:
-		// If we reached this point, the timeout was reached.
-		return fmt.Errorf("command \"%s\" timed out after %d seconds", cmdEscaped, cfg.MaxTotalSeconds)
+	// If the context is done, report why (action timeout, task timeout, run budget or interrupt).
+	if actionCtx.Err() != nil {
+		return recordedOutput(), context.Cause(actionCtx)
+	}
 
-	default:
-		// If we reached this point, the retry limit was reached.
-		return fmt.Errorf("command \"%s\" failed after %d retries", cmdEscaped, cfg.MaxRetries)
+	// If we reached this point, the retry limit was reached, so summarize the attempts and hint at the likely cause of
+	// the last one (if it is recognized)
+	if len(attempts) > 1 {
+		message.SLog.Warn(fmt.Sprintf("All %d attempts of %q failed: %s", len(attempts), cmdEscaped, strings.Join(attempts, "; ")))
 	}
+	if cause != "" {
+		message.SLog.Warn(fmt.Sprintf("Hint: %s", cause.Hint()))
+	}
+
+	// Surface a killed runaway command, an output wait or the line of a script that failed as the reason.
+	var outputErr *OutputLimitError
+	var lineErr *ScriptLineError
+	if errors.As(lastErr, &outputErr) || errors.Is(lastErr, errOutputNotMatched) || errors.As(lastErr, &lineErr) {
+		return recordedOutput(), withFailureCause(fmt.Errorf("command \"%s\" failed after %d retries: %w", cmdEscaped, cfg.MaxRetries, lastErr), cause)
+	}
+	return recordedOutput(), withFailureCause(fmt.Errorf("command \"%s\" failed after %d retries", cmdEscaped, cfg.MaxRetries), cause)
 }
 
 // GetBaseActionCfg merges the ActionDefaults with the BaseAction's configuration
@@ -276,6 +485,14 @@ func GetBaseActionCfg[T any](cfg types.ActionDefaults, a types.BaseAction[T], va
 		cfg.MaxRetries = *a.MaxRetries
 	}
 
+	if a.MaxOutputBytes != nil {
+		cfg.MaxOutputBytes = *a.MaxOutputBytes
+	}
+
+	if a.ANSI != nil {
+		cfg.ANSI = *a.ANSI
+	}
+
 	if a.Dir != nil {
 		cfg.Dir = *a.Dir
 	}
@@ -288,6 +505,10 @@ func GetBaseActionCfg[T any](cfg types.ActionDefaults, a types.BaseAction[T], va
 		cfg.Shell = *a.Shell
 	}
 
+	if a.ShellStrict != nil {
+		cfg.ShellStrict = *a.ShellStrict
+	}
+
 	// Add variables to the environment.
 	for k, v := range vars {
 		cfg.Env = append(cfg.Env, fmt.Sprintf("%s=%s", k, v.Value))
@@ -300,8 +521,24 @@ func GetBaseActionCfg[T any](cfg types.ActionDefaults, a types.BaseAction[T], va
 	return cfg
 }
 
-// ExecAction executes the given action configuration with the provided context
+// ExecAction executes the given action configuration with the provided context (if the command policy allows the command)
 func ExecAction(ctx context.Context, cfg types.ActionDefaults, cmd string, shellPref exec.ShellPreference, spinner helpers.ProgressWriter) (string, error) {
+	if err := checkCommandPolicy(cmd); err != nil {
+		return "", err
+	}
+	return execAction(ctx, cfg, cmd, shellPref, spinner, cmdOptions{})
+}
+
+// execAction executes the given action configuration with the provided context, stopping the command successfully once
+// a line of its output matches opts.untilOutput (if set) and returning that line as the output
+func execAction(ctx context.Context, cfg types.ActionDefaults, cmd string, shellPref exec.ShellPreference, spinner helpers.ProgressWriter, opts cmdOptions) (string, error) {
+	out, _, err := execActionOutput(ctx, cfg, cmd, shellPref, spinner, opts)
+	return out, err
+}
+
+// execActionOutput executes the given action configuration in the same way as execAction, also returning what the
+// command wrote to stderr
+func execActionOutput(ctx context.Context, cfg types.ActionDefaults, cmd string, shellPref exec.ShellPreference, spinner helpers.ProgressWriter, opts cmdOptions) (string, string, error) {
 	shell, shellArgs := exec.GetOSShell(shellPref)
 
 	message.SLog.Debug(fmt.Sprintf("Running command in %s: %s", shell, cmd))
@@ -316,13 +553,24 @@ func ExecAction(ctx context.Context, cfg types.ActionDefaults, cmd string, shell
 		execCfg.Stderr = spinner
 	}
 
-	out, errOut, err := exec.CmdWithContext(ctx, execCfg, shell, append(shellArgs, cmd)...)
+	opts.maxOutputBytes = cfg.MaxOutputBytes
+	result, err := cmdWithContext(ctx, execCfg, opts, shell, append(shellArgs, cmd)...)
+	out := utils.SanitizeOutput(result.Stdout, cfg.ANSI == types.ANSIStrip)
+	errOut := utils.SanitizeOutput(result.Stderr, cfg.ANSI == types.ANSIStrip)
+
 	// Dump final complete output (respect mute to prevent sensitive values from hitting the logs).
 	if !cfg.Mute {
 		message.SLog.Debug(fmt.Sprintf("%s %s %s", cmd, out, errOut))
 	}
 
-	return out, err
+	if opts.untilOutput != nil {
+		if err == nil && result.Matched == "" {
+			return out, errOut, fmt.Errorf("%w %q", errOutputNotMatched, opts.untilOutput.String())
+		}
+		return utils.SanitizeOutput(result.Matched, cfg.ANSI == types.ANSIStrip), errOut, err
+	}
+
+	return out, errOut, err
 }
 
 // TODO: (@WSTARR) - this is broken in Maru right now - this should not shell to Kubectl and instead should internally talk to a cluster
@@ -374,43 +622,44 @@ func convertWaitToCmd(wait types.ActionWait, timeout *int) (string, error) {
 	return "", fmt.Errorf("wait action is missing a cluster or network")
 }
 
-// validateActionableTaskCall validates a tasks "withs" and inputs
+// validateActionableTaskCall validates a tasks "withs" and inputs, checking every with (so deprecated and unknown inputs
+// are all warned about) before returning the missing inputs
 func validateActionableTaskCall(inputTaskName string, inputs map[string]types.InputParameter, withs map[string]string) error {
+	missing := missingInputs(inputs, withs)
+
+	withKeys := []string{}
+	for withKey := range withs {
+		withKeys = append(withKeys, withKey)
+	}
+	slices.Sort(withKeys)
+	for _, withKey := range withKeys {
+		input, ok := inputs[withKey]
+		if !ok {
+			message.SLog.Warn(fmt.Sprintf("Task %s does not have an input named %s", inputTaskName, withKey))
+		} else if input.DeprecatedMessage != "" {
+			message.SLog.Warn(fmt.Sprintf("This input has been marked deprecated: %s", input.DeprecatedMessage))
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("task %s is missing required inputs: %s", inputTaskName, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// missingInputs returns the (sorted) required inputs without a default that a call of a task does not give a value
+func missingInputs(inputs map[string]types.InputParameter, withs map[string]string) []string {
 	missing := []string{}
 	for inputKey, input := range inputs {
 		// skip inputs that are not required or have a default value
 		if !input.Required || input.Default != "" {
 			continue
 		}
-		checked := false
-		for withKey, withVal := range withs {
-			// verify that the input is in the with map and the "with" has a value
-			if inputKey == withKey && withVal != "" {
-				checked = true
-				break
-			}
-		}
-		if !checked {
+		// verify that the input is in the with map and the "with" has a value
+		if withs[inputKey] == "" {
 			missing = append(missing, inputKey)
 		}
 	}
-	if len(missing) > 0 {
-		return fmt.Errorf("task %s is missing required inputs: %s", inputTaskName, strings.Join(missing, ", "))
-	}
-	for withKey := range withs {
-		matched := false
-		for inputKey, input := range inputs {
-			if withKey == inputKey {
-				if input.DeprecatedMessage != "" {
-					message.SLog.Warn(fmt.Sprintf("This input has been marked deprecated: %s", input.DeprecatedMessage))
-				}
-				matched = true
-				break
-			}
-		}
-		if !matched {
-			message.SLog.Warn(fmt.Sprintf("Task %s does not have an input named %s", inputTaskName, withKey))
-		}
-	}
-	return nil
+	slices.Sort(missing)
+	return missing
 }
@@ -4,13 +4,17 @@
 package runner
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"slices"
 	"testing"
+	"time"
 
 	"github.com/defenseunicorns/maru-runner/src/config"
 	"github.com/defenseunicorns/maru-runner/src/types"
 
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
 	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
 
 	"github.com/stretchr/testify/require"
@@ -220,6 +224,16 @@ func Test_validateActionableTaskCall(t *testing.T) {
 	}
 }
 
+func Test_validateActionableTaskCall_everyMissingInput(t *testing.T) {
+	inputs := map[string]types.InputParameter{
+		"c": {Required: true},
+		"a": {Required: true},
+		"b": {Required: true},
+	}
+	err := validateActionableTaskCall("testTask", inputs, map[string]string{"b": "", "unknown": "x"})
+	require.EqualError(t, err, "task testTask is missing required inputs: a, b, c")
+}
+
 func TestRunner_performAction(t *testing.T) {
 	type fields struct {
 		TasksFile                       types.TasksFile
@@ -298,7 +312,7 @@ func TestRunner_performAction(t *testing.T) {
 				envFilePath:                     tt.fields.envFilePath,
 				variableConfig:                  tt.fields.variableConfig,
 			}
-			err := r.performAction(tt.args.action, tt.args.withs, tt.args.inputs)
+			_, _, err := r.performAction(context.TODO(), tt.args.action, tt.args.withs, tt.args.inputs, utils.TemplateOptions{})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("performAction() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -306,6 +320,27 @@ func TestRunner_performAction(t *testing.T) {
 	}
 }
 
+func TestRunner_performAction_templateError(t *testing.T) {
+	r := &Runner{variableConfig: GetMaruVariableConfig()}
+	action := types.Action{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{
+		Description: "Build the binaries",
+		Cmd:         `${{ range platforms "linux-amd64" }}touch ${{ .OS }}${{ end }}`,
+	}}
+
+	// An unknown platform stops the action rather than running it untemplated
+	_, _, err := r.performAction(context.TODO(), action, nil, nil, utils.TemplateOptions{})
+	require.ErrorContains(t, err, `unable to template action "Build the binaries":`)
+	require.ErrorContains(t, err, "linux-amd64")
+	var platformErr *utils.PlatformError
+	require.ErrorAs(t, err, &platformErr)
+
+	// Other template errors leave the action untemplated
+	action.Description = "${{ .inputs.missing }}"
+	action.Cmd = "true"
+	_, _, err = r.performAction(context.TODO(), action, nil, nil, utils.TemplateOptions{})
+	require.NoError(t, err)
+}
+
 func TestRunner_processAction(t *testing.T) {
 	type fields struct {
 		TasksFile                       types.TasksFile
@@ -510,3 +545,229 @@ func TestRunner_GetBaseActionCfg(t *testing.T) {
 	}
 
 }
+
+func TestRunAction_timeouts(t *testing.T) {
+	runnerErr := errors.New("run timed out")
+	tests := []struct {
+		name       string
+		action     types.BaseAction[variables.ExtraVariableInfo]
+		ctx        func() (context.Context, context.CancelFunc)
+		wantErrMsg string
+	}{
+		{
+			name: "action timeout",
+			action: types.BaseAction[variables.ExtraVariableInfo]{
+				Cmd:             "sleep 5",
+				MaxTotalSeconds: IntPtr(1),
+			},
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithCancel(context.Background())
+			},
+			wantErrMsg: `command "sleep 5" timed out after 1 seconds`,
+		},
+		{
+			name: "parent deadline takes precedence over a longer action timeout",
+			action: types.BaseAction[variables.ExtraVariableInfo]{
+				Cmd:             "sleep 5",
+				MaxTotalSeconds: IntPtr(30),
+				MaxRetries:      IntPtr(3),
+			},
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeoutCause(context.Background(), 500*time.Millisecond, runnerErr)
+			},
+			wantErrMsg: runnerErr.Error(),
+		},
+		{
+			name: "retries exhausted",
+			action: types.BaseAction[variables.ExtraVariableInfo]{
+				Cmd:        "exit 1",
+				MaxRetries: IntPtr(2),
+			},
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithCancel(context.Background())
+			},
+			wantErrMsg: `command "exit 1" failed after 2 retries`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			start := time.Now()
+			err := RunActionWithContext(ctx, &tt.action, "", GetMaruVariableConfig(), false)
+			require.EqualError(t, err, tt.wantErrMsg)
+			require.Less(t, time.Since(start), 5*time.Second)
+		})
+	}
+}
+
+func TestRunAction_attemptEnv(t *testing.T) {
+	variableConfig := GetMaruVariableConfig()
+	action := types.BaseAction[variables.ExtraVariableInfo]{
+		Cmd:          `echo "$ATTEMPT_LABEL"; [ "$MARU_ATTEMPT" -eq "$MARU_MAX_ATTEMPTS" ]`,
+		MaxRetries:   IntPtr(2),
+		Env:          []string{"ATTEMPT_LABEL=attempt-${MARU_ATTEMPT}-of-${MARU_MAX_ATTEMPTS}"},
+		SetVariables: []variables.Variable[variables.ExtraVariableInfo]{{Name: "ATTEMPT"}},
+	}
+
+	err := RunAction(&action, "", variableConfig, false)
+	require.NoError(t, err)
+
+	attempt, ok := variableConfig.GetSetVariable("ATTEMPT")
+	require.True(t, ok)
+	require.Equal(t, "attempt-3-of-3", attempt.Value)
+	require.Equal(t, []string{"ATTEMPT_LABEL=attempt-${MARU_ATTEMPT}-of-${MARU_MAX_ATTEMPTS}"}, action.Env)
+}
+
+func TestRunAction_maxOutputBytes(t *testing.T) {
+	variableConfig := GetMaruVariableConfig()
+	action := types.BaseAction[variables.ExtraVariableInfo]{
+		Cmd:            "yes",
+		MaxOutputBytes: IntPtr(1024),
+		SetVariables:   []variables.Variable[variables.ExtraVariableInfo]{{Name: "OUTPUT"}},
+	}
+
+	start := time.Now()
+	err := RunAction(&action, "", variableConfig, false)
+	require.EqualError(t, err, `command "yes" failed after 0 retries: output exceeded the limit of 1024 bytes`)
+	require.Less(t, time.Since(start), 5*time.Second)
+
+	// Output within the limit is unaffected
+	action = types.BaseAction[variables.ExtraVariableInfo]{
+		Cmd:            "echo hello",
+		MaxOutputBytes: IntPtr(1024),
+		SetVariables:   []variables.Variable[variables.ExtraVariableInfo]{{Name: "OUTPUT"}},
+	}
+	err = RunActionWithContext(context.TODO(), &action, "", variableConfig, false)
+	require.NoError(t, err)
+	output, ok := variableConfig.GetSetVariable("OUTPUT")
+	require.True(t, ok)
+	require.Equal(t, "hello", output.Value)
+}
+
+func TestRunAction_untilOutput(t *testing.T) {
+	tests := []struct {
+		name       string
+		action     types.BaseAction[variables.ExtraVariableInfo]
+		want       string
+		wantErrMsg string
+	}{
+		{
+			name: "stops the command once a line matches",
+			action: types.BaseAction[variables.ExtraVariableInfo]{
+				Cmd:         `echo starting; echo "Server started on port 8080" >&2; sleep 30`,
+				UntilOutput: `Server started on port \d+`,
+			},
+			want: "Server started on port 8080",
+		},
+		{
+			name: "matches a last line without a newline",
+			action: types.BaseAction[variables.ExtraVariableInfo]{
+				Cmd:         `echo starting; printf "Server started"`,
+				UntilOutput: "Server started",
+			},
+			want: "Server started",
+		},
+		{
+			name: "fails when the command exits without a match",
+			action: types.BaseAction[variables.ExtraVariableInfo]{
+				Cmd:         "echo starting",
+				UntilOutput: "Server started",
+			},
+			wantErrMsg: `command "echo starting" failed after 0 retries: command exited before its output matched "Server started"`,
+		},
+		{
+			name: "times out waiting for a match",
+			action: types.BaseAction[variables.ExtraVariableInfo]{
+				Cmd:             "echo starting; sleep 30",
+				UntilOutput:     "Server started",
+				MaxTotalSeconds: IntPtr(1),
+			},
+			wantErrMsg: `command "echo starting; sleep 30" timed out after 1 seconds`,
+		},
+		{
+			name: "invalid pattern",
+			action: types.BaseAction[variables.ExtraVariableInfo]{
+				Cmd:         "echo starting",
+				UntilOutput: "(",
+			},
+			wantErrMsg: "invalid untilOutput pattern",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variableConfig := GetMaruVariableConfig()
+			tt.action.SetVariables = []variables.Variable[variables.ExtraVariableInfo]{{Name: "OUTPUT"}}
+
+			start := time.Now()
+			err := RunActionWithContext(context.TODO(), &tt.action, "", variableConfig, false)
+			require.Less(t, time.Since(start), 5*time.Second)
+			if tt.wantErrMsg != "" {
+				require.ErrorContains(t, err, tt.wantErrMsg)
+				return
+			}
+			require.NoError(t, err)
+
+			output, ok := variableConfig.GetSetVariable("OUTPUT")
+			require.True(t, ok)
+			require.Equal(t, tt.want, output.Value)
+		})
+	}
+}
+
+func TestRunAction_expect(t *testing.T) {
+	tests := []struct {
+		name       string
+		action     types.BaseAction[variables.ExtraVariableInfo]
+		want       string
+		wantErrMsg string
+	}{
+		{
+			name: "answers prompts without a newline",
+			action: types.BaseAction[variables.ExtraVariableInfo]{
+				Cmd: `printf "Accept the license? [y/N] "; read accept; printf "Passphrase: " >&2; read pass; echo "accept=$accept pass=$pass"`,
+				Expect: []types.ActionExpect{
+					{Pattern: `Accept the license\? \[y/N\]`, Send: "y"},
+					{Pattern: "Passphrase:", Send: "${PASSPHRASE}"},
+				},
+			},
+			want: "Accept the license? [y/N] accept=y pass=secret",
+		},
+		{
+			name: "answers a prompt each time it is shown",
+			action: types.BaseAction[variables.ExtraVariableInfo]{
+				Cmd:    `for i in 1 2 3; do printf "Continue? "; read answer; printf "%s" "$answer"; done; echo`,
+				Expect: []types.ActionExpect{{Pattern: `Continue\?`, Send: "yes"}},
+			},
+			want: "Continue? yesContinue? yesContinue? yes",
+		},
+		{
+			name: "invalid pattern",
+			action: types.BaseAction[variables.ExtraVariableInfo]{
+				Cmd:    "echo starting",
+				Expect: []types.ActionExpect{{Pattern: "(", Send: "y"}},
+			},
+			wantErrMsg: "invalid expect pattern",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variableConfig := GetMaruVariableConfig()
+			variableConfig.SetVariable("PASSPHRASE", "secret", "", variables.ExtraVariableInfo{})
+			tt.action.SetVariables = []variables.Variable[variables.ExtraVariableInfo]{{Name: "OUTPUT"}}
+			tt.action.MaxTotalSeconds = IntPtr(10)
+
+			err := RunActionWithContext(context.TODO(), &tt.action, "", variableConfig, false)
+			if tt.wantErrMsg != "" {
+				require.ErrorContains(t, err, tt.wantErrMsg)
+				return
+			}
+			require.NoError(t, err)
+
+			output, ok := variableConfig.GetSetVariable("OUTPUT")
+			require.True(t, ok)
+			require.Equal(t, tt.want, output.Value)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/defenseunicorns/pkg/helpers/v2"
+)
+
+// defaultArtifactsDirectory is the directory (relative to where maru runs) that artifacts are collected in by default
+const defaultArtifactsDirectory = "maru-artifacts"
+
+// artifactOutputFile is the file the output of a failed command is written to alongside its artifacts
+const artifactOutputFile = "output.log"
+
+// artifactsDir returns the directory the artifacts of an action in this run are collected in
+func (r *Runner) artifactsDir(taskName string, idx int) string {
+	dir := config.ArtifactsDirectory
+	if dir == "" {
+		dir = defaultArtifactsDirectory
+	}
+	name := strings.NewReplacer(":", "-", "/", "-", `\`, "-").Replace(taskName)
+	return filepath.Join(dir, r.runID, fmt.Sprintf("%s-%d", name, idx))
+}
+
+// collectArtifacts copies the artifacts of a failed action (along with its output) into the artifacts directory so that
+// they are not lost with the machine maru ran on, returning the paths they were copied to. Artifacts that cannot be
+// collected are only warned about since the action has already failed.
+func (r *Runner) collectArtifacts(task types.Task, idx int, action types.Action, withs map[string]string, output string) []string {
+	if len(action.Artifacts) == 0 || r.dryRun {
+		return nil
+	}
+
+	setVariables := r.variableConfig.GetSetVariables()
+	action, _ = utils.TemplateTaskActionWithOptions(action, withs, task.Inputs, setVariables, r.templateOptions(task.Name, action))
+	baseDir := ""
+	if action.BaseAction != nil && action.Dir != nil {
+		baseDir = utils.TemplateString(setVariables, *action.Dir)
+	}
+
+	dest := r.artifactsDir(task.Name, idx)
+	collected := []string{}
+	for _, pattern := range action.Artifacts {
+		pattern = utils.TemplateString(setVariables, pattern)
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(baseDir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			message.SLog.Warn(fmt.Sprintf("Unable to collect artifacts %s: %s", pattern, err.Error()))
+			continue
+		}
+		if len(matches) == 0 {
+			message.SLog.Warn(fmt.Sprintf("No artifacts found at %s", pattern))
+			continue
+		}
+		for _, match := range matches {
+			target := filepath.Join(dest, artifactPath(baseDir, match))
+			if err := helpers.CreatePathAndCopy(match, target); err != nil {
+				message.SLog.Warn(fmt.Sprintf("Unable to collect artifact %s: %s", match, err.Error()))
+				continue
+			}
+			collected = append(collected, target)
+		}
+	}
+
+	if output != "" {
+		// The output is kept past the run, so sensitive values are masked just as they are when printed
+		target := filepath.Join(dest, artifactOutputFile)
+		err := helpers.CreateParentDirectory(target)
+		if err == nil {
+			err = os.WriteFile(target, []byte(message.Mask(output)), helpers.ReadWriteUser)
+		}
+		if err != nil {
+			message.SLog.Warn(fmt.Sprintf("Unable to save the output of the failed action: %s", err.Error()))
+		} else {
+			collected = append(collected, target)
+		}
+	}
+
+	if len(collected) > 0 {
+		message.SLog.Info(fmt.Sprintf("Collected %d artifacts of the failed action into %s", len(collected), dest))
+	}
+	return collected
+}
+
+// artifactPath returns where an artifact is placed within the artifacts directory of its action, keeping the layout of
+// artifacts under the action's dir and using the name of any others
+func artifactPath(baseDir, path string) string {
+	if baseDir == "" {
+		baseDir = "."
+	}
+	rel, err := filepath.Rel(baseDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.Base(path)
+	}
+	return rel
+}
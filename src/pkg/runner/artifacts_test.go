@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_artifactPath(t *testing.T) {
+	require.Equal(t, filepath.Join("logs", "app.log"), artifactPath("", filepath.Join("logs", "app.log")))
+	require.Equal(t, "app.log", artifactPath("build", filepath.Join("build", "app.log")))
+	require.Equal(t, "other.log", artifactPath("build", "other.log"))
+	require.Equal(t, "syslog", artifactPath("", filepath.Join(string(filepath.Separator), "var", "log", "syslog")))
+}
+
+func TestRunner_collectArtifacts(t *testing.T) {
+	workDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(workDir, "logs"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "logs", "a.log"), []byte("a"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "logs", "b.log"), []byte("b"), 0600))
+
+	config.ArtifactsDirectory = t.TempDir()
+	t.Cleanup(func() { config.ArtifactsDirectory = "" })
+
+	task := types.Task{
+		Name: "lib:build",
+		Actions: []types.Action{
+			{
+				BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: "echo failing && exit 1", Dir: &workDir},
+				Artifacts:  []string{"logs/*.log", "missing.txt"},
+			},
+		},
+	}
+	r := &Runner{runID: "run", variableConfig: GetMaruVariableConfig(), tasksFile: types.TasksFile{Tasks: []types.Task{task}}}
+	err := r.executeTask(context.Background(), task, nil)
+	require.Error(t, err)
+
+	dest := filepath.Join(config.ArtifactsDirectory, "run", "lib-build-0")
+	require.Equal(t, []string{
+		filepath.Join(dest, "logs", "a.log"),
+		filepath.Join(dest, "logs", "b.log"),
+		filepath.Join(dest, artifactOutputFile),
+	}, r.results.Actions[0].Artifacts)
+	b, err := os.ReadFile(filepath.Join(dest, artifactOutputFile))
+	require.NoError(t, err)
+	require.Contains(t, string(b), "failing")
+
+	// Nothing is collected when the action succeeds or in a dry run
+	task.Actions[0].Cmd = "true"
+	r.results = RunResults{}
+	require.NoError(t, r.executeTask(context.Background(), task, nil))
+	require.Empty(t, r.results.Actions[0].Artifacts)
+
+	task.Actions[0].Cmd = "exit 1"
+	r = &Runner{runID: "dry", dryRun: true, variableConfig: GetMaruVariableConfig()}
+	require.Empty(t, r.collectArtifacts(task, 0, task.Actions[0], nil, "output"))
+	require.NoDirExists(t, filepath.Join(config.ArtifactsDirectory, "dry"))
+}
+
+func TestRunner_collectArtifacts_masksOutput(t *testing.T) {
+	config.ArtifactsDirectory = t.TempDir()
+	t.Cleanup(func() { config.ArtifactsDirectory = "" })
+	t.Cleanup(message.ClearSensitive)
+	message.AddSensitive("hunter22")
+
+	action := types.Action{
+		BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: "exit 1"},
+		Artifacts:  []string{"missing.txt"},
+	}
+	r := &Runner{runID: "run", variableConfig: GetMaruVariableConfig()}
+	collected := r.collectArtifacts(types.Task{Name: "login"}, 0, action, nil, "logging in with hunter22\n")
+	require.Equal(t, []string{filepath.Join(config.ArtifactsDirectory, "run", "login-0", artifactOutputFile)}, collected)
+
+	b, err := os.ReadFile(collected[0])
+	require.NoError(t, err)
+	require.Equal(t, "logging in with "+message.SanitizedValue+"\n", string(b))
+}
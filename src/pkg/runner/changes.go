@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
+	"github.com/defenseunicorns/pkg/helpers/v2"
+)
+
+const (
+	// OnChangeWarn warns when a tasks file changes during a run and keeps running the definitions the run started with
+	OnChangeWarn = "warn"
+	// OnChangeFail stops a run at the next task once a tasks file changes during it
+	OnChangeFail = "fail"
+)
+
+// watchedFile is the state of a local tasks file when a run loaded it
+type watchedFile struct {
+	modTime time.Time
+	size    int64
+	digest  string
+	changed bool
+}
+
+// validateOnChange checks that the behavior for tasks files that change during a run is known
+func validateOnChange(onChange string) error {
+	if onChange != "" && onChange != OnChangeWarn && onChange != OnChangeFail {
+		return fmt.Errorf("invalid --on-change value %q, must be %q or %q", onChange, OnChangeWarn, OnChangeFail)
+	}
+	return nil
+}
+
+// watchTasksFiles records the state of the local tasks files the run was loaded from (the root tasks file and any local
+// includes) so changes to them can be noticed while the run is going
+func (r *Runner) watchTasksFiles() {
+	r.watchedFiles = map[string]*watchedFile{}
+	for _, path := range r.localTasksFiles() {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		r.watchedFiles[path] = &watchedFile{modTime: info.ModTime(), size: info.Size(), digest: utils.Digest(contents)}
+	}
+}
+
+// localTasksFiles returns the absolute paths of the local tasks files the run was loaded from (the root tasks file and
+// any includes that are not URLs)
+func (r *Runner) localTasksFiles() []string {
+	locations := []string{config.TaskFileLocation}
+	for _, include := range r.results.Includes {
+		locations = append(locations, include.Source)
+	}
+
+	paths := []string{}
+	for _, location := range locations {
+		if location == "" || helpers.IsURL(location) {
+			continue
+		}
+		if path, err := filepath.Abs(location); err == nil && !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// checkTasksFiles checks whether any of the watched tasks files changed before a task starts. The run only ever uses
+// the definitions it was loaded with (reloading part way through would mix old and new definitions), so a change is
+// either warned about (once per file) or stops the run before the task, depending on config.OnChange.
+func (r *Runner) checkTasksFiles(taskName string) error {
+	paths := []string{}
+	for path, watched := range r.watchedFiles {
+		if watched.changed {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err == nil && info.ModTime().Equal(watched.modTime) && info.Size() == watched.size {
+			continue
+		}
+		// The file was touched or removed, only treat it as changed if its contents are different
+		if err == nil {
+			if contents, err := os.ReadFile(path); err == nil && utils.Digest(contents) == watched.digest {
+				watched.modTime, watched.size = info.ModTime(), info.Size()
+				continue
+			}
+		}
+		watched.changed = true
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	for _, path := range paths {
+		if config.OnChange == OnChangeFail {
+			return fmt.Errorf("%s changed during the run, stopping before task %s so old and new task definitions are not mixed (run again to use the new definitions)", path, taskName)
+		}
+		message.SLog.Warn(fmt.Sprintf("%s changed during the run, the run continues with the task definitions it started with", path))
+	}
+	return nil
+}
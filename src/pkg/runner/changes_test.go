@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_checkTasksFiles(t *testing.T) {
+	location, onChange := config.TaskFileLocation, config.OnChange
+	t.Cleanup(func() {
+		config.TaskFileLocation, config.OnChange = location, onChange
+	})
+
+	dir := t.TempDir()
+	config.TaskFileLocation = filepath.Join(dir, "tasks.yaml")
+	include := filepath.Join(dir, "include.yaml")
+	require.NoError(t, os.WriteFile(config.TaskFileLocation, []byte("tasks: []\n"), 0600))
+	require.NoError(t, os.WriteFile(include, []byte("tasks: []\n"), 0600))
+
+	newRunner := func() *Runner {
+		r := &Runner{results: RunResults{Includes: []IncludeSource{
+			{Name: "local", Source: include},
+			{Name: "remote", Source: "https://example.com/tasks.yaml"},
+		}}}
+		r.watchTasksFiles()
+		return r
+	}
+
+	t.Run("unchanged and touched files", func(t *testing.T) {
+		config.OnChange = OnChangeFail
+		r := newRunner()
+		require.Len(t, r.watchedFiles, 2)
+		require.NoError(t, r.checkTasksFiles("build"))
+
+		later := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(include, later, later))
+		require.NoError(t, r.checkTasksFiles("build"))
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		config.OnChange = ""
+		r := newRunner()
+		require.NoError(t, os.WriteFile(include, []byte("tasks:\n  - name: new\n"), 0600))
+		require.NoError(t, r.checkTasksFiles("build"))
+		require.True(t, r.watchedFiles[include].changed)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		config.OnChange = OnChangeFail
+		r := newRunner()
+		require.NoError(t, os.Remove(config.TaskFileLocation))
+		require.EqualError(t, r.checkTasksFiles("build"), config.TaskFileLocation+" changed during the run, stopping before task build so old and new task definitions are not mixed (run again to use the new definitions)")
+	})
+}
+
+func TestRunner_executeTask_onChange(t *testing.T) {
+	location, onChange := config.TaskFileLocation, config.OnChange
+	t.Cleanup(func() {
+		config.TaskFileLocation, config.OnChange = location, onChange
+	})
+	config.TaskFileLocation = filepath.Join(t.TempDir(), "tasks.yaml")
+	config.OnChange = OnChangeFail
+	require.NoError(t, os.WriteFile(config.TaskFileLocation, []byte("tasks: []\n"), 0600))
+
+	// The task running when the file changes finishes, but the next task does not start
+	marker := filepath.Join(t.TempDir(), "ran")
+	cmd := func(cmd string) types.Action {
+		return types.Action{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: cmd}}
+	}
+	tasks := []types.Task{
+		{Name: "default", Actions: []types.Action{
+			cmd("echo '# changed' >> " + config.TaskFileLocation),
+			cmd("echo same task"),
+			{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{}, TaskReference: "next"},
+		}},
+		{Name: "next", Actions: []types.Action{cmd("touch " + marker)}},
+	}
+	r := &Runner{variableConfig: GetMaruVariableConfig(), tasksFile: types.TasksFile{Tasks: tasks}}
+	r.watchTasksFiles()
+
+	err := r.executeTask(context.Background(), tasks[0], nil)
+	require.ErrorContains(t, err, "stopping before task next")
+	require.Len(t, r.results.Actions, 3)
+	require.NoFileExists(t, marker)
+}
+
+func Test_validateOnChange(t *testing.T) {
+	require.NoError(t, validateOnChange(""))
+	require.NoError(t, validateOnChange(OnChangeWarn))
+	require.NoError(t, validateOnChange(OnChangeFail))
+	require.EqualError(t, validateOnChange("reload"), `invalid --on-change value "reload", must be "warn" or "fail"`)
+}
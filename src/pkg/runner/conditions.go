@@ -0,0 +1,42 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/migrate"
+)
+
+// evaluateCondition returns whether an action runs given its `if` condition as written (a boolean or a template
+// expression) and as evaluated. Conditions are typed: they must evaluate to true or false. For compatibility, a
+// condition that evaluates to anything else still runs the action (as it always has) but warns that it should be fixed.
+func evaluateCondition(condition, evaluated string) bool {
+	switch evaluated {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	message.SLog.Warn(fmt.Sprintf("The condition %q evaluated to %q rather than true or false, the action runs for now but conditions that are not booleans are deprecated", condition, evaluated))
+	return true
+}
+
+// warnDeprecatedSyntax warns about every line of the local tasks files of a run that uses deprecated syntax (i.e. an
+// `if` that is the string "false" rather than a boolean) along with how to migrate it
+func (r *Runner) warnDeprecatedSyntax() {
+	for _, path := range r.localTasksFiles() {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		_, changes := migrate.Migrate(path, contents)
+		for _, change := range changes {
+			message.SLog.Warn(fmt.Sprintf("%s (run 'maru migrate -f %s' to update it)", change.String(), path))
+		}
+	}
+}
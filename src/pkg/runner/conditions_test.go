@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_evaluateCondition(t *testing.T) {
+	require.True(t, evaluateCondition("true", "true"))
+	require.False(t, evaluateCondition("false", "false"))
+	require.False(t, evaluateCondition(`${{ eq .variables.FOO "bar" }}`, "false"))
+
+	// Conditions that are not booleans keep running the action for compatibility
+	require.True(t, evaluateCondition("${{ .variables.FOO }}", ""))
+	require.True(t, evaluateCondition("False", "False"))
+	require.True(t, evaluateCondition("no", "no"))
+}
+
+func TestRunner_performAction_conditions(t *testing.T) {
+	variableConfig := GetMaruVariableConfig()
+	variableConfig.SetVariable("ENV", "prod", "", variables.ExtraVariableInfo{})
+	r := &Runner{variableConfig: variableConfig}
+
+	tests := []struct {
+		condition string
+		skipped   bool
+	}{
+		{condition: "", skipped: false},
+		{condition: "true", skipped: false},
+		{condition: "false", skipped: true},
+		{condition: `${{ eq .variables.ENV "prod" }}`, skipped: false},
+		{condition: `${{ eq .variables.ENV "dev" }}`, skipped: true},
+		{condition: "${{ .variables.ENV }}", skipped: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			action := types.Action{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: "echo ran"}, If: tt.condition}
+			skipped, _, err := r.performAction(context.Background(), action, nil, nil, utils.TemplateOptions{})
+			require.NoError(t, err)
+			require.Equal(t, tt.skipped, skipped)
+		})
+	}
+}
@@ -0,0 +1,453 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	osexec "os/exec"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/defenseunicorns/pkg/exec"
+	"github.com/defenseunicorns/pkg/helpers/v2"
+)
+
+// DoctorStatus is the outcome of a check made by Doctor
+type DoctorStatus string
+
+const (
+	// DoctorOK means the check passed
+	DoctorOK DoctorStatus = "ok"
+	// DoctorWarn means the check found something that may stop some tasks from running
+	DoctorWarn DoctorStatus = "warn"
+	// DoctorFail means the check found something that will stop tasks from running
+	DoctorFail DoctorStatus = "fail"
+)
+
+// DoctorCheck is the outcome of a check made by Doctor along with how to fix any problem it found
+type DoctorCheck struct {
+	Name   string
+	Status DoctorStatus
+	Detail string
+	Fix    string
+}
+
+// includeFix is how to fix an include that cannot be fetched
+const includeFix = "check the URL and your network connection, and run 'maru auth login <host>' if the host needs a token"
+
+// doctorKubeTimeout bounds how long the Kubernetes connectivity check can take
+const doctorKubeTimeout = "5s"
+
+// shellWords are the shell keywords and builtins that are never tools on the PATH
+var shellWords = []string{
+	"!", ".", ":", "[", "[[", "alias", "bg", "break", "case", "cd", "command", "continue", "do", "done", "echo", "elif",
+	"else", "esac", "eval", "exec", "exit", "export", "false", "fg", "fi", "for", "function", "getopts", "hash", "if",
+	"in", "jobs", "kill", "local", "printf", "pwd", "read", "readonly", "return", "select", "set", "shift", "source",
+	"test", "then", "time", "times", "trap", "true", "type", "ulimit", "umask", "unalias", "unset", "until", "wait",
+	"while", "{", "}",
+}
+
+var (
+	// toolNameRegex matches a command that is looked up on the PATH (rather than a path, variable or template)
+	toolNameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+-]*$`)
+	// functionRegex matches the definition of a shell function
+	functionRegex = regexp.MustCompile(`(?m)^\s*(?:function\s+([A-Za-z_][\w-]*)|([A-Za-z_][\w-]*)\s*\(\s*\))`)
+)
+
+// Doctor checks that this machine can run the tasks in a tasks file (along with its includes): that the tasks file is
+// valid, that its includes can be reached, that the shells and tools its tasks use are installed and that the cluster
+// can be reached when tasks need it. Problems are returned with how to fix them.
+func Doctor(ctx context.Context, tasksFile types.TasksFile, setVariables map[string]string, auth map[string]string) []DoctorCheck {
+	tasksFileCheck := DoctorCheck{
+		Name:   "tasks file",
+		Status: DoctorFail,
+		Fix:    fmt.Sprintf("run 'maru validate --file %s' and fix the problems it reports", config.TaskFileLocation),
+	}
+	if err := validateTaskNames(config.TaskFileLocation, tasksFile); err != nil {
+		tasksFileCheck.Detail = err.Error()
+		return []DoctorCheck{tasksFileCheck}
+	}
+	variableConfig := GetMaruVariableConfig()
+	if err := variableConfig.PopulateVariables(tasksFile.Variables, setVariables); err != nil {
+		tasksFileCheck.Detail = err.Error()
+		return []DoctorCheck{tasksFileCheck}
+	}
+
+	// Load every task that could run (along with the includes they come from) so what they need can be checked
+	r := Runner{
+		tasksFile:                       tasksFile,
+		existingTaskIncludeNameLocation: map[string]string{},
+		taskFileLocations:               map[string]string{},
+		auth:                            auth,
+		variableConfig:                  variableConfig,
+		dryRun:                          true,
+	}
+	checks := []DoctorCheck{}
+	if err := r.importTasks(tasksFile.Includes, config.TaskFileLocation, setVariables); err != nil {
+		checks = append(checks, DoctorCheck{Name: "includes", Status: DoctorFail, Detail: err.Error(), Fix: includeFix})
+	} else if errs := r.validate(setVariables); len(errs) > 0 {
+		tasksFileCheck.Detail = fmt.Sprintf("%d problems, the first is: %s", len(errs), errs[0].Error())
+		checks = append(checks, tasksFileCheck)
+	} else {
+		checks = append(checks, DoctorCheck{Name: "tasks file", Status: DoctorOK, Detail: fmt.Sprintf("%s is valid", config.TaskFileLocation)})
+	}
+
+	checks = append(checks, r.checkIncludes(auth)...)
+	checks = append(checks, r.checkShellsAndTools()...)
+	checks = append(checks, r.checkPolicy()...)
+	checks = append(checks, r.checkKubernetes(ctx)...)
+	return checks
+}
+
+// checkPolicy checks that the command policy (if there is one) can be loaded and allows the commands of every task
+func (r *Runner) checkPolicy() []DoctorCheck {
+	p, err := LoadPolicy()
+	if err != nil {
+		return []DoctorCheck{{Name: "policy", Status: DoctorFail, Detail: err.Error(), Fix: "ask the administrator of this machine to fix the command policy"}}
+	}
+	if p == nil {
+		return nil
+	}
+
+	checks := []DoctorCheck{}
+	for _, task := range r.tasksFile.Tasks {
+		for idx, action := range task.Actions {
+			if action.BaseAction == nil || action.Cmd == "" {
+				continue
+			}
+			if err := p.check(action.Cmd); err != nil {
+				checks = append(checks, DoctorCheck{
+					Name:   fmt.Sprintf("policy %s[%d]", task.Name, idx),
+					Status: DoctorWarn,
+					Detail: err.Error(),
+					Fix:    "change the command or ask the administrator of this machine to allow it",
+				})
+			}
+		}
+	}
+	if len(checks) == 0 {
+		checks = append(checks, DoctorCheck{Name: "policy", Status: DoctorOK, Detail: fmt.Sprintf("every command is allowed by %s", PolicyFile())})
+	}
+	return checks
+}
+
+// checkIncludes checks that every remote include can be fetched (rather than only being available from the cache)
+func (r *Runner) checkIncludes(auth map[string]string) []DoctorCheck {
+	checks := []DoctorCheck{}
+	for _, include := range r.results.Includes {
+		if !helpers.IsURL(include.Source) {
+			continue
+		}
+		name := fmt.Sprintf("include %s", include.Name)
+		// Offline, all that matters is that the include can be read from the cache
+		if config.Offline {
+			if utils.IncludeCached(include.Source, auth) {
+				checks = append(checks, DoctorCheck{Name: name, Status: DoctorOK, Detail: fmt.Sprintf("%s is cached (not checked as maru is offline)", include.Source)})
+			} else {
+				checks = append(checks, DoctorCheck{
+					Name:   name,
+					Status: DoctorFail,
+					Detail: fmt.Sprintf("%s is not cached and maru is offline", include.Source),
+					Fix:    "run once without --offline to cache it",
+				})
+			}
+			continue
+		}
+		if err := utils.CheckRemoteFile(include.Source, auth); err != nil {
+			checks = append(checks, DoctorCheck{
+				Name:   name,
+				Status: DoctorFail,
+				Detail: err.Error(),
+				Fix:    includeFix,
+			})
+			continue
+		}
+		checks = append(checks, DoctorCheck{Name: name, Status: DoctorOK, Detail: fmt.Sprintf("%s is reachable", include.Source)})
+	}
+	return checks
+}
+
+// checkShellsAndTools checks that the shells and the tools on the PATH that the tasks use are installed
+func (r *Runner) checkShellsAndTools() []DoctorCheck {
+	defaultShell, _ := exec.GetOSShell(exec.ShellPreference{})
+	shells := map[string][]string{defaultShell: nil}
+	tools := map[string][]string{}
+	use := func(uses map[string][]string, name, task string) {
+		if !slices.Contains(uses[name], task) {
+			uses[name] = append(uses[name], task)
+		}
+	}
+
+	for _, v := range r.tasksFile.Variables {
+		if v.Extra.FromK8s != "" {
+			use(tools, "kubectl", fmt.Sprintf("variable %s", v.Name))
+		}
+	}
+	for _, task := range r.tasksFile.Tasks {
+		for _, action := range task.Actions {
+			if action.Tunnel != nil {
+				use(tools, "ssh", task.Name)
+			}
+			if action.BaseAction == nil {
+				continue
+			}
+			if action.Wait != nil && !isHTTPWait(action.Wait) {
+				use(tools, "zarf", task.Name)
+			}
+			if action.Cmd == "" {
+				continue
+			}
+			shellPref := exec.ShellPreference{}
+			if action.Shell != nil {
+				shellPref = *action.Shell
+			}
+			shell, _ := exec.GetOSShell(shellPref)
+			use(shells, shell, task.Name)
+			if !isPOSIXShell(shell) {
+				continue
+			}
+			for _, tool := range commandTools(action.Cmd) {
+				use(tools, tool, task.Name)
+			}
+		}
+	}
+
+	checks := []DoctorCheck{}
+	for _, shell := range sortedKeys(shells) {
+		name := fmt.Sprintf("shell %s", shell)
+		if path, err := osexec.LookPath(shell); err == nil {
+			checks = append(checks, DoctorCheck{Name: name, Status: DoctorOK, Detail: path})
+			continue
+		}
+		check := DoctorCheck{
+			Name:   name,
+			Status: DoctorFail,
+			Detail: fmt.Sprintf("%s is not installed", shell),
+			Fix:    fmt.Sprintf("install %s or change the %s shell of the actions that use it", shell, runtime.GOOS),
+		}
+		if len(shells[shell]) > 0 {
+			check.Detail = fmt.Sprintf("%s is not installed (used by %s)", shell, strings.Join(shells[shell], ", "))
+		}
+		checks = append(checks, check)
+	}
+
+	found := []string{}
+	for _, tool := range sortedKeys(tools) {
+		if _, err := osexec.LookPath(tool); err == nil {
+			found = append(found, tool)
+			continue
+		}
+		checks = append(checks, DoctorCheck{
+			Name:   fmt.Sprintf("tool %s", tool),
+			Status: DoctorWarn,
+			Detail: fmt.Sprintf("%s is not on the PATH (used by %s)", tool, strings.Join(tools[tool], ", ")),
+			Fix:    fmt.Sprintf("install %s or add the directory it is in to your PATH", tool),
+		})
+	}
+	if len(found) > 0 {
+		checks = append(checks, DoctorCheck{Name: "tools", Status: DoctorOK, Detail: strings.Join(found, ", ")})
+	}
+	return checks
+}
+
+// checkKubernetes checks that the current kubecontext can be reached (failing if tasks need the cluster and otherwise
+// only checking when kubectl is installed)
+func (r *Runner) checkKubernetes(ctx context.Context) []DoctorCheck {
+	needed := []string{}
+	for _, v := range r.tasksFile.Variables {
+		if v.Extra.FromK8s != "" {
+			needed = append(needed, fmt.Sprintf("variable %s", v.Name))
+		}
+	}
+	for _, task := range r.tasksFile.Tasks {
+		for _, action := range task.Actions {
+			if action.BaseAction != nil && action.Wait != nil && action.Wait.Cluster != nil && !slices.Contains(needed, task.Name) {
+				needed = append(needed, task.Name)
+			}
+		}
+	}
+
+	if _, err := osexec.LookPath("kubectl"); err != nil && len(needed) == 0 {
+		return nil
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := kubectlCommand(ctx, "version", "--output", "json", "--request-timeout", doctorKubeTimeout)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		check := DoctorCheck{
+			Name:   "kubernetes",
+			Status: DoctorWarn,
+			Detail: fmt.Sprintf("unable to reach the cluster: %s", firstLine(stderr.String(), err)),
+			Fix:    "start the cluster or switch to one that is running with 'kubectl config use-context <context>'",
+		}
+		if len(needed) > 0 {
+			check.Status = DoctorFail
+			check.Detail = fmt.Sprintf("%s (needed by %s)", check.Detail, strings.Join(needed, ", "))
+		}
+		return []DoctorCheck{check}
+	}
+
+	var version struct {
+		ServerVersion struct {
+			GitVersion string `json:"gitVersion"`
+		} `json:"serverVersion"`
+	}
+	detail := "the cluster is reachable"
+	if json.Unmarshal(stdout.Bytes(), &version) == nil && version.ServerVersion.GitVersion != "" {
+		detail = fmt.Sprintf("the cluster is reachable (Kubernetes %s)", version.ServerVersion.GitVersion)
+	}
+	return []DoctorCheck{{Name: "kubernetes", Status: DoctorOK, Detail: detail}}
+}
+
+// commandTools returns the commands that a POSIX shell script looks up on the PATH (skipping shell builtins, functions
+// the script defines and anything that is a path, variable or template)
+func commandTools(script string) []string {
+	functions := []string{}
+	for _, match := range functionRegex.FindAllStringSubmatch(script, -1) {
+		functions = append(functions, match[1]+match[2])
+	}
+
+	tools := []string{}
+	lines := strings.Split(script, "\n")
+	for i, markable := range markableLines(lines) {
+		if !markable {
+			continue
+		}
+		for _, word := range commandWords(lines[i]) {
+			if toolNameRegex.MatchString(word) && !slices.Contains(shellWords, word) && !slices.Contains(functions, word) &&
+				!slices.Contains(tools, word) {
+				tools = append(tools, word)
+			}
+		}
+	}
+	return tools
+}
+
+// commandWords returns the words that are in command position on a line of a POSIX shell script (the first word of
+// each command in a list or pipeline, skipping variable assignments, keywords and redirections)
+func commandWords(line string) []string {
+	var (
+		words        []string
+		word         strings.Builder
+		quote        byte
+		commandStart = true
+		redirect     bool
+	)
+	endWord := func() {
+		if word.Len() == 0 {
+			return
+		}
+		w := word.String()
+		word.Reset()
+		switch {
+		case redirect:
+			redirect = false
+		case !commandStart:
+		case strings.Contains(w, "=") && !strings.HasPrefix(w, "="):
+			// Variable assignments come before the command
+		case slices.Contains([]string{"!", "if", "then", "else", "elif", "while", "until", "do", "{", "time", "exec", "command"}, w):
+			// These are followed by another command
+		default:
+			words = append(words, w)
+			commandStart = false
+		}
+	}
+
+	for j := 0; j < len(line); j++ {
+		c := line[j]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				j++
+			}
+			word.WriteByte(c)
+			continue
+		}
+		switch c {
+		case '\'', '"':
+			quote = c
+			word.WriteByte(c)
+		case '\\':
+			word.WriteByte(c)
+			if j+1 < len(line) {
+				word.WriteByte(line[j+1])
+				j++
+			}
+		case '$':
+			// Expansions are part of the word they are in
+			end := j + 1
+			if end < len(line) && (line[end] == '(' || line[end] == '{') {
+				open, depth := line[end], 0
+				for ; end < len(line); end++ {
+					if line[end] == open {
+						depth++
+					} else if (open == '(' && line[end] == ')') || (open == '{' && line[end] == '}') {
+						if depth--; depth == 0 {
+							break
+						}
+					}
+				}
+			}
+			end = min(end, len(line)-1)
+			word.WriteString(line[j : end+1])
+			j = end
+		case '#':
+			if word.Len() == 0 {
+				endWord()
+				return words
+			}
+			word.WriteByte(c)
+		case ' ', '\t':
+			endWord()
+		case '<', '>':
+			// A file descriptor (i.e. 2>) is part of the redirection rather than a word
+			if w := word.String(); w != "" && strings.Trim(w, "0123456789") == "" {
+				word.Reset()
+			}
+			endWord()
+			for j+1 < len(line) && strings.ContainsRune("<>&|", rune(line[j+1])) {
+				j++
+			}
+			redirect = true
+		case '|', '&', ';', '(', ')':
+			endWord()
+			commandStart = true
+		default:
+			word.WriteByte(c)
+		}
+	}
+	endWord()
+	return words
+}
+
+// firstLine returns the first line of a command's stderr (or its error if it wrote nothing)
+func firstLine(stderr string, err error) string {
+	if line, _, _ := strings.Cut(strings.TrimSpace(stderr), "\n"); line != "" {
+		return line
+	}
+	return err.Error()
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_commandTools(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "pipelines and lists",
+			script: "curl -s https://example.com | jq .name && git status; helm version || true",
+			want:   []string{"curl", "jq", "git", "helm"},
+		},
+		{
+			name:   "builtins, paths, variables and templates are skipped",
+			script: "echo hi\ncd /tmp\n./build.sh\n${ZARF} tools\n$(which docker) ps\n{{ .inputs.cmd }}",
+			want:   []string{},
+		},
+		{
+			name:   "assignments, keywords and redirections",
+			script: "FOO=bar make build 2>&1 >/dev/null\nif grep -q x file; then\n  sed -i s/a/b/ file\nfi\nwhile read -r line; do wc -l; done < input",
+			want:   []string{"make", "grep", "sed", "wc"},
+		},
+		{
+			name:   "quotes, expansions and functions",
+			script: "check() {\n  echo \"not | a tool\"\n}\ncheck\nfunction other {\n  true\n}\nother\necho $((COUNT - 1)) | tee out\ncat <<EOF\nnot a tool\nEOF",
+			want:   []string{"tee", "cat"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, commandTools(tt.script))
+		})
+	}
+}
+
+func TestDoctor(t *testing.T) {
+	useHelperKubectl(t)
+
+	dir := t.TempDir()
+	tasksFileLocation := filepath.Join(dir, "tasks.yaml")
+	contents := `includes:
+  - lib: ./lib.yaml
+variables:
+  - name: DOMAIN
+    fromK8s: platform/app-config/domain
+tasks:
+  - name: build
+    actions:
+      - cmd: maru-doctor-missing-tool --version | cat
+      - task: lib:test
+`
+	require.NoError(t, os.WriteFile(tasksFileLocation, []byte(contents), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib.yaml"), []byte("tasks:\n  - name: test\n    actions:\n      - cmd: sh -c true\n"), 0600))
+
+	previousLocation := config.TaskFileLocation
+	config.TaskFileLocation = tasksFileLocation
+	t.Cleanup(func() { config.TaskFileLocation = previousLocation })
+
+	var tasksFile types.TasksFile
+	require.NoError(t, utils.ReadYaml(tasksFileLocation, &tasksFile))
+	checks := map[string]DoctorCheck{}
+	for _, check := range Doctor(context.TODO(), tasksFile, nil, nil) {
+		checks[check.Name] = check
+	}
+
+	require.Equal(t, DoctorOK, checks["tasks file"].Status)
+	require.Equal(t, DoctorOK, checks["shell sh"].Status)
+	require.Equal(t, DoctorOK, checks["tools"].Status)
+	require.Contains(t, checks["tools"].Detail, "cat")
+	require.Equal(t, DoctorWarn, checks["tool maru-doctor-missing-tool"].Status)
+	require.Equal(t, "maru-doctor-missing-tool is not on the PATH (used by build)", checks["tool maru-doctor-missing-tool"].Detail)
+	require.Equal(t, "install maru-doctor-missing-tool or add the directory it is in to your PATH", checks["tool maru-doctor-missing-tool"].Fix)
+	require.Equal(t, DoctorOK, checks["kubernetes"].Status)
+	require.Equal(t, "the cluster is reachable (Kubernetes v1.30.2)", checks["kubernetes"].Detail)
+
+	// Commands the command policy does not allow are flagged
+	usePolicy(t, "deny:\n  - pattern: maru-doctor-missing-tool\n")
+	checks = map[string]DoctorCheck{}
+	for _, check := range Doctor(context.TODO(), tasksFile, nil, nil) {
+		checks[check.Name] = check
+	}
+	require.Equal(t, DoctorWarn, checks["policy build[0]"].Status)
+	require.Contains(t, checks["policy build[0]"].Detail, `it matches the denied pattern "maru-doctor-missing-tool"`)
+	require.NotContains(t, checks, "policy")
+
+	// Broken references are reported with how to find every problem
+	tasksFile.Tasks[0].Actions[1].TaskReference = "lib:missing"
+	checks = map[string]DoctorCheck{}
+	for _, check := range Doctor(context.TODO(), tasksFile, nil, nil) {
+		checks[check.Name] = check
+	}
+	require.Equal(t, DoctorFail, checks["tasks file"].Status)
+	require.Contains(t, checks["tasks file"].Detail, "1 problems, the first is:")
+	require.Contains(t, checks["tasks file"].Fix, "run 'maru validate --file")
+}
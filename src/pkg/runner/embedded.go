@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"fmt"
+	"io/fs"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/defenseunicorns/pkg/helpers/v2"
+	goyaml "github.com/goccy/go-yaml"
+)
+
+// embeddedTasksFiles are the tasks files registered by an application embedding Maru, in the order they were registered
+var embeddedTasksFiles []types.TasksFile
+
+// RegisterEmbeddedTasks registers a tasks file read from an fs.FS (i.e. an embed.FS) whose tasks, variables and includes
+// are available alongside those of every tasks file that is run. A task, variable or include in the tasks file on disk
+// overrides an embedded one with the same name, and an earlier registered file overrides a later one. Embedded files can
+// only include URLs and cannot change their template delimiters, since they have no directory of their own.
+func RegisterEmbeddedTasks(fsys fs.FS, path string) error {
+	contents, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return fmt.Errorf("unable to read the embedded tasks file %s: %w", path, err)
+	}
+	if err := utils.CheckAliases(contents); err != nil {
+		return fmt.Errorf("cannot unmarshal the embedded tasks file %s: %w", path, err)
+	}
+	var tasksFile types.TasksFile
+	if err := goyaml.Unmarshal(contents, &tasksFile); err != nil {
+		return fmt.Errorf("cannot unmarshal the embedded tasks file %s: %s", path, strings.SplitN(err.Error(), "\n", 2)[0])
+	}
+
+	if tasksFile.TemplateDelims != nil {
+		return fmt.Errorf("the embedded tasks file %s cannot set templateDelims", path)
+	}
+	for _, include := range tasksFile.Includes {
+		for name, location := range include {
+			if !helpers.IsURL(location) {
+				return fmt.Errorf("the embedded tasks file %s can only include URLs, not %s: %s", path, name, location)
+			}
+		}
+	}
+	if err := validateTaskNames(path, tasksFile); err != nil {
+		return err
+	}
+
+	embeddedTasksFiles = append(embeddedTasksFiles, tasksFile)
+	return nil
+}
+
+// HasEmbeddedTasks returns whether any embedded tasks files have been registered
+func HasEmbeddedTasks() bool {
+	return len(embeddedTasksFiles) > 0
+}
+
+// ClearEmbeddedTasks removes every registered embedded tasks file
+func ClearEmbeddedTasks() {
+	embeddedTasksFiles = nil
+}
+
+// embeddedTaskNames returns the names of the tasks in a tasks file that are embedded tasks (rather than tasks on disk
+// that override them)
+func embeddedTaskNames(tasksFile types.TasksFile) map[string]bool {
+	names := map[string]bool{}
+	for _, task := range tasksFile.Tasks {
+		for _, embedded := range embeddedTasksFiles {
+			if idx := slices.IndexFunc(embedded.Tasks, func(t types.Task) bool { return t.Name == task.Name }); idx >= 0 {
+				names[task.Name] = reflect.DeepEqual(embedded.Tasks[idx], task)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// MergeEmbeddedTasks returns a tasks file with the tasks, variables and includes of the registered embedded tasks files
+// added after its own, skipping any that the tasks file (or an earlier registered file) already defines
+func MergeEmbeddedTasks(tasksFile types.TasksFile) types.TasksFile {
+	merged := tasksFile
+	merged.Tasks = slices.Clone(tasksFile.Tasks)
+	merged.Variables = slices.Clone(tasksFile.Variables)
+	merged.Includes = slices.Clone(tasksFile.Includes)
+
+	for _, embedded := range embeddedTasksFiles {
+		for _, task := range embedded.Tasks {
+			if !slices.ContainsFunc(merged.Tasks, func(t types.Task) bool { return t.Name == task.Name }) {
+				// The actions are copied so resolving references during a run does not change the registered file
+				task.Actions = slices.Clone(task.Actions)
+				merged.Tasks = append(merged.Tasks, task)
+			}
+		}
+		for _, variable := range embedded.Variables {
+			defined := func(v variables.InteractiveVariable[variables.ExtraVariableInfo]) bool {
+				return v.Name == variable.Name
+			}
+			if !slices.ContainsFunc(merged.Variables, defined) {
+				merged.Variables = append(merged.Variables, variable)
+			}
+		}
+		for _, include := range embedded.Includes {
+			for name, location := range include {
+				if !slices.ContainsFunc(merged.Includes, func(i map[string]string) bool { _, ok := i[name]; return ok }) {
+					merged.Includes = append(merged.Includes, map[string]string{name: location})
+				}
+			}
+		}
+	}
+	return merged
+}
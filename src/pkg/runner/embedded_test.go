@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterEmbeddedTasks(t *testing.T) {
+	t.Cleanup(ClearEmbeddedTasks)
+	fsys := fstest.MapFS{
+		"tasks/builtin.yaml": {Data: []byte(`includes:
+  - remote: https://example.com/tasks.yaml
+variables:
+  - name: REGISTRY
+    default: ghcr.io
+tasks:
+  - name: lint
+    actions:
+      - cmd: echo builtin lint
+  - name: release
+    actions:
+      - cmd: echo builtin release
+`)},
+		"tasks/extra.yaml":   {Data: []byte("tasks:\n  - name: release\n  - name: docs\n")},
+		"tasks/local.yaml":   {Data: []byte("includes:\n  - lib: ./lib.yaml\ntasks: []\n")},
+		"tasks/delims.yaml":  {Data: []byte("templateDelims:\n  left: '[['\n  right: ']]'\ntasks: []\n")},
+		"tasks/invalid.yaml": {Data: []byte("tasks:\n  - name: -lint\n")},
+	}
+
+	require.ErrorContains(t, RegisterEmbeddedTasks(fsys, "tasks/missing.yaml"), "unable to read the embedded tasks file tasks/missing.yaml")
+	require.EqualError(t, RegisterEmbeddedTasks(fsys, "tasks/local.yaml"), "the embedded tasks file tasks/local.yaml can only include URLs, not lib: ./lib.yaml")
+	require.EqualError(t, RegisterEmbeddedTasks(fsys, "tasks/delims.yaml"), "the embedded tasks file tasks/delims.yaml cannot set templateDelims")
+	require.ErrorContains(t, RegisterEmbeddedTasks(fsys, "tasks/invalid.yaml"), "cannot start with a dash")
+	require.False(t, HasEmbeddedTasks())
+
+	require.NoError(t, RegisterEmbeddedTasks(fsys, "tasks/builtin.yaml"))
+	require.NoError(t, RegisterEmbeddedTasks(fsys, "tasks/extra.yaml"))
+	require.True(t, HasEmbeddedTasks())
+
+	// Tasks on disk override embedded ones, and earlier registered files override later ones
+	onDisk := types.TasksFile{
+		Variables: []variables.InteractiveVariable[variables.ExtraVariableInfo]{{Variable: variables.Variable[variables.ExtraVariableInfo]{Name: "REGISTRY"}, Default: "docker.io"}},
+		Tasks:     []types.Task{{Name: "lint", Actions: []types.Action{{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: "echo repo lint"}}}}},
+	}
+	merged := MergeEmbeddedTasks(onDisk)
+	names := []string{}
+	for _, task := range merged.Tasks {
+		names = append(names, task.Name)
+	}
+	require.Equal(t, []string{"lint", "release", "docs"}, names)
+	require.Equal(t, "echo repo lint", merged.Tasks[0].Actions[0].Cmd)
+	require.Equal(t, "echo builtin release", merged.Tasks[1].Actions[0].Cmd)
+	require.Len(t, merged.Variables, 1)
+	require.Equal(t, "docker.io", merged.Variables[0].Default)
+	require.Equal(t, []map[string]string{{"remote": "https://example.com/tasks.yaml"}}, merged.Includes)
+	require.Len(t, onDisk.Tasks, 1)
+
+	require.Equal(t, map[string]bool{"lint": false, "release": true, "docs": true}, embeddedTaskNames(merged))
+}
+
+func TestRun_embeddedTasks(t *testing.T) {
+	t.Cleanup(ClearEmbeddedTasks)
+	config.TaskFileLocation = filepath.Join(t.TempDir(), "tasks.yaml")
+	t.Cleanup(func() { config.TaskFileLocation = "" })
+
+	// Embedded tasks keep the default template delimiters when the tasks file on disk changes them
+	result := filepath.Join(t.TempDir(), "result")
+	fsys := fstest.MapFS{"tasks.yaml": {Data: []byte(`tasks:
+  - name: greet
+    inputs:
+      name:
+        description: Who to greet
+        default: world
+    actions:
+      - cmd: echo "hello ${{ .inputs.name }}" >> ` + result + `
+`)}}
+	require.NoError(t, RegisterEmbeddedTasks(fsys, "tasks.yaml"))
+
+	tasksFile := MergeEmbeddedTasks(types.TasksFile{
+		TemplateDelims: &types.TemplateDelims{Left: "[[", Right: "]]"},
+		Tasks: []types.Task{{Name: "default", Actions: []types.Action{
+			{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: `echo '[[ "from disk" ]] ${{ github.sha }}' >> ` + result}},
+			{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{}, TaskReference: "greet", With: map[string]string{"name": "maru"}},
+		}}},
+	})
+	require.NoError(t, Run(tasksFile, "default", nil, false, nil))
+
+	contents, err := os.ReadFile(result)
+	require.NoError(t, err)
+	require.Equal(t, "from disk ${{ github.sha }}\nhello maru\n", string(contents))
+}
@@ -0,0 +1,173 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
+)
+
+// The sources a variable in the environment of a command can come from
+const (
+	envSourceInherited = "inherited environment"
+	envSourceEnvFile   = "env file"
+	envSourceAction    = "action env"
+	envSourceVariables = "variables"
+	envSourceExtra     = "extra env"
+	envSourceArguments = "command"
+)
+
+// maxReportedVariables is the number of the largest variables an EnvTooLargeError names
+const maxReportedVariables = 5
+
+// EnvSize is the size in bytes of a variable (or of every variable from a source) in the environment of a command
+type EnvSize struct {
+	Name   string
+	Source string
+	Size   int
+}
+
+// EnvTooLargeError is returned instead of running a command whose environment (along with its arguments) is larger
+// than the OS allows, which would otherwise fail with a bare "argument list too long" (E2BIG)
+type EnvTooLargeError struct {
+	// Size is the size of the arguments and environment of the command in bytes, or of Variable if it is set
+	Size int
+	// Limit is the size the OS allows (0 if it is not known because the OS refused the command itself)
+	Limit int
+	// Variable is set when a single variable is larger than the OS allows
+	Variable string
+	// Sources are the total size of each source of the environment, largest first
+	Sources []EnvSize
+	// Largest are the largest variables in the environment, largest first
+	Largest []EnvSize
+}
+
+// Error describes the size of the environment and where it came from
+func (e *EnvTooLargeError) Error() string {
+	var b strings.Builder
+	switch {
+	case e.Variable != "":
+		b.WriteString(fmt.Sprintf("the environment variable %s is %s which is more than the %s the OS allows for a single variable", e.Variable, utils.FormatByteSize(int64(e.Size)), utils.FormatByteSize(int64(e.Limit))))
+	case e.Limit > 0:
+		b.WriteString(fmt.Sprintf("the environment and arguments of the command are %s which is more than the %s the OS allows", utils.FormatByteSize(int64(e.Size)), utils.FormatByteSize(int64(e.Limit))))
+	default:
+		b.WriteString(fmt.Sprintf("the OS refused to run the command as its environment and arguments (%s) are too large", utils.FormatByteSize(int64(e.Size))))
+	}
+
+	sources := []string{}
+	for _, source := range e.Sources {
+		sources = append(sources, fmt.Sprintf("%s %s", source.Name, utils.FormatByteSize(int64(source.Size))))
+	}
+	largest := []string{}
+	for _, variable := range e.Largest {
+		largest = append(largest, fmt.Sprintf("%s (%s) %s", variable.Name, variable.Source, utils.FormatByteSize(int64(variable.Size))))
+	}
+	b.WriteString(fmt.Sprintf(" (by source: %s; largest variables: %s)", strings.Join(sources, ", "), strings.Join(largest, ", ")))
+	return b.String()
+}
+
+// envLimits returns the largest total size of the arguments and environment of a command and the largest size of a
+// single variable that the OS allows (0 where there is no known limit)
+func envLimits() (total int, single int) {
+	switch runtime.GOOS {
+	case "linux":
+		// A quarter of the stack limit and MAX_ARG_STRLEN
+		return argMax(), 128 * 1024
+	case "darwin":
+		return 1024 * 1024, 0
+	case "windows":
+		return 0, 32767
+	}
+	return 0, 0
+}
+
+// checkEnvSize returns an EnvTooLargeError if the arguments and environment of a command (the inherited environment
+// followed by env, with later variables replacing earlier ones as they do when the command starts) are larger than the
+// OS allows. sources names where variables in env came from, anything else being from the action itself.
+func checkEnvSize(args, env []string, sources map[string]string) error {
+	total, single := envLimits()
+	return measureEnv(args, env, sources, total, single, false)
+}
+
+// envTooLarge explains an error starting a command if it is because its arguments and environment are too large
+func envTooLarge(err error, args, env []string, sources map[string]string) error {
+	if !errors.Is(err, syscall.E2BIG) {
+		return err
+	}
+	return measureEnv(args, env, sources, 0, 0, true)
+}
+
+// measureEnv measures the arguments and environment of a command against the given limits, returning an
+// EnvTooLargeError if they are exceeded (or always if refused is set, for a command the OS already refused)
+func measureEnv(args, env []string, sources map[string]string, total, single int, refused bool) error {
+	variables := []EnvSize{}
+	indexes := map[string]int{}
+	add := func(entry, source string) {
+		name, _, _ := strings.Cut(entry, "=")
+		size := EnvSize{Name: name, Source: source, Size: len(entry) + 1}
+		if idx, ok := indexes[name]; ok {
+			variables[idx] = size
+			return
+		}
+		indexes[name] = len(variables)
+		variables = append(variables, size)
+	}
+	for _, entry := range os.Environ() {
+		add(entry, envSourceInherited)
+	}
+	extraEnv := config.GetExtraEnv()
+	for _, entry := range env {
+		name, _, _ := strings.Cut(entry, "=")
+		source, ok := sources[name]
+		if _, extra := extraEnv[name]; extra {
+			source = envSourceExtra
+		} else if !ok {
+			source = envSourceAction
+		}
+		add(entry, source)
+	}
+
+	// Each argument and variable takes its bytes, a terminating NUL and a pointer
+	e := &EnvTooLargeError{}
+	bySource := map[string]int{}
+	for _, arg := range args {
+		bySource[envSourceArguments] += len(arg) + 1 + 8
+	}
+	for _, variable := range variables {
+		bySource[variable.Source] += variable.Size + 8
+		if single > 0 && variable.Size > single && e.Variable == "" {
+			e.Variable, e.Size, e.Limit = variable.Name, variable.Size, single
+		}
+	}
+	size := 0
+	for name, sourceSize := range bySource {
+		size += sourceSize
+		e.Sources = append(e.Sources, EnvSize{Name: name, Size: sourceSize})
+	}
+	if e.Variable == "" {
+		if !refused && (total == 0 || size <= total) {
+			return nil
+		}
+		e.Size, e.Limit = size, total
+	}
+
+	slices.SortFunc(e.Sources, func(a, b EnvSize) int {
+		if a.Size != b.Size {
+			return b.Size - a.Size
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	slices.SortStableFunc(variables, func(a, b EnvSize) int { return b.Size - a.Size })
+	e.Largest = variables[:min(maxReportedVariables, len(variables))]
+	return e
+}
@@ -0,0 +1,29 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+//go:build linux
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// defaultArgMax is the total size Linux allows with the default 8MiB stack (used if the stack limit can't be read)
+	defaultArgMax = 2 * 1024 * 1024
+	// minArgMax and maxArgMax bound the total size Linux allows whatever the stack limit is (ARG_MAX and 3/4 of _STK_LIM)
+	minArgMax = 128 * 1024
+	maxArgMax = 6 * 1024 * 1024
+)
+
+// argMax returns the largest total size of the arguments and environment of a command, which Linux allows to be a
+// quarter of the stack limit
+func argMax() int {
+	var limit unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_STACK, &limit); err != nil {
+		return defaultArgMax
+	}
+	return int(max(min(limit.Cur/4, maxArgMax), minArgMax))
+}
@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+//go:build linux
+
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func Test_argMax(t *testing.T) {
+	var limit unix.Rlimit
+	require.NoError(t, unix.Getrlimit(unix.RLIMIT_STACK, &limit))
+	t.Cleanup(func() { require.NoError(t, unix.Setrlimit(unix.RLIMIT_STACK, &limit)) })
+
+	tests := []struct {
+		stack uint64
+		want  int
+	}{
+		{stack: 8 * 1024 * 1024, want: 2 * 1024 * 1024},
+		{stack: 16 * 1024 * 1024, want: 4 * 1024 * 1024},
+		{stack: 64 * 1024 * 1024, want: 6 * 1024 * 1024},
+		{stack: unix.RLIM_INFINITY, want: 6 * 1024 * 1024},
+		{stack: 256 * 1024, want: 128 * 1024},
+	}
+	for _, tt := range tests {
+		if tt.stack > limit.Max {
+			continue
+		}
+		require.NoError(t, unix.Setrlimit(unix.RLIMIT_STACK, &unix.Rlimit{Cur: tt.stack, Max: limit.Max}))
+		require.Equal(t, tt.want, argMax(), "stack limit %d", tt.stack)
+	}
+}
@@ -0,0 +1,12 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+//go:build !linux
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+// argMax is only used on Linux, where the total size of the arguments and environment depends on the stack limit
+func argMax() int {
+	return 0
+}
@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_measureEnv(t *testing.T) {
+	config.ClearExtraEnv()
+	config.AddExtraEnv("EXTRA", strings.Repeat("e", 100))
+	t.Cleanup(config.ClearExtraEnv)
+
+	args := []string{"sh", "-c", "echo hi"}
+	env := []string{
+		"FROM_FILE=" + strings.Repeat("f", 3000),
+		"BIG_VAR=" + strings.Repeat("v", 5000),
+		"FROM_ACTION=a",
+		// Later variables replace earlier ones so only this BIG_VAR counts
+		"BIG_VAR=" + strings.Repeat("v", 4000),
+		"EXTRA=" + strings.Repeat("e", 100),
+	}
+	sources := map[string]string{"FROM_FILE": envSourceEnvFile, "BIG_VAR": envSourceVariables}
+
+	// Within the limits
+	require.NoError(t, measureEnv(args, env, sources, 0, 0, false))
+	require.NoError(t, measureEnv(args, env, sources, 1<<30, 1<<20, false))
+
+	// Over the total
+	err := measureEnv(args, env, sources, 5000, 0, false)
+	var tooLarge *EnvTooLargeError
+	require.ErrorAs(t, err, &tooLarge)
+	require.Equal(t, 5000, tooLarge.Limit)
+	require.Greater(t, tooLarge.Size, 7000)
+	require.Equal(t, EnvSize{Name: "BIG_VAR", Source: envSourceVariables, Size: len("BIG_VAR=") + 4000 + 1}, tooLarge.Largest[0])
+	require.Equal(t, EnvSize{Name: "FROM_FILE", Source: envSourceEnvFile, Size: len("FROM_FILE=") + 3000 + 1}, tooLarge.Largest[1])
+	require.Len(t, tooLarge.Largest, maxReportedVariables)
+	names := map[string]int{}
+	for _, source := range tooLarge.Sources {
+		names[source.Name] = source.Size
+	}
+	require.Equal(t, len("BIG_VAR=")+4000+1+8, names[envSourceVariables])
+	require.Equal(t, len("EXTRA=")+100+1+8, names[envSourceExtra])
+	require.Equal(t, len("FROM_ACTION=a")+1+8, names[envSourceAction])
+	require.Contains(t, names, envSourceInherited)
+	require.Contains(t, err.Error(), "which is more than the 4.9 KiB the OS allows (by source: ")
+	require.Contains(t, err.Error(), "largest variables: BIG_VAR (variables) 3.9 KiB, FROM_FILE (env file) 2.9 KiB")
+
+	// A single variable over the limit
+	err = measureEnv(args, env, sources, 0, 3500, false)
+	require.ErrorContains(t, err, "the environment variable BIG_VAR is 3.9 KiB which is more than the 3.4 KiB the OS allows for a single variable")
+}
+
+func Test_envTooLarge(t *testing.T) {
+	other := errors.New("exit status 1")
+	require.Equal(t, other, envTooLarge(other, nil, nil, nil))
+	require.NoError(t, envTooLarge(nil, nil, nil, nil))
+
+	refused := &fs.PathError{Op: "fork/exec", Path: "/bin/sh", Err: syscall.E2BIG}
+	err := envTooLarge(refused, []string{"sh"}, []string{"A=" + strings.Repeat("a", 100*1024)}, nil)
+	require.ErrorContains(t, err, "the OS refused to run the command as its environment and arguments")
+	require.ErrorContains(t, err, "largest variables: A (action env) 100.0 KiB")
+}
+
+func Test_runAction_envTooLarge(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the size of a single variable is only limited on linux")
+	}
+	variableConfig := GetMaruVariableConfig()
+	variableConfig.SetVariable("HUGE", strings.Repeat("x", 200*1024), "", variables.ExtraVariableInfo{})
+	action := &types.BaseAction[variables.ExtraVariableInfo]{Cmd: "echo never runs"}
+
+	_, err := runAction(context.Background(), action, "", variableConfig, false)
+	var tooLarge *EnvTooLargeError
+	require.ErrorAs(t, err, &tooLarge)
+	require.Equal(t, "HUGE", tooLarge.Variable)
+	require.Equal(t, envSourceVariables, tooLarge.Largest[0].Source)
+}
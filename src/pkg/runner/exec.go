@@ -0,0 +1,315 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	osexec "os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/defenseunicorns/pkg/exec"
+)
+
+// cmdWaitDelay bounds how long to wait for a cancelled command's output to drain before giving up on it
+const cmdWaitDelay = 2 * time.Second
+
+// OutputLimitError is returned when a command is killed for writing more output than it was allowed to
+type OutputLimitError struct {
+	Limit int
+}
+
+// Error returns the output limit that was exceeded
+func (e *OutputLimitError) Error() string {
+	return fmt.Sprintf("output exceeded the limit of %d bytes", e.Limit)
+}
+
+// outputLimit tracks the combined output of a command against a byte limit, calling exceeded once it is reached
+type outputLimit struct {
+	mu       sync.Mutex
+	limit    int
+	written  int
+	exceeded func()
+}
+
+// limitedWriter passes writes through to w until the shared limit is reached, dropping any further output (while
+// still reporting success so the command's pipes keep draining until it is killed)
+type limitedWriter struct {
+	limit *outputLimit
+	w     io.Writer
+}
+
+// Write writes as much of p as fits within the remaining limit
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	lw.limit.mu.Lock()
+	defer lw.limit.mu.Unlock()
+
+	// Any output past the limit (including output after writes that reached it exactly) exceeds it
+	remaining := lw.limit.limit - lw.limit.written
+	if len(p) > remaining {
+		lw.limit.written = lw.limit.limit
+		lw.limit.exceeded()
+		if remaining <= 0 {
+			return len(p), nil
+		}
+		_, err := lw.w.Write(p[:remaining])
+		return len(p), err
+	}
+
+	lw.limit.written += len(p)
+	_, err := lw.w.Write(p)
+	return len(p), err
+}
+
+// errOutputNotMatched is returned when a command exits before any line of its output matched the pattern it was waiting for
+var errOutputNotMatched = errors.New("command exited before its output matched")
+
+// errOutputMatched stops a command once a line of its output matches the pattern it was waiting for
+var errOutputMatched = errors.New("command output matched")
+
+// outputMatch records the first line of a command's output (across both streams) that matches a pattern
+type outputMatch struct {
+	mu      sync.Mutex
+	pattern *regexp.Regexp
+	found   bool
+	line    string
+	onMatch func()
+}
+
+// maxMatchLine bounds how much of a line without a newline is kept to match the pattern of untilOutput against
+const maxMatchLine = 64 * 1024
+
+// lineMatcher passes writes through to w while checking each complete line (buffering partial lines between
+// writes) against a shared outputMatch
+type lineMatcher struct {
+	match   *outputMatch
+	w       io.Writer
+	partial []byte
+}
+
+// Write passes p through to the underlying writer and checks any complete lines in it against the pattern
+func (lm *lineMatcher) Write(p []byte) (int, error) {
+	n, err := lm.w.Write(p)
+
+	lm.match.mu.Lock()
+	defer lm.match.mu.Unlock()
+	if lm.match.found {
+		return n, err
+	}
+
+	lm.partial = append(lm.partial, p...)
+	for {
+		idx := bytes.IndexByte(lm.partial, '\n')
+		if idx < 0 {
+			break
+		}
+		line := lm.partial[:idx]
+		lm.partial = lm.partial[idx+1:]
+		if lm.check(line) {
+			return n, err
+		}
+	}
+
+	// A line that grows too long without a newline is checked as it is, keeping only its end for later writes
+	if len(lm.partial) > maxMatchLine {
+		if lm.check(lm.partial) {
+			return n, err
+		}
+		lm.partial = append([]byte(nil), lm.partial[len(lm.partial)-maxMatchLine:]...)
+	}
+
+	return n, err
+}
+
+// flush checks the last line of the output (if it did not end in a newline) once the command has exited
+func (lm *lineMatcher) flush() {
+	lm.match.mu.Lock()
+	defer lm.match.mu.Unlock()
+	if !lm.match.found && len(lm.partial) > 0 {
+		lm.check(lm.partial)
+	}
+	lm.partial = nil
+}
+
+// check records the line as the match if it matches the pattern (the lock of the outputMatch must be held)
+func (lm *lineMatcher) check(line []byte) bool {
+	text := strings.TrimSuffix(string(line), "\r")
+	if !lm.match.pattern.MatchString(text) {
+		return false
+	}
+	lm.match.found = true
+	lm.match.line = text
+	lm.match.onMatch()
+	return true
+}
+
+// maxExpectBuffer bounds how much of a command's output is kept to match the patterns of its expect rules against
+const maxExpectBuffer = 64 * 1024
+
+// expectRule answers a prompt by sending input to a command whenever its output matches a pattern
+type expectRule struct {
+	pattern *regexp.Regexp
+	send    string
+}
+
+// expecter checks the output of a command (across both streams) against its expect rules, writing the input of a rule
+// to the command's stdin when it matches. Output is matched as it arrives rather than line by line since prompts
+// rarely end in a newline, and only output after the last match is checked so a prompt is answered once.
+type expecter struct {
+	mu     sync.Mutex
+	rules  []expectRule
+	buffer []byte
+	stdin  io.Writer
+}
+
+// expectWriter passes writes through to w while checking them against a shared expecter
+type expectWriter struct {
+	expecter *expecter
+	w        io.Writer
+}
+
+// Write passes p through to the underlying writer and answers any prompts in it
+func (ew *expectWriter) Write(p []byte) (int, error) {
+	n, err := ew.w.Write(p)
+	ew.expecter.check(p)
+	return n, err
+}
+
+// check adds output to the buffer and sends the input of every rule that matches it
+func (e *expecter) check(p []byte) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.buffer = append(e.buffer, p...)
+	if len(e.buffer) > maxExpectBuffer {
+		e.buffer = e.buffer[len(e.buffer)-maxExpectBuffer:]
+	}
+
+	for {
+		// Answer whichever prompt appears first in the output
+		first, end := -1, 0
+		for i, rule := range e.rules {
+			if loc := rule.pattern.FindIndex(e.buffer); loc != nil && (first < 0 || loc[0] < end) {
+				first, end = i, loc[1]
+			}
+		}
+		if first < 0 {
+			return
+		}
+		e.buffer = e.buffer[end:]
+		// The command may have exited without reading its input, in which case it fails (or succeeds) on its own
+		_, _ = io.WriteString(e.stdin, e.rules[first].send+"\n")
+	}
+}
+
+// cmdOptions are the limits and conditions applied to a command run by cmdWithContext
+type cmdOptions struct {
+	// maxOutputBytes kills the command once its combined output exceeds the limit (if greater than 0)
+	maxOutputBytes int
+	// untilOutput stops the command successfully once a line of its output matches (if set)
+	untilOutput *regexp.Regexp
+	// expect answers prompts in the output of the command by writing to its stdin (if set)
+	expect []expectRule
+	// envSources names where variables in the environment came from (i.e. the env file or variables) so an environment
+	// that is too large can be explained
+	envSources map[string]string
+}
+
+// cmdResult is the output of a command run by cmdWithContext
+type cmdResult struct {
+	Stdout string
+	Stderr string
+	// Matched is the line that matched cmdOptions.untilOutput (if any)
+	Matched string
+}
+
+// cmdWithContext executes a given command with the given config, similar to exec.CmdWithContext except that the
+// whole process tree is stopped when the context is done (rather than only the shell that was started), when the
+// command writes more output than allowed or when a line of its output matches the pattern being waited for (and any
+// prompts it is expected to show are answered)
+func cmdWithContext(ctx context.Context, config exec.Config, opts cmdOptions, command string, args ...string) (cmdResult, error) {
+	var result cmdResult
+	if command == "" {
+		return result, errors.New("command is required")
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	cmd := osexec.CommandContext(ctx, command, args...)
+	cmd.Dir = config.Dir
+	cmd.Env = append(os.Environ(), config.Env...)
+	cmd.WaitDelay = cmdWaitDelay
+	setProcessGroup(cmd)
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	stdoutWriters := []io.Writer{&stdoutBuf}
+	stderrWriters := []io.Writer{&stderrBuf}
+
+	if config.Stdout != nil {
+		stdoutWriters = append(stdoutWriters, config.Stdout)
+	}
+	if config.Stderr != nil {
+		stderrWriters = append(stderrWriters, config.Stderr)
+	}
+
+	cmd.Stdout = io.MultiWriter(stdoutWriters...)
+	cmd.Stderr = io.MultiWriter(stderrWriters...)
+
+	var match *outputMatch
+	var matchers []*lineMatcher
+	if opts.untilOutput != nil {
+		match = &outputMatch{pattern: opts.untilOutput, onMatch: func() { cancel(errOutputMatched) }}
+		matchers = []*lineMatcher{{match: match, w: cmd.Stdout}, {match: match, w: cmd.Stderr}}
+		cmd.Stdout = matchers[0]
+		cmd.Stderr = matchers[1]
+	}
+
+	if len(opts.expect) > 0 {
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return result, err
+		}
+		e := &expecter{rules: opts.expect, stdin: stdin}
+		cmd.Stdout = &expectWriter{expecter: e, w: cmd.Stdout}
+		cmd.Stderr = &expectWriter{expecter: e, w: cmd.Stderr}
+	}
+
+	var limitErr *OutputLimitError
+	if opts.maxOutputBytes > 0 {
+		limitErr = &OutputLimitError{Limit: opts.maxOutputBytes}
+		limit := &outputLimit{limit: opts.maxOutputBytes, exceeded: func() { cancel(limitErr) }}
+		cmd.Stdout = &limitedWriter{limit: limit, w: cmd.Stdout}
+		cmd.Stderr = &limitedWriter{limit: limit, w: cmd.Stderr}
+	}
+
+	// Catch an environment that is too large before the OS refuses it with a bare "argument list too long"
+	if err := checkEnvSize(cmd.Args, config.Env, opts.envSources); err != nil {
+		return result, err
+	}
+
+	err := envTooLarge(cmd.Run(), cmd.Args, config.Env, opts.envSources)
+	for _, lm := range matchers {
+		lm.flush()
+	}
+	switch cause := context.Cause(ctx); {
+	case limitErr != nil && errors.Is(cause, limitErr):
+		err = limitErr
+	case errors.Is(cause, errOutputMatched):
+		err = nil
+		result.Matched = match.line
+	}
+
+	result.Stdout = stdoutBuf.String()
+	result.Stderr = stderrBuf.String()
+	return result, err
+}
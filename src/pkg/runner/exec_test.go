@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitedWriter_exactBoundary(t *testing.T) {
+	var out bytes.Buffer
+	exceeded := 0
+	limit := &outputLimit{limit: 5, exceeded: func() { exceeded++ }}
+	lw := &limitedWriter{limit: limit, w: &out}
+
+	// Output that reaches the limit exactly is within it
+	n, err := lw.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	require.Equal(t, 0, exceeded)
+
+	// Any output after that exceeds it (and is dropped)
+	n, err = lw.Write([]byte("!"))
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, 1, exceeded)
+	require.Equal(t, "hello", out.String())
+
+	// Writing nothing does not exceed it
+	_, err = lw.Write(nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, exceeded)
+}
+
+func TestLineMatcher(t *testing.T) {
+	newMatcher := func(pattern string) (*lineMatcher, *outputMatch, *bytes.Buffer) {
+		var out bytes.Buffer
+		match := &outputMatch{pattern: regexp.MustCompile(pattern), onMatch: func() {}}
+		return &lineMatcher{match: match, w: &out}, match, &out
+	}
+
+	t.Run("matches lines split across writes", func(t *testing.T) {
+		lm, match, out := newMatcher(`^ready on \d+$`)
+		_, _ = lm.Write([]byte("starting\nready "))
+		require.False(t, match.found)
+		_, _ = lm.Write([]byte("on 8080\r\nmore\n"))
+		require.True(t, match.found)
+		require.Equal(t, "ready on 8080", match.line)
+		require.Equal(t, "starting\nready on 8080\r\nmore\n", out.String())
+	})
+
+	t.Run("matches a last line without a newline once flushed", func(t *testing.T) {
+		lm, match, _ := newMatcher(`ready`)
+		_, _ = lm.Write([]byte("starting\nready"))
+		require.False(t, match.found)
+		lm.flush()
+		require.True(t, match.found)
+		require.Equal(t, "ready", match.line)
+	})
+
+	t.Run("bounds output without newlines", func(t *testing.T) {
+		lm, match, _ := newMatcher(`ready`)
+		for i := 0; i < 4; i++ {
+			_, _ = lm.Write(bytes.Repeat([]byte("x"), maxMatchLine))
+		}
+		require.False(t, match.found)
+		require.LessOrEqual(t, len(lm.partial), maxMatchLine)
+
+		_, _ = lm.Write([]byte("ready"))
+		lm.flush()
+		require.True(t, match.found)
+	})
+}
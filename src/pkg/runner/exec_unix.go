@@ -0,0 +1,20 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+//go:build !windows
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	osexec "os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts the command in its own process group so that cancelling it also stops any children
+func setProcessGroup(cmd *osexec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,14 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+//go:build windows
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	osexec "os/exec"
+)
+
+// setProcessGroup is a no-op on Windows where the default cancellation behavior is used
+func setProcessGroup(_ *osexec.Cmd) {}
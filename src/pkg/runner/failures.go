@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	osexec "os/exec"
+	"regexp"
+	"strings"
+)
+
+// FailureCause is a common reason for a command to fail that maru can recognize and give a hint for
+type FailureCause string
+
+const (
+	// FailureDNS means a host name could not be resolved
+	FailureDNS FailureCause = "dns"
+	// FailureConnectionRefused means nothing was listening on the address a connection was made to
+	FailureConnectionRefused FailureCause = "connection-refused"
+	// FailureForbidden means a request was refused with a 403
+	FailureForbidden FailureCause = "forbidden"
+	// FailureOOMKilled means the command (or a process it started) was killed, usually for running out of memory
+	FailureOOMKilled FailureCause = "oom-killed"
+	// FailureCommandNotFound means a command the script runs is not installed (or not on the PATH)
+	FailureCommandNotFound FailureCause = "command-not-found"
+)
+
+// maxClassifiedOutput is the number of bytes at the end of a command's output that are searched for a failure cause
+const maxClassifiedOutput = 64 * 1024
+
+// failurePatterns match the output of commands that failed for each cause, checked in order
+var failurePatterns = []struct {
+	cause   FailureCause
+	pattern *regexp.Regexp
+}{
+	{FailureDNS, regexp.MustCompile(`(?i)could not resolve host|no such host|name or service not known|temporary failure in name resolution|nodename nor servname provided|name does not resolve|getaddrinfo enotfound`)},
+	{FailureConnectionRefused, regexp.MustCompile(`(?i)connection refused|econnrefused|actively refused`)},
+	{FailureForbidden, regexp.MustCompile(`(?i)\b403 forbidden\b|\(forbidden\)|forbidden \(403\)|http/[0-9.]+ 403\b|status(?: code)?[:= ]+403\b|returned error: 403\b`)},
+	{FailureOOMKilled, regexp.MustCompile(`(?i)out of memory|oomkilled|cannot allocate memory`)},
+	{FailureCommandNotFound, regexp.MustCompile(`(?i)command not found|is not recognized as an internal or external command`)},
+}
+
+// failureHints are what to check for each failure cause
+var failureHints = map[FailureCause]string{
+	FailureDNS:               "a host name could not be resolved: check that it is spelled correctly and that this machine's DNS (or VPN or proxy) settings can resolve it",
+	FailureConnectionRefused: "a connection was refused: check that the service is running and listening on the address and port the command uses (if it may still be starting, add a wait action before this one)",
+	FailureForbidden:         "a request was forbidden (403): check that the credentials the command uses are valid, have not expired and have access to what is requested",
+	FailureOOMKilled:         "the command was killed (exit status 137), which usually means it ran out of memory: give it (or its container) more memory or have it do less at once",
+	FailureCommandNotFound:   "a command was not found (exit status 127): check that it is installed and on the PATH ('maru doctor' lists the tools tasks need)",
+}
+
+// Hint returns what to check for a failure cause
+func (c FailureCause) Hint() string {
+	return failureHints[c]
+}
+
+// failureCauseError marks the error of an action whose command failed for a recognized cause, keeping the message of
+// the error it wraps
+type failureCauseError struct {
+	cause FailureCause
+	err   error
+}
+
+// Error returns the message of the wrapped error
+func (e *failureCauseError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *failureCauseError) Unwrap() error {
+	return e.err
+}
+
+// withFailureCause marks err with a failure cause (if there is one)
+func withFailureCause(err error, cause FailureCause) error {
+	if err == nil || cause == "" {
+		return err
+	}
+	return &failureCauseError{cause: cause, err: err}
+}
+
+// failureCauseOf returns the failure cause an error was marked with (if any)
+func failureCauseOf(err error) FailureCause {
+	var causeErr *failureCauseError
+	if errors.As(err, &causeErr) {
+		return causeErr.cause
+	}
+	return ""
+}
+
+// attemptSummary describes how an attempt of a command failed (along with its likely cause, if it is recognized)
+func attemptSummary(attempt int, err error, cause FailureCause) string {
+	if cause != "" {
+		return fmt.Sprintf("attempt %d: %s (%s)", attempt, err.Error(), cause)
+	}
+	return fmt.Sprintf("attempt %d: %s", attempt, err.Error())
+}
+
+// classifyFailure returns the likely cause of a command failing from its error and output, or nothing if the cause is
+// not one maru recognizes. What the command printed is checked first since it is the most specific. A command that was
+// killed is only taken to have run out of memory if ctx (the context it ran with) was not cancelled, since maru kills
+// commands itself when they time out or are interrupted (commands that exceed their output limit are never an exit).
+func classifyFailure(ctx context.Context, err error, output string) FailureCause {
+	if err == nil {
+		return ""
+	}
+	if len(output) > maxClassifiedOutput {
+		output = output[len(output)-maxClassifiedOutput:]
+	}
+	for _, fp := range failurePatterns {
+		if fp.pattern.MatchString(output) {
+			return fp.cause
+		}
+	}
+
+	exitCode := -1
+	var lineErr *ScriptLineError
+	var exitErr *osexec.ExitError
+	switch {
+	case errors.As(err, &lineErr):
+		exitCode = lineErr.ExitCode
+	case errors.As(err, &exitErr):
+		exitCode = exitErr.ExitCode()
+	}
+	switch {
+	case ctx.Err() == nil && (exitCode == 137 || exitErr != nil && strings.Contains(exitErr.Error(), "signal: killed")):
+		return FailureOOMKilled
+	case exitCode == 127:
+		return FailureCommandNotFound
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_classifyFailure(t *testing.T) {
+	failed := errors.New("exit status 1")
+	tests := []struct {
+		name   string
+		err    error
+		output string
+		want   FailureCause
+	}{
+		{name: "succeeded", output: "could not resolve host"},
+		{name: "unrecognized", err: failed, output: "something else went wrong"},
+		{name: "curl dns", err: failed, output: "curl: (6) Could not resolve host: registry.example.com", want: FailureDNS},
+		{name: "go dns", err: failed, output: "dial tcp: lookup registry.example.com on 127.0.0.53:53: no such host", want: FailureDNS},
+		{name: "connection refused", err: failed, output: "curl: (7) Failed to connect to localhost port 8080: Connection refused", want: FailureConnectionRefused},
+		{name: "curl 403", err: failed, output: "curl: (22) The requested URL returned error: 403", want: FailureForbidden},
+		{name: "kubectl forbidden", err: failed, output: `Error from server (Forbidden): pods is forbidden: User "dev" cannot list resource "pods"`, want: FailureForbidden},
+		{name: "403 in a path is not forbidden", err: failed, output: "failed to pull ghcr.io/app/403: not found"},
+		{name: "out of memory", err: failed, output: "fatal error: runtime: out of memory", want: FailureOOMKilled},
+		{name: "exit status 137", err: &ScriptLineError{Line: 2, Command: "make build", ExitCode: 137}, want: FailureOOMKilled},
+		{name: "exit status 127", err: &ScriptLineError{Line: 1, Command: "kustomize build", ExitCode: 127}, want: FailureCommandNotFound},
+		{name: "bash command not found", err: failed, output: "bash: line 1: kustomize: command not found", want: FailureCommandNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, classifyFailure(context.Background(), tt.err, tt.output))
+		})
+	}
+}
+
+func Test_classifyFailure_killedByTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the command is POSIX")
+	}
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep is not installed")
+	}
+
+	// A command killed because it timed out did not run out of memory
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := exec.CommandContext(ctx, "sleep", "5").Run()
+	require.ErrorContains(t, err, "signal: killed")
+	require.Equal(t, FailureCause(""), classifyFailure(ctx, err, ""))
+	require.Equal(t, FailureCause(""), classifyFailure(ctx, &ScriptLineError{Line: 1, Command: "sleep 5", ExitCode: 137}, ""))
+
+	// The same command killed by anything else likely did
+	require.Equal(t, FailureOOMKilled, classifyFailure(context.Background(), err, ""))
+}
+
+func Test_runAction_failureCause(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands are POSIX shell")
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not installed")
+	}
+	retries := 1
+	tests := []struct {
+		cmd  string
+		want FailureCause
+	}{
+		{cmd: "echo 'curl: (6) Could not resolve host: registry.example.com' >&2; exit 6", want: FailureDNS},
+		{cmd: "maru-test-command-that-does-not-exist", want: FailureCommandNotFound},
+		{cmd: "exit 3"},
+	}
+	for _, tt := range tests {
+		action := &types.BaseAction[variables.ExtraVariableInfo]{Cmd: tt.cmd, MaxRetries: &retries}
+		_, err := runAction(context.Background(), action, "", GetMaruVariableConfig(), false)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed after 1 retries")
+		require.Equal(t, tt.want, failureCauseOf(err), tt.cmd)
+	}
+
+	// The cause and its hint are recorded in the results
+	r := &Runner{}
+	action := types.Action{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: "exit 6"}}
+	r.recordAction(types.Task{Name: "push"}, 0, action, false, "", 0, withFailureCause(errors.New(`command "exit 6" failed after 0 retries`), FailureDNS))
+	require.Equal(t, FailureDNS, r.results.Actions[0].Cause)
+	require.Equal(t, FailureDNS.Hint(), r.results.Actions[0].Hint)
+	require.Equal(t, `command "exit 6" failed after 0 retries`, r.results.Actions[0].Reason)
+}
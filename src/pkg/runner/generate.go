@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	goyaml "github.com/goccy/go-yaml"
+)
+
+// newGeneratedTasksFile creates the (empty) file the actions of a call of a task can write the tasks they generate to
+func newGeneratedTasksFile() (string, error) {
+	f, err := os.CreateTemp("", "maru-tasks-*")
+	if err != nil {
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+// runGeneratedTasks runs the tasks an action wrote to its generated tasks file (if any) in the order they were written,
+// adding them to the run so that later actions can reference them too
+func (r *Runner) runGeneratedTasks(ctx context.Context, task types.Task, idx int, path string) error {
+	generated, err := r.readGeneratedTasks(task, idx, path)
+	if err != nil {
+		return err
+	}
+
+	for _, generatedTask := range generated {
+		if r.progress != nil && len(r.progress.durations) == 0 {
+			r.progress.total += r.countActions(generatedTask, 0)
+		}
+	}
+	for _, generatedTask := range generated {
+		message.SLog.Debug(fmt.Sprintf("Running task %s generated by action %d of task %s", generatedTask.Name, idx, task.Name))
+		if err := r.executeTask(ctx, generatedTask, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readGeneratedTasks reads, validates and adds to the run the tasks an action wrote to its generated tasks file, which
+// is emptied for the next action. The file holds either a list of tasks or a tasks file with only tasks, in YAML or
+// JSON.
+func (r *Runner) readGeneratedTasks(task types.Task, idx int, path string) ([]types.Task, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(string(contents)) == "" {
+		return nil, nil
+	}
+	if err := os.Truncate(path, 0); err != nil {
+		return nil, err
+	}
+
+	location := fmt.Sprintf("%s of action %d of task %s", GeneratedTasksEnv, idx, task.Name)
+	var document any
+	if err := goyaml.Unmarshal(contents, &document); err != nil {
+		return nil, fmt.Errorf("%s: unable to read the generated tasks: %w", location, err)
+	}
+	var generated types.TasksFile
+	if _, isList := document.([]any); isList {
+		err = goyaml.Unmarshal(contents, &generated.Tasks)
+	} else {
+		err = goyaml.Unmarshal(contents, &generated)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: unable to read the generated tasks: %w", location, err)
+	}
+	if len(generated.Includes) > 0 || len(generated.Variables) > 0 || generated.TemplateDelims != nil {
+		return nil, fmt.Errorf("%s: generated tasks cannot set includes, variables or templateDelims", location)
+	}
+
+	errs := splitErrors(validateTaskNames(location, generated))
+	seen := map[string]bool{}
+	for i, generatedTask := range generated.Tasks {
+		if _, err := r.getTask(generatedTask.Name); err == nil || seen[generatedTask.Name] {
+			errs = append(errs, fmt.Errorf("%s: tasks[%d]: task %q already exists", location, i, generatedTask.Name))
+		}
+		seen[generatedTask.Name] = true
+		if err := validateActionableTaskCall(generatedTask.Name, generatedTask.Inputs, nil); err != nil {
+			errs = append(errs, fmt.Errorf("%s: tasks[%d]: %w", location, i, err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	// Generated tasks are templated with the delimiters of the task that generated them
+	delims := r.templateDelims(task.Name)
+	if r.taskTemplateDelims == nil {
+		r.taskTemplateDelims = map[string]*types.TemplateDelims{}
+	}
+	for _, generatedTask := range generated.Tasks {
+		r.taskTemplateDelims[generatedTask.Name] = delims
+	}
+	r.tasksFile.Tasks = append(r.tasksFile.Tasks, generated.Tasks...)
+
+	// References are checked once every generated task is known so generated tasks can reference each other
+	if errs := r.validateTasks(generated.Tasks, false); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return generated.Tasks, nil
+}
@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_executeTask_generatedTasks(t *testing.T) {
+	cmd := func(cmd string) types.Action {
+		return types.Action{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: cmd}}
+	}
+	dir := t.TempDir()
+	result := filepath.Join(dir, "result")
+	for _, module := range []string{"api", "web"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "modules", module), 0700))
+	}
+	tasks := []types.Task{
+		{Name: "default", Actions: []types.Action{
+			// One task per module, written as a list of tasks in YAML (with the template of the generated task escaped so
+			// it is templated when that task runs)
+			cmd(`cd ` + dir + `/modules && for m in *; do printf -- '- name: test-%s\n  actions:\n    - cmd: echo "test $${{ .inputs.module }}" >> ` + result + `\n  inputs:\n    module:\n      description: the module\n      default: %s\n' "$m" "$m" >> "$MARU_TASKS"; done`),
+			// A tasks file in JSON that references a task that already exists
+			cmd(`echo '{"tasks": [{"name": "summary", "actions": [{"task": "report"}]}]}' > "$MARU_TASKS"`),
+			cmd(`echo done >> ` + result),
+		}},
+		{Name: "report", Actions: []types.Action{cmd(`echo report >> ` + result)}},
+	}
+	r := &Runner{variableConfig: GetMaruVariableConfig(), tasksFile: types.TasksFile{Tasks: tasks}}
+	require.NoError(t, r.executeTask(context.Background(), tasks[0], nil))
+
+	contents, err := os.ReadFile(result)
+	require.NoError(t, err)
+	require.Equal(t, "test api\ntest web\nreport\ndone\n", string(contents))
+	names := []string{}
+	for _, action := range r.results.Actions {
+		names = append(names, action.Task)
+	}
+	require.Equal(t, []string{"default", "test-api", "test-web", "default", "report", "summary", "default"}, names)
+	_, err = r.getTask("test-web")
+	require.NoError(t, err)
+}
+
+func TestRunner_readGeneratedTasks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks")
+	task := types.Task{Name: "discover"}
+	tests := []struct {
+		name      string
+		generated string
+		want      []string
+		wantErr   string
+	}{
+		{
+			name: "nothing generated",
+			want: nil,
+		},
+		{
+			name:      "list of tasks",
+			generated: "- name: one\n- name: two\n  actions:\n    - task: one\n",
+			want:      []string{"one", "two"},
+		},
+		{
+			name:      "invalid yaml",
+			generated: "tasks: [\n",
+			wantErr:   "MARU_TASKS of action 0 of task discover: unable to read the generated tasks",
+		},
+		{
+			name:      "more than tasks",
+			generated: "variables:\n  - name: FOO\ntasks:\n  - name: one\n",
+			wantErr:   "generated tasks cannot set includes, variables or templateDelims",
+		},
+		{
+			name:      "existing and duplicate names",
+			generated: "- name: discover\n- name: one\n- name: one\n- name: -bad\n",
+			wantErr:   `tasks[0]: task "discover" already exists`,
+		},
+		{
+			name:      "required inputs",
+			generated: "- name: one\n  inputs:\n    module:\n      description: the module\n      required: true\n",
+			wantErr:   "tasks[0]: ",
+		},
+		{
+			name:      "unknown references",
+			generated: "- name: one\n  actions:\n    - task: missing\n",
+			wantErr:   "missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Runner{tasksFile: types.TasksFile{Tasks: []types.Task{task}}}
+			require.NoError(t, os.WriteFile(path, []byte(tt.generated), 0600))
+
+			generated, err := r.readGeneratedTasks(task, 0, path)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			names := []string{}
+			for _, generatedTask := range generated {
+				names = append(names, generatedTask.Name)
+			}
+			if tt.want == nil {
+				require.Empty(t, names)
+			} else {
+				require.Equal(t, tt.want, names)
+			}
+			require.Len(t, r.tasksFile.Tasks, 1+len(generated))
+
+			// The file is emptied for the next action
+			contents, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.Empty(t, contents)
+		})
+	}
+}
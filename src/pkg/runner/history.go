@@ -0,0 +1,222 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
+	"github.com/defenseunicorns/pkg/helpers/v2"
+)
+
+// RunHistoryDir returns the directory that the results of recent runs are recorded in
+func RunHistoryDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".maru", "runs"), nil
+}
+
+// recordRun writes the results of a run into the run history, removing the oldest runs so that at most keep are kept
+func recordRun(results RunResults, keep int) error {
+	dir, err := RunHistoryDir()
+	if err != nil {
+		return err
+	}
+	if err := helpers.CreateDirectory(dir, helpers.ReadWriteExecuteUser); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	if b, err = utils.Seal(b); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, results.RunID+".json"), b, helpers.ReadWriteUser); err != nil {
+		return err
+	}
+
+	// Run IDs sort by the time they were started, so the oldest runs are first
+	ids, err := ListRuns()
+	if err != nil {
+		return err
+	}
+	for len(ids) > keep {
+		if err := os.Remove(filepath.Join(dir, ids[0]+".json")); err != nil {
+			return err
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
+
+// ListRuns returns the IDs of the runs in the run history, oldest first
+func ListRuns() ([]string, error) {
+	dir, err := RunHistoryDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for _, entry := range entries {
+		if id, ok := strings.CutSuffix(entry.Name(), ".json"); ok && !entry.IsDir() {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// LoadRun loads the results of a run given its ID (or a unique prefix of it) in the run history, or the path to a
+// results file written with --results-file
+func LoadRun(ref string) (RunResults, error) {
+	var results RunResults
+
+	path := ref
+	if _, err := os.Stat(ref); err != nil {
+		ids, err := ListRuns()
+		if err != nil {
+			return results, err
+		}
+		matches := []string{}
+		for _, id := range ids {
+			if id == ref {
+				matches = []string{id}
+				break
+			}
+			if strings.HasPrefix(id, ref) {
+				matches = append(matches, id)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return results, fmt.Errorf("run %q was not found in the run history", ref)
+		case 1:
+		default:
+			return results, fmt.Errorf("run %q is ambiguous, it matches %s", ref, strings.Join(matches, ", "))
+		}
+		dir, err := RunHistoryDir()
+		if err != nil {
+			return results, err
+		}
+		path = filepath.Join(dir, matches[0]+".json")
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return results, err
+	}
+	if b, err = utils.Unseal(b); err != nil {
+		return results, fmt.Errorf("unable to read the results of run %q: %w", ref, err)
+	}
+	if err := json.Unmarshal(b, &results); err != nil {
+		return results, fmt.Errorf("unable to read the results of run %q: %w", ref, err)
+	}
+	return results, nil
+}
+
+// ActionDiff compares an action between two runs (an empty status means the action was not reached in that run)
+type ActionDiff struct {
+	Task     string
+	Action   int
+	Name     string
+	Status   [2]ActionStatus
+	Duration [2]float64
+	Reason   [2]string
+	Output   [2]string
+}
+
+// StatusChanged returns whether the action had a different outcome in the two runs
+func (d ActionDiff) StatusChanged() bool {
+	return d.Status[0] != d.Status[1]
+}
+
+// OutputChanged returns whether the action produced different output in the two runs
+func (d ActionDiff) OutputChanged() bool {
+	return d.Output[0] != d.Output[1]
+}
+
+// VariableDiff is a variable whose value differed between two runs (Set is false when a run did not have the variable)
+type VariableDiff struct {
+	Name  string
+	Value [2]string
+	Set   [2]bool
+}
+
+// RunDiff is the comparison of two runs
+type RunDiff struct {
+	Runs      [2]RunResults
+	Actions   []ActionDiff
+	Variables []VariableDiff
+}
+
+// DiffRuns compares two runs action by action and variable by variable. Actions are matched by their task, their index
+// in the task and how many times that action had already run (so actions in tasks that are called more than once are
+// compared in order), and are returned in the order they ran in the first run followed by any that only ran in the
+// second run.
+func DiffRuns(a, b RunResults) RunDiff {
+	diff := RunDiff{Runs: [2]RunResults{a, b}}
+
+	type actionKey struct {
+		task       string
+		action     int
+		occurrence int
+	}
+	index := map[actionKey]int{}
+	for i, run := range diff.Runs {
+		seen := map[actionKey]int{}
+		for _, result := range run.Actions {
+			base := actionKey{task: result.Task, action: result.Action}
+			key := actionKey{task: result.Task, action: result.Action, occurrence: seen[base]}
+			seen[base]++
+
+			pos, ok := index[key]
+			if !ok {
+				pos = len(diff.Actions)
+				index[key] = pos
+				diff.Actions = append(diff.Actions, ActionDiff{Task: result.Task, Action: result.Action, Name: result.Name})
+			}
+			diff.Actions[pos].Status[i] = result.Status
+			diff.Actions[pos].Duration[i] = result.Duration
+			diff.Actions[pos].Reason[i] = result.Reason
+			diff.Actions[pos].Output[i] = result.Output
+		}
+	}
+
+	names := []string{}
+	for name := range a.Variables {
+		names = append(names, name)
+	}
+	for name := range b.Variables {
+		if _, ok := a.Variables[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		valueA, setA := a.Variables[name]
+		valueB, setB := b.Variables[name]
+		if valueA != valueB || setA != setB {
+			diff.Variables = append(diff.Variables, VariableDiff{Name: name, Value: [2]string{valueA, valueB}, Set: [2]bool{setA, setB}})
+		}
+	}
+
+	return diff
+}
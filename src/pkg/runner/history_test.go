@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+)
+
+func TestRunHistory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	keyring.MockInit()
+
+	ids, err := ListRuns()
+	require.NoError(t, err)
+	require.Empty(t, ids)
+
+	for _, id := range []string{"20240101T000000Z-aaaa", "20240102T000000Z-bbbb", "20240103T000000Z-cccc"} {
+		require.NoError(t, recordRun(RunResults{RunID: id, Task: "default"}, 2))
+	}
+
+	// Only the most recent runs are kept
+	ids, err = ListRuns()
+	require.NoError(t, err)
+	require.Equal(t, []string{"20240102T000000Z-bbbb", "20240103T000000Z-cccc"}, ids)
+
+	run, err := LoadRun("20240103")
+	require.NoError(t, err)
+	require.Equal(t, "20240103T000000Z-cccc", run.RunID)
+
+	_, err = LoadRun("2024")
+	require.ErrorContains(t, err, "is ambiguous")
+	_, err = LoadRun("20240101")
+	require.EqualError(t, err, `run "20240101" was not found in the run history`)
+
+	// Results files can be loaded by path
+	dir, err := RunHistoryDir()
+	require.NoError(t, err)
+	recorded, err := os.ReadFile(filepath.Join(dir, "20240102T000000Z-bbbb.json"))
+	require.NoError(t, err)
+	require.NotContains(t, string(recorded), "20240102T000000Z-bbbb", "the run history should be encrypted")
+	run, err = LoadRun(filepath.Join(dir, "20240102T000000Z-bbbb.json"))
+	require.NoError(t, err)
+	require.Equal(t, "20240102T000000Z-bbbb", run.RunID)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "20240104T000000Z-dddd.json"), []byte("not json"), 0600))
+	_, err = LoadRun("20240104")
+	require.ErrorContains(t, err, "unable to read the results of run")
+}
+
+func TestDiffRuns(t *testing.T) {
+	a := RunResults{
+		RunID:  "a",
+		Status: ActionSucceeded,
+		Actions: []ActionResult{
+			{Task: "build", Action: 0, Name: "echo", Status: ActionSucceeded, Output: "v1"},
+			{Task: "test", Action: 0, Name: "test", Status: ActionSucceeded},
+			{Task: "test", Action: 0, Name: "test", Status: ActionSucceeded, Duration: 1},
+			{Task: "build", Action: 1, Name: "deploy", Status: ActionSucceeded},
+		},
+		Variables: map[string]string{"VERSION": "1", "SAME": "x", "REMOVED": "y"},
+	}
+	b := RunResults{
+		RunID:  "b",
+		Status: ActionFailed,
+		Actions: []ActionResult{
+			{Task: "build", Action: 0, Name: "echo", Status: ActionSucceeded, Output: "v2"},
+			{Task: "test", Action: 0, Name: "test", Status: ActionSucceeded},
+			{Task: "test", Action: 0, Name: "test", Status: ActionFailed, Reason: "boom", Duration: 2},
+			{Task: "cleanup", Action: 0, Name: "cleanup", Status: ActionSucceeded},
+		},
+		Variables: map[string]string{"VERSION": "2", "SAME": "x", "ADDED": "z"},
+	}
+
+	diff := DiffRuns(a, b)
+	require.Len(t, diff.Actions, 5)
+
+	require.True(t, diff.Actions[0].OutputChanged())
+	require.False(t, diff.Actions[0].StatusChanged())
+	require.False(t, diff.Actions[1].StatusChanged())
+
+	// The second run of the same action is compared with the second run in the other run
+	require.Equal(t, [2]ActionStatus{ActionSucceeded, ActionFailed}, diff.Actions[2].Status)
+	require.Equal(t, [2]float64{1, 2}, diff.Actions[2].Duration)
+	require.Equal(t, [2]string{"", "boom"}, diff.Actions[2].Reason)
+
+	require.Equal(t, [2]ActionStatus{ActionSucceeded, ""}, diff.Actions[3].Status)
+	require.Equal(t, "cleanup", diff.Actions[4].Task)
+	require.Equal(t, [2]ActionStatus{"", ActionSucceeded}, diff.Actions[4].Status)
+
+	require.Equal(t, []VariableDiff{
+		{Name: "ADDED", Value: [2]string{"", "z"}, Set: [2]bool{false, true}},
+		{Name: "REMOVED", Value: [2]string{"y", ""}, Set: [2]bool{true, false}},
+		{Name: "VERSION", Value: [2]string{"1", "2"}, Set: [2]bool{true, true}},
+	}, diff.Variables)
+}
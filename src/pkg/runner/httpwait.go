@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+)
+
+const (
+	defaultHTTPWaitInitial = time.Second
+	defaultHTTPWaitMax     = 30 * time.Second
+	defaultHTTPWaitFactor  = 2
+	// httpWaitRequestTimeout bounds how long a single request of an HTTP wait can take
+	httpWaitRequestTimeout = 10 * time.Second
+	// httpWaitMaxBody bounds how much of a response body is read when checking it for the expected body
+	httpWaitMaxBody = 1024 * 1024
+)
+
+// isHTTPWait returns whether a wait is an HTTP wait that Maru performs itself (rather than with zarf tools wait-for),
+// which is the case when it sets headers, an expected body or a backoff
+func isHTTPWait(wait *types.ActionWait) bool {
+	if wait == nil || wait.Network == nil || wait.Cluster != nil {
+		return false
+	}
+	network := wait.Network
+	return strings.HasPrefix(strings.ToLower(network.Protocol), "http") &&
+		(len(network.Headers) > 0 || network.Body != "" || network.Backoff != nil)
+}
+
+// httpWaitURL returns the URL an HTTP wait requests
+func httpWaitURL(network types.ActionWaitNetwork) string {
+	if strings.Contains(network.Address, "://") {
+		return network.Address
+	}
+	return fmt.Sprintf("%s://%s", strings.ToLower(network.Protocol), network.Address)
+}
+
+// httpWaitBackoff returns the initial delay, max delay and factor of an HTTP wait's backoff
+func httpWaitBackoff(backoff *types.ActionWaitBackoff) (time.Duration, time.Duration, float64, error) {
+	initial, maxDelay, factor := defaultHTTPWaitInitial, defaultHTTPWaitMax, float64(defaultHTTPWaitFactor)
+	if backoff == nil {
+		return initial, maxDelay, factor, nil
+	}
+
+	var err error
+	if backoff.Initial != "" {
+		if initial, err = time.ParseDuration(backoff.Initial); err != nil || initial <= 0 {
+			return 0, 0, 0, fmt.Errorf("invalid backoff initial %q: must be a positive duration (i.e. 500ms or 2s)", backoff.Initial)
+		}
+	}
+	if backoff.Max != "" {
+		if maxDelay, err = time.ParseDuration(backoff.Max); err != nil || maxDelay <= 0 {
+			return 0, 0, 0, fmt.Errorf("invalid backoff max %q: must be a positive duration (i.e. 10s or 1m)", backoff.Max)
+		}
+	}
+	if backoff.Factor != 0 {
+		if backoff.Factor < 1 {
+			return 0, 0, 0, fmt.Errorf("invalid backoff factor %v: must be at least 1", backoff.Factor)
+		}
+		factor = backoff.Factor
+	}
+	return initial, max(initial, maxDelay), factor, nil
+}
+
+// runHTTPWait performs an HTTP wait, requesting the address (with backoff between requests) until it responds with the
+// expected status code and body or the wait times out
+func runHTTPWait[T any](ctx context.Context, action *types.BaseAction[T], variableConfig *variables.VariableConfig[T], dryRun bool) error {
+	network := *action.Wait.Network
+	url := httpWaitURL(network)
+
+	description := action.Description
+	if description == "" {
+		description = url
+	}
+
+	timeout := 300
+	if action.MaxTotalSeconds != nil {
+		timeout = *action.MaxTotalSeconds
+	}
+	initial, maxDelay, factor, err := httpWaitBackoff(network.Backoff)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		message.SLog.Info(fmt.Sprintf("Dry-running wait for %q (timeout: %ds)", url, timeout))
+		return nil
+	}
+
+	// Template the header values so that secrets can be passed in through variables
+	headers := map[string]string{}
+	for name, value := range network.Headers {
+		headers[name] = utils.TemplateString(variableConfig.GetSetVariables(), value)
+	}
+
+	code := network.Code
+	if code == 0 {
+		code = http.StatusOK
+	}
+
+	spinner := message.NewProgressSpinner("Waiting for %s (timeout: %ds)", description, timeout)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, time.Duration(timeout)*time.Second, fmt.Errorf("wait for %s timed out after %d seconds", url, timeout))
+		defer cancel()
+	}
+
+	client := &http.Client{Timeout: httpWaitRequestTimeout}
+	delay := initial
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		err := checkHTTP(ctx, client, url, headers, code, network.Body)
+		if err == nil {
+			spinner.Successf("Wait for %s succeeded", description)
+			return nil
+		}
+		// An attempt cut off by the wait timing out says nothing about the address, so the one before it is reported
+		if ctx.Err() == nil || lastErr == nil {
+			lastErr = err
+		}
+		message.SLog.Debug(fmt.Sprintf("Attempt %d waiting for %s: %s", attempt, url, err.Error()))
+		spinner.Updatef("Waiting for %s (attempt %d: %s)", description, attempt, lastErr.Error())
+
+		select {
+		case <-ctx.Done():
+			spinner.Failf("Wait for %s failed", description)
+			return fmt.Errorf("%w (last attempt: %s)", context.Cause(ctx), lastErr.Error())
+		case <-time.After(delay):
+		}
+		delay = min(time.Duration(float64(delay)*factor), maxDelay)
+	}
+}
+
+// checkHTTP requests the url once, returning why it did not respond with the expected status code and body (if it did not)
+func checkHTTP(ctx context.Context, client *http.Client, url string, headers map[string]string, code int, body string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != code {
+		return fmt.Errorf("got status %d, want %d", resp.StatusCode, code)
+	}
+	if body == "" {
+		return nil
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, httpWaitMaxBody))
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(string(b), body) {
+		return fmt.Errorf("response body does not contain %q", body)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_httpWaitBackoff(t *testing.T) {
+	tests := []struct {
+		name        string
+		backoff     *types.ActionWaitBackoff
+		wantInitial time.Duration
+		wantMax     time.Duration
+		wantFactor  float64
+		wantErrMsg  string
+	}{
+		{
+			name:        "defaults",
+			wantInitial: time.Second,
+			wantMax:     30 * time.Second,
+			wantFactor:  2,
+		},
+		{
+			name:        "custom",
+			backoff:     &types.ActionWaitBackoff{Initial: "100ms", Max: "2s", Factor: 1.5},
+			wantInitial: 100 * time.Millisecond,
+			wantMax:     2 * time.Second,
+			wantFactor:  1.5,
+		},
+		{
+			name:        "max is at least the initial delay",
+			backoff:     &types.ActionWaitBackoff{Initial: "1m"},
+			wantInitial: time.Minute,
+			wantMax:     time.Minute,
+			wantFactor:  2,
+		},
+		{
+			name:       "invalid initial",
+			backoff:    &types.ActionWaitBackoff{Initial: "soon"},
+			wantErrMsg: `invalid backoff initial "soon"`,
+		},
+		{
+			name:       "invalid factor",
+			backoff:    &types.ActionWaitBackoff{Factor: 0.5},
+			wantErrMsg: "invalid backoff factor 0.5: must be at least 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			initial, maxDelay, factor, err := httpWaitBackoff(tt.backoff)
+			if tt.wantErrMsg != "" {
+				require.ErrorContains(t, err, tt.wantErrMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantInitial, initial)
+			require.Equal(t, tt.wantMax, maxDelay)
+			require.Equal(t, tt.wantFactor, factor)
+		})
+	}
+}
+
+func TestRunAction_httpWait(t *testing.T) {
+	// The server only becomes ready on the third request and requires a token
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if requests.Add(1) < 3 {
+			w.Write([]byte(`{"status":"starting"}`))
+			return
+		}
+		w.Write([]byte(`{"status":"ready"}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		network    types.ActionWaitNetwork
+		timeout    int
+		wantErrMsg string
+	}{
+		{
+			name: "waits for the expected body with headers",
+			network: types.ActionWaitNetwork{
+				Headers: map[string]string{"Authorization": "Bearer ${TOKEN}"},
+				Body:    `"status":"ready"`,
+				Backoff: &types.ActionWaitBackoff{Initial: "10ms"},
+			},
+			timeout: 10,
+		},
+		{
+			name: "times out without the headers",
+			network: types.ActionWaitNetwork{
+				Backoff: &types.ActionWaitBackoff{Initial: "10ms", Max: "50ms"},
+			},
+			timeout:    1,
+			wantErrMsg: "timed out after 1 seconds (last attempt: got status 401, want 200)",
+		},
+		{
+			name: "times out when the body never matches",
+			network: types.ActionWaitNetwork{
+				Headers: map[string]string{"Authorization": "Bearer ${TOKEN}"},
+				Body:    "never",
+				Backoff: &types.ActionWaitBackoff{Initial: "10ms", Max: "50ms"},
+			},
+			timeout:    1,
+			wantErrMsg: `(last attempt: response body does not contain "never")`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.network.Protocol = "http"
+			tt.network.Address = server.URL
+			action := types.BaseAction[variables.ExtraVariableInfo]{
+				Wait:            &types.ActionWait{Network: &tt.network},
+				MaxTotalSeconds: &tt.timeout,
+			}
+			variableConfig := GetMaruVariableConfig()
+			variableConfig.SetVariable("TOKEN", "secret", "", variables.ExtraVariableInfo{})
+
+			err := RunActionWithContext(context.TODO(), &action, "", variableConfig, false)
+			if tt.wantErrMsg != "" {
+				require.ErrorContains(t, err, tt.wantErrMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, requests.Load(), int32(3))
+		})
+	}
+}
@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	osexec "os/exec"
+	"slices"
+	"strings"
+
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+)
+
+// k8sRequestTimeout bounds how long kubectl can take to read a variable from the cluster
+const k8sRequestTimeout = "30s"
+
+// kubectlCommand builds the kubectl command used to read variables from the cluster (swapped out in tests)
+var kubectlCommand = func(ctx context.Context, args ...string) *osexec.Cmd {
+	return osexec.CommandContext(ctx, "kubectl", args...)
+}
+
+// K8sReference is a parsed `fromK8s` reference to a key of a ConfigMap or Secret
+type K8sReference struct {
+	// Kind is either configmap or secret
+	Kind      string
+	Namespace string
+	Name      string
+	Key       string
+}
+
+// ParseK8sReference parses a `fromK8s` reference in the form [configmap/|secret/]<namespace>/<name>/<key> (references
+// without a kind are to a ConfigMap)
+func ParseK8sReference(ref string) (K8sReference, error) {
+	parts := strings.Split(ref, "/")
+	kind := "configmap"
+	if len(parts) == 4 {
+		kind, parts = strings.ToLower(parts[0]), parts[1:]
+	}
+	if len(parts) != 3 || slices.Contains(parts, "") {
+		return K8sReference{}, fmt.Errorf("fromK8s %q must be in the form [configmap/|secret/]<namespace>/<name>/<key>", ref)
+	}
+	if kind != "configmap" && kind != "secret" {
+		return K8sReference{}, fmt.Errorf("fromK8s %q has an unknown kind %q (must be configmap or secret)", ref, kind)
+	}
+	return K8sReference{Kind: kind, Namespace: parts[0], Name: parts[1], Key: parts[2]}, nil
+}
+
+// String returns the reference in the form <kind>/<namespace>/<name>/<key>
+func (ref K8sReference) String() string {
+	return fmt.Sprintf("%s/%s/%s/%s", ref.Kind, ref.Namespace, ref.Name, ref.Key)
+}
+
+// readK8sValue reads the value of a key of a ConfigMap or Secret with kubectl (using the current kubecontext)
+func readK8sValue(ctx context.Context, ref K8sReference) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := kubectlCommand(ctx, "get", ref.Kind, ref.Name, "--namespace", ref.Namespace, "--output", "json", "--request-timeout", k8sRequestTimeout)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("unable to read %s/%s from namespace %s: %s", ref.Kind, ref.Name, ref.Namespace, msg)
+		}
+		return "", fmt.Errorf("unable to read %s/%s from namespace %s: %w", ref.Kind, ref.Name, ref.Namespace, err)
+	}
+
+	var object struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &object); err != nil {
+		return "", fmt.Errorf("unable to read %s/%s from namespace %s: %w", ref.Kind, ref.Name, ref.Namespace, err)
+	}
+	value, ok := object.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("%s/%s in namespace %s does not have a key %q", ref.Kind, ref.Name, ref.Namespace, ref.Key)
+	}
+
+	if ref.Kind == "secret" {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return "", fmt.Errorf("unable to decode key %q of secret/%s in namespace %s: %w", ref.Key, ref.Name, ref.Namespace, err)
+		}
+		value = string(decoded)
+	}
+	return value, nil
+}
+
+// resolveK8sVariables sets the variables that are read from the cluster with `fromK8s` (unless they were set with --set,
+// an environment variable or a session), marking variables read from a Secret as sensitive and masking their values
+func resolveK8sVariables(ctx context.Context, vars []variables.InteractiveVariable[variables.ExtraVariableInfo], variableConfig *variables.VariableConfig[variables.ExtraVariableInfo], setVariables map[string]string, dryRun bool) error {
+	var errs []error
+	for _, v := range vars {
+		if v.Extra.FromK8s == "" {
+			continue
+		}
+		ref, err := ParseK8sReference(v.Extra.FromK8s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("variable %s: %w", v.Name, err))
+			continue
+		}
+		if _, ok := setVariables[v.Name]; ok {
+			continue
+		}
+
+		if dryRun {
+			message.SLog.Info(fmt.Sprintf("Dry-run: not reading variable %s from %s", v.Name, ref))
+			continue
+		}
+
+		value, err := readK8sValue(ctx, ref)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("variable %s: %w", v.Name, err))
+			continue
+		}
+		message.SLog.Debug(fmt.Sprintf("Read variable %s from %s", v.Name, ref))
+		extra := v.Extra
+		extra.Sensitive = extra.Sensitive || ref.Kind == "secret"
+		if ref.Kind == "secret" {
+			message.AddSensitive(value)
+		}
+		variableConfig.SetVariable(v.Name, value, v.Pattern, extra)
+		if err := variableConfig.CheckVariablePattern(v.Name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
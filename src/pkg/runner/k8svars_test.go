@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"slices"
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHelperKubectlProcess stands in for kubectl, printing the version or the ConfigMap or Secret that was requested
+func TestHelperKubectlProcess(t *testing.T) {
+	if os.Getenv("MARU_TEST_KUBECTL_HELPER") == "" {
+		return
+	}
+
+	args := os.Args
+	switch {
+	case slices.Contains(args, "version"):
+		fmt.Println(`{"serverVersion":{"gitVersion":"v1.30.2"}}`)
+	case slices.Contains(args, "app-config"):
+		fmt.Println(`{"kind":"ConfigMap","data":{"domain":"uds.dev"}}`)
+	case slices.Contains(args, "app-db"):
+		// czNjcjN0LXB3 is "s3cr3t-pw"
+		fmt.Println(`{"kind":"Secret","data":{"password":"czNjcjN0LXB3"}}`)
+	default:
+		fmt.Fprintf(os.Stderr, "Error from server (NotFound): %s %q not found\n", args[slices.Index(args, "get")+1], args[slices.Index(args, "get")+2])
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func TestParseK8sReference(t *testing.T) {
+	tests := []struct {
+		ref        string
+		want       K8sReference
+		wantErrMsg string
+	}{
+		{ref: "platform/app-config/domain", want: K8sReference{Kind: "configmap", Namespace: "platform", Name: "app-config", Key: "domain"}},
+		{ref: "configmap/platform/app-config/domain", want: K8sReference{Kind: "configmap", Namespace: "platform", Name: "app-config", Key: "domain"}},
+		{ref: "Secret/platform/app-db/password", want: K8sReference{Kind: "secret", Namespace: "platform", Name: "app-db", Key: "password"}},
+		{ref: "app-config/domain", wantErrMsg: `fromK8s "app-config/domain" must be in the form [configmap/|secret/]<namespace>/<name>/<key>`},
+		{ref: "platform//domain", wantErrMsg: `fromK8s "platform//domain" must be in the form`},
+		{ref: "pod/platform/app/name", wantErrMsg: `fromK8s "pod/platform/app/name" has an unknown kind "pod" (must be configmap or secret)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := ParseK8sReference(tt.ref)
+			if tt.wantErrMsg != "" {
+				require.ErrorContains(t, err, tt.wantErrMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// useHelperKubectl swaps kubectl for TestHelperKubectlProcess until the test finishes
+func useHelperKubectl(t *testing.T) {
+	kubectlCommand = func(ctx context.Context, args ...string) *osexec.Cmd {
+		cmd := osexec.CommandContext(ctx, os.Args[0], append([]string{"-test.run=TestHelperKubectlProcess", "--"}, args...)...)
+		cmd.Env = append(os.Environ(), "MARU_TEST_KUBECTL_HELPER=1")
+		return cmd
+	}
+	t.Cleanup(func() {
+		kubectlCommand = func(ctx context.Context, args ...string) *osexec.Cmd {
+			return osexec.CommandContext(ctx, "kubectl", args...)
+		}
+	})
+}
+
+func Test_resolveK8sVariables(t *testing.T) {
+	useHelperKubectl(t)
+
+	newVariable := func(name, fromK8s string) variables.InteractiveVariable[variables.ExtraVariableInfo] {
+		return variables.InteractiveVariable[variables.ExtraVariableInfo]{
+			Variable: variables.Variable[variables.ExtraVariableInfo]{Name: name, Extra: variables.ExtraVariableInfo{FromK8s: fromK8s}},
+		}
+	}
+
+	t.Run("reads ConfigMaps and Secrets", func(t *testing.T) {
+		t.Cleanup(message.ClearSensitive)
+		vars := []variables.InteractiveVariable[variables.ExtraVariableInfo]{
+			newVariable("DOMAIN", "platform/app-config/domain"),
+			newVariable("DB_PASSWORD", "secret/platform/app-db/password"),
+			newVariable("PLAIN", ""),
+		}
+		variableConfig := GetMaruVariableConfig()
+		require.NoError(t, resolveK8sVariables(context.TODO(), vars, variableConfig, nil, false))
+
+		domain, ok := variableConfig.GetSetVariable("DOMAIN")
+		require.True(t, ok)
+		require.Equal(t, "uds.dev", domain.Value)
+		require.False(t, domain.Extra.Sensitive)
+
+		password, ok := variableConfig.GetSetVariable("DB_PASSWORD")
+		require.True(t, ok)
+		require.Equal(t, "s3cr3t-pw", password.Value)
+		require.True(t, password.Extra.Sensitive)
+
+		// Values read from a Secret are masked in everything that is printed, values from a ConfigMap are not
+		require.Equal(t, "password="+message.SanitizedValue+" domain=uds.dev", message.Mask("password=s3cr3t-pw domain=uds.dev"))
+
+		_, ok = variableConfig.GetSetVariable("PLAIN")
+		require.False(t, ok)
+	})
+
+	t.Run("set variables take precedence", func(t *testing.T) {
+		vars := []variables.InteractiveVariable[variables.ExtraVariableInfo]{newVariable("DOMAIN", "platform/missing/domain")}
+		variableConfig := GetMaruVariableConfig()
+		setVariables := map[string]string{"DOMAIN": "example.com"}
+		require.NoError(t, variableConfig.PopulateVariables(vars, setVariables))
+		require.NoError(t, resolveK8sVariables(context.TODO(), vars, variableConfig, setVariables, false))
+
+		domain, ok := variableConfig.GetSetVariable("DOMAIN")
+		require.True(t, ok)
+		require.Equal(t, "example.com", domain.Value)
+	})
+
+	t.Run("dry runs do not read the cluster", func(t *testing.T) {
+		vars := []variables.InteractiveVariable[variables.ExtraVariableInfo]{newVariable("DOMAIN", "platform/missing/domain")}
+		require.NoError(t, resolveK8sVariables(context.TODO(), vars, GetMaruVariableConfig(), nil, true))
+	})
+
+	t.Run("reports every variable that could not be read", func(t *testing.T) {
+		vars := []variables.InteractiveVariable[variables.ExtraVariableInfo]{
+			newVariable("MISSING", "platform/missing/domain"),
+			newVariable("NO_KEY", "platform/app-config/port"),
+			newVariable("INVALID", "app-config/domain"),
+		}
+		err := resolveK8sVariables(context.TODO(), vars, GetMaruVariableConfig(), nil, false)
+		require.ErrorContains(t, err, `variable MISSING: unable to read configmap/missing from namespace platform: Error from server (NotFound): configmap "missing" not found`)
+		require.ErrorContains(t, err, `variable NO_KEY: configmap/app-config in namespace platform does not have a key "port"`)
+		require.ErrorContains(t, err, `variable INVALID: fromK8s "app-config/domain" must be in the form`)
+	})
+}
@@ -0,0 +1,367 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/defenseunicorns/pkg/helpers/v2"
+)
+
+// lineMarker is the shell function that instrumented scripts call before each line to record the line they are on.
+// It keeps the exit status of the previous command for scripts that check $? (unless errexit is on, where a failing
+// status would stop the script).
+const lineMarker = `maru_line() { maru_line_status=$?; printf '%%s\n' "$1" >| %s; case $- in *e*) return 0;; esac; return "$maru_line_status"; }; `
+
+// lineSkipWords are the words that continue a compound command, so a line starting with one cannot be marked
+var lineSkipWords = []string{"then", "do", "else", "elif", "fi", "done", "esac", "in"}
+
+// ScriptLineError is returned when a line of a multi-line script fails
+type ScriptLineError struct {
+	Line     int
+	Command  string
+	ExitCode int
+}
+
+// Error returns the line that failed along with its exit status
+func (e *ScriptLineError) Error() string {
+	return fmt.Sprintf("line %d exited with status %d: %s", e.Line, e.ExitCode, helpers.Truncate(strings.TrimSpace(e.Command), 60, false))
+}
+
+// isPOSIXShell returns whether the given shell runs POSIX shell scripts
+func isPOSIXShell(shell string) bool {
+	switch shellName(shell) {
+	case "powershell", "pwsh", "cmd", "fish":
+		return false
+	default:
+		return true
+	}
+}
+
+// shellName returns the name of a shell without its path or extension
+func shellName(shell string) string {
+	return strings.TrimSuffix(strings.ToLower(filepath.Base(shell)), ".exe")
+}
+
+// instrumentLines returns the script with a call recording the line number before each line that can safely be
+// marked (lines inside quotes, heredocs, case statements or continued commands are left as they are). Markers are
+// added to the start of each line so the shell's own line numbers still match the script.
+func instrumentLines(script, lineFile string) string {
+	lines := strings.Split(script, "\n")
+	markable := markableLines(lines)
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf(lineMarker, shellQuote(filepath.ToSlash(lineFile))))
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if markable[i] {
+			b.WriteString(fmt.Sprintf("maru_line %d; ", i+1))
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// failedLine returns err as a ScriptLineError when it is a failing exit status and the script recorded the line it was on
+func failedLine(err error, lineFile string, lines []string) error {
+	var exitErr *osexec.ExitError
+	if lineFile == "" || !errors.As(err, &exitErr) {
+		return err
+	}
+	b, readErr := os.ReadFile(lineFile)
+	if readErr != nil {
+		return err
+	}
+	line, convErr := strconv.Atoi(strings.TrimSpace(string(b)))
+	if convErr != nil || line < 1 || line > len(lines) {
+		return err
+	}
+	return &ScriptLineError{Line: line, Command: lines[line-1], ExitCode: exitErr.ExitCode()}
+}
+
+// shellQuote single quotes a string for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// markableLines returns which lines of a POSIX shell script start a new command, so a line marker can be added before
+// them without changing what the script does. This is deliberately conservative: it tracks quotes, expansions,
+// subshells, arrays, heredocs, case statements and continued commands, and does not mark any line it is unsure about
+// (or any line at all if the script does not end with everything it opened closed).
+func markableLines(lines []string) []bool {
+	markable := make([]bool, len(lines))
+	s := &lineScanner{}
+
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+
+		// Skip the bodies of any heredocs started on the previous line
+		if len(s.heredocs) > 0 {
+			doc := s.heredocs[0]
+			if doc.stripTabs {
+				line = strings.TrimLeft(line, "\t")
+			}
+			if line == doc.delimiter {
+				s.heredocs = s.heredocs[1:]
+			}
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		markable[i] = len(s.contexts) == 0 && s.caseDepth == 0 && !s.continued && startsCommand(trimmed)
+		s.scan(line)
+	}
+
+	// Syntax left open means the scan misread the script somewhere, so none of its lines can be trusted
+	if len(s.contexts) > 0 || len(s.heredocs) > 0 || s.caseDepth > 0 || s.continued {
+		return make([]bool, len(lines))
+	}
+	return markable
+}
+
+// startsCommand returns whether a (trimmed) line starts a new command rather than continuing a compound command
+func startsCommand(line string) bool {
+	if line == "" || strings.ContainsAny(line[:1], "#;|&(){}") {
+		return false
+	}
+	word := line
+	if end := strings.IndexAny(line, " \t;&|)"); end >= 0 {
+		word = line[:end]
+	}
+	return !slices.Contains(lineSkipWords, word)
+}
+
+// heredoc is a heredoc whose body starts on the next line
+type heredoc struct {
+	delimiter string
+	stripTabs bool
+}
+
+// lineScanner tracks the shell syntax that can span lines of a script
+type lineScanner struct {
+	// contexts are the open quotes, expansions and parentheses (', ", `, $(, $((, ${, (( and ( for subshells, arrays and
+	// groupings within expansions)
+	contexts []string
+	// heredocs are the heredocs whose bodies follow the current line
+	heredocs []heredoc
+	// caseDepth is the number of case statements that are open
+	caseDepth int
+	// continued is whether the last line continues onto the next one (i.e. it ended with a pipe or a backslash)
+	continued bool
+}
+
+// scan updates the scanner with a line of the script
+func (s *lineScanner) scan(line string) {
+	var (
+		word         strings.Builder
+		lastWord     string
+		commandStart = true
+	)
+	endWord := func() {
+		if word.Len() == 0 {
+			return
+		}
+		w := word.String()
+		word.Reset()
+		if len(s.contexts) == 0 && commandStart {
+			switch w {
+			case "case":
+				s.caseDepth++
+			case "esac":
+				s.caseDepth = max(s.caseDepth-1, 0)
+			}
+		}
+		lastWord = w
+		commandStart = slices.Contains([]string{"then", "do", "else", "elif", "if", "while", "until", "!", "{", "}"}, w)
+	}
+
+	s.continued = false
+	for j := 0; j < len(line); j++ {
+		c := line[j]
+		next := byte(0)
+		if j+1 < len(line) {
+			next = line[j+1]
+		}
+		top := ""
+		if len(s.contexts) > 0 {
+			top = s.contexts[len(s.contexts)-1]
+		}
+
+		switch top {
+		case "'":
+			if c == '\'' {
+				s.pop()
+			}
+			continue
+		case `"`, "${":
+			switch {
+			case c == '\\':
+				j++
+			case c == '"' && top == `"`, c == '}' && top == "${":
+				s.pop()
+			case top == "${" && (c == '"' || c == '\''):
+				s.push(string(c))
+			case c == '`':
+				s.push("`")
+			case c == '$' && next == '(':
+				j += s.pushExpansion(line[j:])
+			case c == '$' && next == '{':
+				s.push("${")
+				j++
+			}
+			continue
+		}
+
+		inWord := false
+		switch {
+		case c == '\\':
+			if j == len(line)-1 {
+				s.continued = true
+			} else {
+				word.WriteByte(line[j+1])
+				j++
+			}
+		case c == '#' && word.Len() == 0:
+			j = len(line)
+		case c == '\'' || c == '"':
+			s.push(string(c))
+			inWord = true
+		case c == '`':
+			if top == "`" {
+				s.pop()
+			} else {
+				s.push("`")
+			}
+			inWord = true
+		case c == '$' && next == '(':
+			j += s.pushExpansion(line[j:])
+			inWord = true
+		case c == '$' && next == '{':
+			s.push("${")
+			j++
+			inWord = true
+		case c == '(' && next == '(':
+			endWord()
+			s.push("((")
+			j++
+		case c == '(':
+			// Subshells and arrays (i.e. arr=( on its own line) span lines just like expansions do
+			if top == "" {
+				endWord()
+			}
+			s.push("(")
+		case c == ')' && (top == "(" || top == "$("):
+			s.pop()
+			inWord = top == "$("
+		case c == ')' && (top == "$((" || top == "((") && next == ')':
+			s.pop()
+			j++
+			inWord = top == "$(("
+		case c == '<' && next == '<' && !s.inArithmetic() && (j+2 >= len(line) || line[j+2] != '<'):
+			j = s.readHeredoc(line, j+2) - 1
+		case c == ' ' || c == '\t':
+		case strings.IndexByte(";&|()<>", c) >= 0:
+			endWord()
+			if len(s.contexts) == 0 {
+				commandStart = c != '<' && c != '>'
+			}
+		default:
+			inWord = true
+		}
+
+		if inWord {
+			word.WriteByte(c)
+		} else if c == ' ' || c == '\t' {
+			endWord()
+		}
+	}
+	endWord()
+
+	// Commands continue onto the next line after a pipe or list operator (or a for/case header)
+	if len(s.contexts) == 0 {
+		code := strings.TrimSpace(stripComment(line))
+		if strings.HasSuffix(code, "|") || strings.HasSuffix(code, "&&") || lastWord == "in" && !strings.HasSuffix(code, ";") {
+			s.continued = true
+		}
+	}
+}
+
+// pushExpansion opens a $( or $(( expansion at the start of the given text, returning the number of extra characters consumed
+func (s *lineScanner) pushExpansion(text string) int {
+	if strings.HasPrefix(text, "$((") {
+		s.push("$((")
+		return 2
+	}
+	s.push("$(")
+	return 1
+}
+
+// readHeredoc records the heredoc whose delimiter starts at the given index (just past the <<), returning the index after it
+func (s *lineScanner) readHeredoc(line string, j int) int {
+	doc := heredoc{}
+	if j < len(line) && line[j] == '-' {
+		doc.stripTabs = true
+		j++
+	}
+	for j < len(line) && (line[j] == ' ' || line[j] == '\t') {
+		j++
+	}
+
+	var delimiter strings.Builder
+	quote := byte(0)
+	for ; j < len(line); j++ {
+		c := line[j]
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			delimiter.WriteByte(c)
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '\\':
+		case strings.IndexByte(" \t;&|<>()", c) >= 0:
+			doc.delimiter = delimiter.String()
+			s.heredocs = append(s.heredocs, doc)
+			return j
+		default:
+			delimiter.WriteByte(c)
+		}
+	}
+	doc.delimiter = delimiter.String()
+	s.heredocs = append(s.heredocs, doc)
+	return j
+}
+
+// inArithmetic returns whether the scanner is within an arithmetic expression (where << is a shift, not a heredoc)
+func (s *lineScanner) inArithmetic() bool {
+	return slices.Contains(s.contexts, "$((") || slices.Contains(s.contexts, "((")
+}
+
+func (s *lineScanner) push(context string) {
+	s.contexts = append(s.contexts, context)
+}
+
+func (s *lineScanner) pop() {
+	s.contexts = s.contexts[:len(s.contexts)-1]
+}
+
+// stripComment removes a trailing comment from a line of a script that has no open quotes
+func stripComment(line string) string {
+	for j := 0; j < len(line); j++ {
+		if line[j] == '#' && (j == 0 || line[j-1] == ' ' || line[j-1] == '\t') {
+			return line[:j]
+		}
+	}
+	return line
+}
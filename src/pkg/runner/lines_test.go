@@ -0,0 +1,198 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"context"
+	osexec "os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/defenseunicorns/pkg/helpers/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// lineTestScript exercises the shell syntax that spans lines
+const lineTestScript = `echo start
+if [ -n "x" ]; then
+  echo "in if"
+else
+  echo "in else"
+fi
+for j in a \
+  b; do
+  echo "$j"
+done
+case "$1" in
+  a) echo a ;;
+  *) echo other ;;
+esac
+echo "multi
+line string"
+echo 'single
+quoted'
+cat <<EOF
+heredoc $1
+EOF
+cat <<-'END' | tr a-z A-Z
+	quoted heredoc
+	END
+echo one |
+  cat
+true &&
+  echo and
+f()
+{
+  echo "in function"
+}
+f
+echo "$(echo substitution
+)"
+echo $(( 1 +
+  2 ))
+x=${HOME:-"# not a comment"}
+# a comment
+echo "${x}" >/dev/null
+echo done`
+
+// lineTestMarkable are the lines of lineTestScript that start a new command
+var lineTestMarkable = []int{1, 2, 3, 5, 7, 9, 11, 15, 17, 19, 22, 25, 27, 29, 31, 33, 34, 36, 38, 40, 41}
+
+func Test_markableLines(t *testing.T) {
+	lines := strings.Split(lineTestScript, "\n")
+	markable := markableLines(lines)
+	for i, line := range lines {
+		require.Equal(t, slices.Contains(lineTestMarkable, i+1), markable[i], "line %d: %s", i+1, line)
+	}
+}
+
+// lineTestBashScript exercises the parentheses that span lines of bash scripts
+const lineTestBashScript = `arr=(
+  one
+  two
+)
+echo "${arr[@]}"
+(
+  cd /
+  echo "in subshell"
+)
+out=$(
+  echo substitution
+)
+echo "$out"
+(( total = 1 +
+  2 ))
+cat <<END | tr a-z A-Z
+$(( 1 << 2 )) $total
+END
+echo done`
+
+// lineTestBashMarkable are the lines of lineTestBashScript that start a new command
+var lineTestBashMarkable = []int{1, 5, 10, 13, 16, 19}
+
+func Test_markableLines_parentheses(t *testing.T) {
+	lines := strings.Split(lineTestBashScript, "\n")
+	markable := markableLines(lines)
+	for i, line := range lines {
+		require.Equal(t, slices.Contains(lineTestBashMarkable, i+1), markable[i], "line %d: %s", i+1, line)
+	}
+
+	// No line is marked in a script that the scan can't make sense of
+	require.Equal(t, []bool{false, false, false}, markableLines([]string{"echo one", "echo 'two", "echo three"}))
+	require.Equal(t, []bool{false, false}, markableLines([]string{"(", "echo one"}))
+}
+
+func Test_instrumentLines_bash(t *testing.T) {
+	if _, err := osexec.LookPath("bash"); err != nil || runtime.GOOS == "windows" {
+		t.Skip("bash is not installed")
+	}
+
+	// Instrumenting a script with arrays and subshells does not change what it does
+	lineFile := filepath.Join(t.TempDir(), "line")
+	want, err := osexec.Command("bash", "-e", "-c", lineTestBashScript).CombinedOutput()
+	require.NoError(t, err, string(want))
+	got, err := osexec.Command("bash", "-e", "-c", instrumentLines(lineTestBashScript, lineFile)).CombinedOutput()
+	require.NoError(t, err, string(got))
+	require.Equal(t, string(want), string(got))
+	require.Equal(t, "one two\nin subshell\nsubstitution\n4 3\ndone\n", string(got))
+}
+
+func Test_instrumentLines(t *testing.T) {
+	lineFile := filepath.Join(t.TempDir(), "line")
+	for _, shell := range []string{"sh", "bash"} {
+		if _, err := osexec.LookPath(shell); err != nil || runtime.GOOS == "windows" {
+			continue
+		}
+		t.Run(shell, func(t *testing.T) {
+			// Instrumenting a script does not change what it does
+			want, err := osexec.Command(shell, "-e", "-c", lineTestScript, "script", "a").CombinedOutput()
+			require.NoError(t, err, string(want))
+			got, err := osexec.Command(shell, "-e", "-c", instrumentLines(lineTestScript, lineFile), "script", "a").CombinedOutput()
+			require.NoError(t, err, string(got))
+			require.Equal(t, string(want), string(got))
+
+			// The exit status of the previous command is kept when errexit is off
+			script := "false\necho \"status $?\""
+			got, err = osexec.Command(shell, "-c", instrumentLines(script, lineFile)).CombinedOutput()
+			require.NoError(t, err, string(got))
+			require.Equal(t, "status 1\n", string(got))
+		})
+	}
+}
+
+func TestRunAction_failedLine(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("line reporting is only supported for POSIX shells")
+	}
+
+	tests := []struct {
+		name       string
+		cmd        string
+		maxRetries int
+		reportLine *bool
+		wantErrMsg string
+	}{
+		{
+			name:       "reports the line that failed",
+			cmd:        "echo one\nexit 3\necho three\n",
+			wantErrMsg: "failed after 0 retries: line 2 exited with status 3: exit 3",
+		},
+		{
+			name:       "reports the line within a function that failed",
+			cmd:        "check() {\n  echo checking\n  [ -n \"\" ]\n}\necho ok\ncheck\necho done",
+			wantErrMsg: `failed after 0 retries: line 3 exited with status 1: [ -n "" ]`,
+		},
+		{
+			name:       "reports the line from the last attempt",
+			cmd:        "echo one\n[ \"$MARU_ATTEMPT\" -ne 1 ]\nexit 4",
+			maxRetries: 1,
+			wantErrMsg: "failed after 1 retries: line 3 exited with status 4: exit 4",
+		},
+		{
+			name:       "does not report the line when turned off",
+			cmd:        "echo one\nexit 3\necho three\n",
+			reportLine: helpers.BoolPtr(false),
+			wantErrMsg: `command "echo one; exit 3; echo three; " failed after 0 retries`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action := types.BaseAction[variables.ExtraVariableInfo]{
+				Cmd:        tt.cmd,
+				MaxRetries: &tt.maxRetries,
+				ReportLine: tt.reportLine,
+			}
+			err := RunActionWithContext(context.TODO(), &action, "", GetMaruVariableConfig(), false)
+			require.ErrorContains(t, err, tt.wantErrMsg)
+			if tt.reportLine != nil {
+				require.NotContains(t, err.Error(), "line 2")
+			}
+		})
+	}
+}
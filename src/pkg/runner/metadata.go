@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/types"
+)
+
+// TaskOwner returns who owns a task as a single line (i.e. "@jane (platform)"), or an empty string if it has no owner
+func TaskOwner(task types.Task) string {
+	if task.Metadata == nil {
+		return ""
+	}
+	switch {
+	case task.Metadata.Owner != "" && task.Metadata.Team != "":
+		return fmt.Sprintf("%s (%s)", task.Metadata.Owner, task.Metadata.Team)
+	case task.Metadata.Owner != "":
+		return task.Metadata.Owner
+	default:
+		return task.Metadata.Team
+	}
+}
+
+// TaskLinks returns the documentation and runbook links of a task as a single line, or an empty string if it has none
+func TaskLinks(task types.Task) string {
+	if task.Metadata == nil {
+		return ""
+	}
+	links := []string{}
+	if task.Metadata.DocsURL != "" {
+		links = append(links, "docs: "+task.Metadata.DocsURL)
+	}
+	if task.Metadata.Runbook != "" {
+		links = append(links, "runbook: "+task.Metadata.Runbook)
+	}
+	return strings.Join(links, ", ")
+}
+
+// reportTaskOwner logs who owns a failing task and where to find help with it. Only the innermost failing task that
+// has an owner or links is reported (a failure is returned through every task that references the failing task).
+func (r *Runner) reportTaskOwner(task types.Task) {
+	if r.ownerReported {
+		return
+	}
+	owner, links := TaskOwner(task), TaskLinks(task)
+	if owner == "" && links == "" {
+		return
+	}
+	r.ownerReported = true
+
+	details := []string{}
+	if owner != "" {
+		details = append(details, "owned by "+owner)
+	}
+	if links != "" {
+		details = append(details, links)
+	}
+	message.SLog.Warn(fmt.Sprintf("Task %s failed, it is %s", task.Name, strings.Join(details, ", ")))
+}
@@ -0,0 +1,42 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskOwnerAndLinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata *types.TaskMetadata
+		owner    string
+		links    string
+	}{
+		{name: "no metadata"},
+		{name: "owner and team", metadata: &types.TaskMetadata{Owner: "@jane", Team: "platform"}, owner: "@jane (platform)"},
+		{name: "team only", metadata: &types.TaskMetadata{Team: "platform", Runbook: "https://example.com/runbook"}, owner: "platform", links: "runbook: https://example.com/runbook"},
+		{name: "links only", metadata: &types.TaskMetadata{DocsURL: "https://example.com/docs", Runbook: "https://example.com/runbook"}, links: "docs: https://example.com/docs, runbook: https://example.com/runbook"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := types.Task{Name: "deploy", Metadata: tt.metadata}
+			require.Equal(t, tt.owner, TaskOwner(task))
+			require.Equal(t, tt.links, TaskLinks(task))
+		})
+	}
+}
+
+func TestRunner_reportTaskOwner(t *testing.T) {
+	r := &Runner{}
+	r.reportTaskOwner(types.Task{Name: "unowned"})
+	require.False(t, r.ownerReported)
+
+	r.reportTaskOwner(types.Task{Name: "migrate", Metadata: &types.TaskMetadata{Team: "data"}})
+	require.True(t, r.ownerReported)
+}
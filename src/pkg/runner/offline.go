@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/config/lang"
+	"github.com/defenseunicorns/maru-runner/src/types"
+)
+
+// offlineLookupTimeout bounds how long resolving the host of a wait takes when maru is offline
+const offlineLookupTimeout = 5 * time.Second
+
+// waitHost returns the host a network wait connects to (i.e. example.com for https://example.com/healthz or
+// example.com:443)
+func waitHost(network types.ActionWaitNetwork) string {
+	address := network.Address
+	if strings.Contains(address, "://") {
+		if u, err := url.Parse(address); err == nil {
+			return u.Hostname()
+		}
+	}
+	address, _, _ = strings.Cut(address, "/")
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+	return strings.Trim(address, "[]")
+}
+
+// checkOfflineWait returns an error when maru is offline and the host of a network wait can't be resolved, so that the
+// wait fails right away rather than once it times out (waits for addresses and hosts that resolve are left alone since
+// they can be on a local or air-gapped network)
+func checkOfflineWait(ctx context.Context, network types.ActionWaitNetwork) error {
+	if !config.Offline {
+		return nil
+	}
+	host := waitHost(network)
+	if host == "" || net.ParseIP(host) != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, offlineLookupTimeout)
+	defer cancel()
+	if _, err := net.DefaultResolver.LookupHost(ctx, host); err != nil {
+		return fmt.Errorf("not waiting for %s as its host %s can't be resolved (%s): %w", network.Address, host, err.Error(), lang.ErrOffline)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/config/lang"
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_waitHost(t *testing.T) {
+	tests := map[string]string{
+		"https://example.com:8443/healthz": "example.com",
+		"example.com/healthz":              "example.com",
+		"example.com:443":                  "example.com",
+		"localhost":                        "localhost",
+		"127.0.0.1:8080":                   "127.0.0.1",
+		"[::1]:8080":                       "::1",
+		"[::1]":                            "::1",
+	}
+	for address, want := range tests {
+		require.Equal(t, want, waitHost(types.ActionWaitNetwork{Address: address}), address)
+	}
+}
+
+func Test_checkOfflineWait(t *testing.T) {
+	t.Cleanup(func() { config.Offline = false })
+	unresolvable := types.ActionWaitNetwork{Protocol: "https", Address: "maru-offline-test.invalid/healthz"}
+
+	// Online, waits are left to time out on their own
+	require.NoError(t, checkOfflineWait(context.Background(), unresolvable))
+
+	config.Offline = true
+	err := checkOfflineWait(context.Background(), unresolvable)
+	require.ErrorIs(t, err, lang.ErrOffline)
+	require.ErrorContains(t, err, "not waiting for maru-offline-test.invalid/healthz as its host maru-offline-test.invalid can't be resolved")
+	require.NoError(t, checkOfflineWait(context.Background(), types.ActionWaitNetwork{Protocol: "tcp", Address: "127.0.0.1:1"}))
+	require.NoError(t, checkOfflineWait(context.Background(), types.ActionWaitNetwork{Protocol: "http", Address: "localhost:8080"}))
+
+	// The wait fails without being retried
+	timeout := 60
+	action := &types.BaseAction[variables.ExtraVariableInfo]{Wait: &types.ActionWait{Network: &unresolvable}, MaxTotalSeconds: &timeout}
+	_, err = runAction(context.Background(), action, "", GetMaruVariableConfig(), false)
+	require.ErrorIs(t, err, lang.ErrOffline)
+}
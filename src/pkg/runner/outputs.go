@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+)
+
+// outputVariableName matches the names of variables that can be set from a task's output file
+var outputVariableName = regexp.MustCompile(`^[A-Z0-9_]+$`)
+
+// newOutputFile creates the (empty) output file for a call of a task
+func newOutputFile() (string, error) {
+	f, err := os.CreateTemp("", "maru-output-*")
+	if err != nil {
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+// foldOutputFile sets a variable for every NAME=value line the actions of a task wrote to its output file, keeping the
+// pattern and settings of an existing variable. Blank lines and lines starting with # are ignored.
+func (r *Runner) foldOutputFile(taskName, path string) error {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	for idx, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !outputVariableName.MatchString(name) {
+			return fmt.Errorf("line %d of the %s file of task %s must be NAME=value (with an uppercase NAME), not %q", idx+1, OutputFileEnv, taskName, line)
+		}
+
+		pattern, extra := "", variables.ExtraVariableInfo{}
+		if existing, ok := r.variableConfig.GetSetVariable(name); ok {
+			pattern, extra = existing.Pattern, existing.Extra
+		}
+		if pattern != "" && !regexp.MustCompile(pattern).MatchString(value) {
+			return fmt.Errorf("value for variable %q set by task %s does not match pattern %q", name, taskName, pattern)
+		}
+		r.variableConfig.SetVariable(name, value, pattern, extra)
+		message.SLog.Debug(fmt.Sprintf("Task %s set variable %s", taskName, name))
+	}
+	return nil
+}
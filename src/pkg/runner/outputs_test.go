@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_executeTask_outputFile(t *testing.T) {
+	cmd := func(cmd string) types.Action {
+		return types.Action{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: cmd}}
+	}
+	call := func(task string) types.Action {
+		return types.Action{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{}, TaskReference: task}
+	}
+	result := filepath.Join(t.TempDir(), "result")
+	tasks := []types.Task{
+		{Name: "default", Actions: []types.Action{
+			cmd(`echo "VERSION=1.2.3" >> "$MARU_OUTPUT"`),
+			// The values are only set once the task finishes
+			cmd(`echo "${VERSION:-unset}" > ` + result),
+			call("nested"),
+			cmd(`echo "${IMAGE}" >> ` + result),
+		}},
+		{Name: "nested", Actions: []types.Action{
+			cmd(`printf '# the image to deploy\n\nIMAGE=registry.example.com/app:v1=latest\r\n' >> "$MARU_OUTPUT"`),
+		}},
+	}
+	r := &Runner{variableConfig: GetMaruVariableConfig(), tasksFile: types.TasksFile{Tasks: tasks}}
+	require.NoError(t, r.executeTask(context.Background(), tasks[0], nil))
+
+	contents, err := os.ReadFile(result)
+	require.NoError(t, err)
+	require.Equal(t, "unset\nregistry.example.com/app:v1=latest\n", string(contents))
+	version, ok := r.variableConfig.GetSetVariable("VERSION")
+	require.True(t, ok)
+	require.Equal(t, "1.2.3", version.Value)
+}
+
+func TestRunner_foldOutputFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output")
+
+	t.Run("invalid lines", func(t *testing.T) {
+		r := &Runner{variableConfig: GetMaruVariableConfig()}
+		require.NoError(t, os.WriteFile(path, []byte("OK=1\nversion=2\n"), 0600))
+		require.EqualError(t, r.foldOutputFile("build", path), `line 2 of the MARU_OUTPUT file of task build must be NAME=value (with an uppercase NAME), not "version=2"`)
+
+		require.NoError(t, os.WriteFile(path, []byte("just some output\n"), 0600))
+		require.ErrorContains(t, r.foldOutputFile("build", path), "line 1 of the MARU_OUTPUT file")
+	})
+
+	t.Run("existing variables keep their settings", func(t *testing.T) {
+		r := &Runner{variableConfig: GetMaruVariableConfig()}
+		r.variableConfig.SetVariable("TOKEN", "old", "^[a-z]+$", variables.ExtraVariableInfo{Sensitive: true})
+
+		require.NoError(t, os.WriteFile(path, []byte("TOKEN=NEW\n"), 0600))
+		require.EqualError(t, r.foldOutputFile("build", path), `value for variable "TOKEN" set by task build does not match pattern "^[a-z]+$"`)
+
+		require.NoError(t, os.WriteFile(path, []byte("TOKEN=new\n"), 0600))
+		require.NoError(t, r.foldOutputFile("build", path))
+		token, _ := r.variableConfig.GetSetVariable("TOKEN")
+		require.Equal(t, "new", token.Value)
+		require.True(t, token.Extra.Sensitive)
+	})
+}
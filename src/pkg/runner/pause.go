@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
+	"github.com/defenseunicorns/maru-runner/src/types"
+)
+
+// pauseDuration returns how long a pause should last starting from now along with a description of it (a pause until a
+// time that has already passed lasts for no time at all)
+func pauseDuration(pause types.ActionPause, now time.Time) (time.Duration, string, error) {
+	switch {
+	case pause.Duration != "" && pause.Until != "":
+		return 0, "", errors.New("pause cannot have both a duration and an until time")
+	case pause.Duration != "":
+		duration, err := time.ParseDuration(pause.Duration)
+		if err != nil {
+			return 0, "", fmt.Errorf("pause duration %q is invalid (i.e. 30s or 5m): %w", pause.Duration, err)
+		}
+		if duration < 0 {
+			return 0, "", fmt.Errorf("pause duration %q cannot be negative", pause.Duration)
+		}
+		return duration, fmt.Sprintf("Pause for %s", duration), nil
+	case pause.Until != "":
+		until, err := time.Parse(time.RFC3339, pause.Until)
+		if err != nil {
+			return 0, "", fmt.Errorf("pause until %q is not an RFC 3339 timestamp (i.e. 2024-06-01T12:00:00Z): %w", pause.Until, err)
+		}
+		return max(until.Sub(now), 0), fmt.Sprintf("Pause until %s", until.Format(time.RFC3339)), nil
+	default:
+		return 0, "", errors.New("pause is missing a duration or until time")
+	}
+}
+
+// pause waits for the duration of a pause action, returning early if the run is cancelled (or times out)
+func (r *Runner) pause(ctx context.Context, action types.Action) error {
+	pause := *action.Pause
+	setVariables := r.variableConfig.GetSetVariables()
+	pause.Duration = utils.TemplateString(setVariables, pause.Duration)
+	pause.Until = utils.TemplateString(setVariables, pause.Until)
+	pause.Reason = utils.TemplateString(setVariables, pause.Reason)
+
+	duration, description, err := pauseDuration(pause, time.Now())
+	if err != nil {
+		return err
+	}
+	if pause.Reason != "" {
+		description = fmt.Sprintf("%s (%s)", description, pause.Reason)
+	}
+
+	if r.dryRun {
+		message.SLog.Info(fmt.Sprintf("Dry-running %q", description))
+		return nil
+	}
+
+	spinner := message.NewProgressSpinner("Running %q", description)
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		spinner.Successf("Completed %q", description)
+		return nil
+	case <-ctx.Done():
+		spinner.Failf("Interrupted %q", description)
+		return context.Cause(ctx)
+	}
+}
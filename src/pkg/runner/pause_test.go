@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_pauseDuration(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		pause       types.ActionPause
+		duration    time.Duration
+		description string
+		err         string
+	}{
+		{name: "duration", pause: types.ActionPause{Duration: "90s"}, duration: 90 * time.Second, description: "Pause for 1m30s"},
+		{name: "until", pause: types.ActionPause{Until: "2024-06-01T12:05:00Z"}, duration: 5 * time.Minute, description: "Pause until 2024-06-01T12:05:00Z"},
+		{name: "until in the past", pause: types.ActionPause{Until: "2024-06-01T11:00:00Z"}, duration: 0, description: "Pause until 2024-06-01T11:00:00Z"},
+		{name: "both", pause: types.ActionPause{Duration: "1s", Until: "2024-06-01T12:05:00Z"}, err: "pause cannot have both a duration and an until time"},
+		{name: "neither", pause: types.ActionPause{Reason: "settle"}, err: "pause is missing a duration or until time"},
+		{name: "invalid duration", pause: types.ActionPause{Duration: "soon"}, err: `pause duration "soon" is invalid`},
+		{name: "negative duration", pause: types.ActionPause{Duration: "-1s"}, err: `pause duration "-1s" cannot be negative`},
+		{name: "invalid until", pause: types.ActionPause{Until: "tomorrow"}, err: `pause until "tomorrow" is not an RFC 3339 timestamp`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			duration, description, err := pauseDuration(tt.pause, now)
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.duration, duration)
+			require.Equal(t, tt.description, description)
+		})
+	}
+}
+
+func TestRunner_pause(t *testing.T) {
+	t.Run("pauses for the duration", func(t *testing.T) {
+		r := &Runner{variableConfig: GetMaruVariableConfig()}
+		start := time.Now()
+		err := r.pause(context.Background(), types.Action{Pause: &types.ActionPause{Duration: "200ms", Reason: "let it settle"}})
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	})
+
+	t.Run("is interrupted when cancelled", func(t *testing.T) {
+		r := &Runner{variableConfig: GetMaruVariableConfig()}
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		err := r.pause(ctx, types.Action{Pause: &types.ActionPause{Duration: "1h"}})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Less(t, time.Since(start), time.Minute)
+	})
+
+	t.Run("templates variables", func(t *testing.T) {
+		r := &Runner{variableConfig: GetMaruVariableConfig()}
+		r.variableConfig.SetVariable("SETTLE", "10ms", "", variables.ExtraVariableInfo{})
+		err := r.pause(context.Background(), types.Action{Pause: &types.ActionPause{Duration: "${SETTLE}"}})
+		require.NoError(t, err)
+	})
+
+	t.Run("dry run does not pause", func(t *testing.T) {
+		r := &Runner{variableConfig: GetMaruVariableConfig(), dryRun: true}
+		start := time.Now()
+		err := r.pause(context.Background(), types.Action{Pause: &types.ActionPause{Duration: "1h"}})
+		require.NoError(t, err)
+		require.Less(t, time.Since(start), time.Minute)
+	})
+}
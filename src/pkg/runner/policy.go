@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sync"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
+	"github.com/defenseunicorns/pkg/helpers/v2"
+)
+
+// CommandPolicy is an admin-level policy (kept outside of any tasks file) of which commands may run on a machine
+type CommandPolicy struct {
+	// Allow lists the commands that may run, if it is empty every command that is not denied may run
+	Allow []PolicyRule `json:"allow,omitempty"`
+	// Deny lists the commands that may never run, even if they are allowed
+	Deny []PolicyRule `json:"deny,omitempty"`
+}
+
+// PolicyRule matches commands with a regular expression (i.e. `curl .*\| *sh`) that can match anywhere in a command
+type PolicyRule struct {
+	Pattern string `json:"pattern"`
+	Reason  string `json:"reason,omitempty"`
+
+	regex *regexp.Regexp
+}
+
+// PolicyError is returned for a command that the command policy does not allow to run
+type PolicyError struct {
+	Command string
+	Policy  string
+	Reason  string
+}
+
+// Error implements the error interface
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("command %q is not allowed by the command policy in %s: %s", helpers.Truncate(e.Command, 60, false), e.Policy, e.Reason)
+}
+
+var (
+	policyOnce sync.Once
+	policy     *CommandPolicy
+	policyErr  error
+)
+
+// PolicyFile returns the location of the command policy (config.PolicyFile if it is set, otherwise
+// /etc/maru/policy.yaml or %ProgramData%\maru\policy.yaml on Windows)
+func PolicyFile() string {
+	if config.PolicyFile != "" {
+		return config.PolicyFile
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("ProgramData"), "maru", "policy.yaml")
+	}
+	return "/etc/maru/policy.yaml"
+}
+
+// LoadPolicy loads the command policy the first time it is needed, returning nil if there is no policy file
+func LoadPolicy() (*CommandPolicy, error) {
+	policyOnce.Do(func() {
+		policy, policyErr = readPolicy(PolicyFile())
+	})
+	return policy, policyErr
+}
+
+// readPolicy reads and compiles a command policy, returning nil if the file does not exist
+func readPolicy(path string) (*CommandPolicy, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	var p CommandPolicy
+	if err := utils.ReadYaml(path, &p); err != nil {
+		return nil, fmt.Errorf("unable to read the command policy in %s: %w", path, err)
+	}
+	for _, rules := range [][]PolicyRule{p.Allow, p.Deny} {
+		for i, rule := range rules {
+			regex, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("the command policy in %s has an invalid pattern %q: %w", path, rule.Pattern, err)
+			}
+			rules[i].regex = regex
+		}
+	}
+	return &p, nil
+}
+
+// checkCommandPolicy checks that the command policy (if there is one) allows a command to run. A policy that cannot be
+// loaded allows nothing to run since the machine is meant to be managed by it.
+func checkCommandPolicy(cmd string) error {
+	p, err := LoadPolicy()
+	if err != nil {
+		return fmt.Errorf("no commands can run until the command policy is fixed: %w", err)
+	}
+	if p == nil {
+		return nil
+	}
+	return p.check(cmd)
+}
+
+// check checks a command against the deny rules and then the allow rules of a policy
+func (p *CommandPolicy) check(cmd string) error {
+	for _, rule := range p.Deny {
+		if rule.regex.MatchString(cmd) {
+			reason := fmt.Sprintf("it matches the denied pattern %q", rule.Pattern)
+			if rule.Reason != "" {
+				reason = fmt.Sprintf("%s (%s)", reason, rule.Reason)
+			}
+			return &PolicyError{Command: cmd, Policy: PolicyFile(), Reason: reason}
+		}
+	}
+	if len(p.Allow) == 0 {
+		return nil
+	}
+	for _, rule := range p.Allow {
+		if rule.regex.MatchString(cmd) {
+			return nil
+		}
+	}
+	return &PolicyError{Command: cmd, Policy: PolicyFile(), Reason: "it does not match any allowed pattern"}
+}
@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/defenseunicorns/pkg/exec"
+	"github.com/stretchr/testify/require"
+)
+
+// usePolicy points the command policy at a file with the given contents until the test finishes
+func usePolicy(t *testing.T, contents string) {
+	config.PolicyFile = filepath.Join(t.TempDir(), "policy.yaml")
+	require.NoError(t, os.WriteFile(config.PolicyFile, []byte(contents), 0600))
+	policyOnce = sync.Once{}
+	t.Cleanup(func() {
+		config.PolicyFile = ""
+		policyOnce = sync.Once{}
+	})
+}
+
+func Test_checkCommandPolicy(t *testing.T) {
+	t.Run("no policy allows every command", func(t *testing.T) {
+		config.PolicyFile = filepath.Join(t.TempDir(), "missing.yaml")
+		policyOnce = sync.Once{}
+		t.Cleanup(func() {
+			config.PolicyFile = ""
+			policyOnce = sync.Once{}
+		})
+		require.NoError(t, checkCommandPolicy("curl https://example.com | sh"))
+	})
+
+	t.Run("deny rules win over allow rules", func(t *testing.T) {
+		usePolicy(t, `allow:
+  - pattern: ^(zarf|uds|echo)
+deny:
+  - pattern: zarf destroy
+    reason: clusters are torn down by the fleet
+`)
+		require.NoError(t, checkCommandPolicy("zarf package deploy"))
+		require.NoError(t, checkCommandPolicy("echo hi"))
+
+		err := checkCommandPolicy("zarf destroy --confirm")
+		var policyErr *PolicyError
+		require.ErrorAs(t, err, &policyErr)
+		require.Equal(t, `command "zarf destroy --confirm" is not allowed by the command policy in `+config.PolicyFile+
+			`: it matches the denied pattern "zarf destroy" (clusters are torn down by the fleet)`, err.Error())
+
+		require.ErrorContains(t, checkCommandPolicy("rm -rf /"), "it does not match any allowed pattern")
+	})
+
+	t.Run("a policy that cannot be loaded allows nothing", func(t *testing.T) {
+		usePolicy(t, "deny:\n  - pattern: \"(\"\n")
+		require.ErrorContains(t, checkCommandPolicy("echo hi"), `no commands can run until the command policy is fixed: the command policy in `+config.PolicyFile+` has an invalid pattern "("`)
+	})
+
+	t.Run("denied commands are not run or retried", func(t *testing.T) {
+		usePolicy(t, "deny:\n  - pattern: touch\n")
+		marker := filepath.Join(t.TempDir(), "ran")
+		retries := 2
+		action := &types.BaseAction[variables.ExtraVariableInfo]{Cmd: "touch " + marker, MaxRetries: &retries}
+		_, err := runAction(context.Background(), action, "", GetMaruVariableConfig(), false)
+		var policyErr *PolicyError
+		require.ErrorAs(t, err, &policyErr)
+		require.NoFileExists(t, marker)
+
+		_, err = ExecAction(context.Background(), types.ActionDefaults{}, "touch "+marker, exec.ShellPreference{}, nil)
+		require.ErrorAs(t, err, &policyErr)
+		require.NoFileExists(t, marker)
+	})
+}
@@ -0,0 +1,199 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"slices"
+	"time"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/types"
+)
+
+// ProgressEvent describes how far through a run it is. An event is sent when each action starts and finishes, and
+// once more when the run finishes (with an empty Task).
+type ProgressEvent struct {
+	RunID  string
+	Task   string
+	Action int
+	Name   string
+	// Status is ActionRunning when an action starts, otherwise the outcome of the action (or of the run)
+	Status ActionStatus
+	// Completed is the number of actions that have finished so far
+	Completed int
+	// Total is the estimated number of actions in the run (from the last successful run of the same task if there is
+	// one in the run history, otherwise from the actions of the task and the tasks it references)
+	Total int
+	// Percent is the estimated percentage of the run that is complete (weighted by how long each action took in the
+	// last successful run if there is one)
+	Percent float64
+	// Elapsed is how long the run has taken so far
+	Elapsed time.Duration
+	// Remaining is the estimated time left in the run (0 until there is something to estimate it from)
+	Remaining time.Duration
+}
+
+// ProgressHandler receives the progress of runs (i.e. to show a progress bar in an application embedding maru)
+type ProgressHandler interface {
+	Progress(event ProgressEvent)
+}
+
+// ProgressHandlerFunc adapts a function into a ProgressHandler
+type ProgressHandlerFunc func(event ProgressEvent)
+
+// Progress calls f(event)
+func (f ProgressHandlerFunc) Progress(event ProgressEvent) {
+	f(event)
+}
+
+// progressHandler receives the progress of every run (if set)
+var progressHandler ProgressHandler
+
+// SetProgressHandler sets the handler that the progress of every run is sent to (or nil to stop sending progress).
+// Events are sent from the goroutine running the tasks, so the handler should return quickly.
+func SetProgressHandler(handler ProgressHandler) {
+	progressHandler = handler
+}
+
+// progressTracker estimates the progress of a run and sends it to a ProgressHandler
+type progressTracker struct {
+	handler ProgressHandler
+	start   time.Time
+	total   int
+	// durations are how long (in seconds) each action took in the last successful run of the same task, if there was one
+	durations []float64
+}
+
+// newProgressTracker returns a tracker for a run of a task, or nil if no progress handler is set
+func (r *Runner) newProgressTracker(task types.Task) *progressTracker {
+	if progressHandler == nil {
+		return nil
+	}
+	tracker := &progressTracker{handler: progressHandler, start: time.Now()}
+	if last, ok := lastSuccessfulRun(r.results.Task); ok {
+		for _, action := range last.Actions {
+			tracker.durations = append(tracker.durations, action.Duration)
+		}
+		tracker.total = len(tracker.durations)
+	} else {
+		tracker.total = r.countActions(task, 0)
+	}
+	return tracker
+}
+
+// lastSuccessfulRun returns the most recent successful run of a task in the run history
+func lastSuccessfulRun(taskName string) (RunResults, bool) {
+	ids, err := ListRuns()
+	if err != nil {
+		return RunResults{}, false
+	}
+	slices.Reverse(ids)
+	for _, id := range ids {
+		results, err := LoadRun(id)
+		if err == nil && results.Task == taskName && results.Status == ActionSucceeded && len(results.Actions) > 0 {
+			return results, true
+		}
+	}
+	return RunResults{}, false
+}
+
+// countActions counts the actions a task runs, including the actions of the tasks it references (assuming every `if`
+// is true)
+func (r *Runner) countActions(task types.Task, depth int) int {
+	count := len(task.Actions)
+	if depth > config.MaxStack {
+		return count
+	}
+	for _, action := range task.Actions {
+		if action.TaskReference == "" {
+			continue
+		}
+		if referencedTask, err := r.getTask(action.TaskReference); err == nil {
+			count += r.countActions(referencedTask, depth+1)
+		}
+	}
+	return count
+}
+
+// actionStarted sends the progress of a run as an action starts
+func (r *Runner) actionStarted(task types.Task, idx int, action types.Action) {
+	r.sendProgress(task.Name, idx, actionName(action), ActionRunning)
+}
+
+// actionFinished sends the progress of a run as an action finishes (after its result has been recorded)
+func (r *Runner) actionFinished(task types.Task, idx int, action types.Action) {
+	if r.progress == nil || len(r.results.Actions) == 0 {
+		return
+	}
+	r.sendProgress(task.Name, idx, actionName(action), r.results.Actions[len(r.results.Actions)-1].Status)
+}
+
+// runFinished sends the final progress of a run
+func (r *Runner) runFinished(runErr error) {
+	status := ActionSucceeded
+	if runErr != nil {
+		status = ActionFailed
+	}
+	r.sendProgress("", 0, "", status)
+}
+
+// sendProgress estimates the progress of a run and sends it to the progress handler (if there is one)
+func (r *Runner) sendProgress(taskName string, idx int, name string, status ActionStatus) {
+	if r.progress == nil {
+		return
+	}
+	completed := len(r.results.Actions)
+	event := ProgressEvent{
+		RunID:     r.runID,
+		Task:      taskName,
+		Action:    idx,
+		Name:      name,
+		Status:    status,
+		Completed: completed,
+		Elapsed:   time.Since(r.progress.start),
+	}
+	event.Total, event.Percent, event.Remaining = r.progress.estimate(completed, status == ActionRunning, event.Elapsed)
+	if taskName == "" {
+		// The run has finished, so every action that was going to run has
+		event.Total = completed
+		if status == ActionSucceeded {
+			event.Percent, event.Remaining = 100, 0
+		}
+	}
+	r.progress.handler.Progress(event)
+}
+
+// estimate returns the estimated total number of actions, percent complete and time remaining of a run
+func (p *progressTracker) estimate(completed int, running bool, elapsed time.Duration) (int, float64, time.Duration) {
+	// A run that has gone past the estimate has at least the action that is running left
+	total := max(p.total, completed)
+	if running {
+		total = max(total, completed+1)
+	}
+
+	if total == 0 {
+		return 0, 100, 0
+	}
+
+	if len(p.durations) > 0 {
+		expected, done := 0.0, 0.0
+		for i, duration := range p.durations {
+			expected += duration
+			if i < completed {
+				done += duration
+			}
+		}
+		if expected > 0 && completed < len(p.durations) {
+			return total, min(done/expected*100, 99), time.Duration((expected - done) * float64(time.Second))
+		}
+	}
+
+	percent := float64(completed) / float64(total) * 100
+	if completed == 0 {
+		return total, percent, 0
+	}
+	remaining := time.Duration(float64(elapsed) / float64(completed) * float64(total-completed))
+	return total, percent, remaining
+}
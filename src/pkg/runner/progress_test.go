@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+)
+
+func Test_progressTracker_estimate(t *testing.T) {
+	t.Run("from the number of actions", func(t *testing.T) {
+		p := &progressTracker{total: 4}
+		total, percent, remaining := p.estimate(1, true, 10*time.Second)
+		require.Equal(t, 4, total)
+		require.Equal(t, 25.0, percent)
+		require.Equal(t, 30*time.Second, remaining)
+
+		// A run that goes past the estimate still has the running action left
+		total, percent, _ = p.estimate(4, true, 10*time.Second)
+		require.Equal(t, 5, total)
+		require.Equal(t, 80.0, percent)
+	})
+
+	t.Run("from the last successful run", func(t *testing.T) {
+		p := &progressTracker{total: 3, durations: []float64{1, 8, 1}}
+		total, percent, remaining := p.estimate(2, false, time.Second)
+		require.Equal(t, 3, total)
+		require.Equal(t, 90.0, percent)
+		require.Equal(t, time.Second, remaining)
+	})
+
+	t.Run("a run with nothing to do", func(t *testing.T) {
+		total, percent, _ := (&progressTracker{}).estimate(0, false, 0)
+		require.Equal(t, 0, total)
+		require.Equal(t, 100.0, percent)
+	})
+}
+
+func TestRunner_progress(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	keyring.MockInit()
+
+	events := []ProgressEvent{}
+	SetProgressHandler(ProgressHandlerFunc(func(event ProgressEvent) {
+		events = append(events, event)
+	}))
+	t.Cleanup(func() { SetProgressHandler(nil) })
+
+	echo := func(cmd string) types.Action {
+		return types.Action{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: cmd}}
+	}
+	tasks := []types.Task{
+		{Name: "default", Actions: []types.Action{echo("echo one"), {BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{}, TaskReference: "nested"}}},
+		{Name: "nested", Actions: []types.Action{echo("echo two"), echo("echo three")}},
+	}
+	r := &Runner{runID: "run", variableConfig: GetMaruVariableConfig(), tasksFile: types.TasksFile{Tasks: tasks}, results: RunResults{Task: "default"}}
+	r.progress = r.newProgressTracker(tasks[0])
+	require.Equal(t, 4, r.progress.total)
+
+	err := r.executeTask(context.Background(), tasks[0], nil)
+	require.NoError(t, err)
+	r.runFinished(err)
+
+	statuses := []ActionStatus{}
+	completed := []int{}
+	for _, event := range events {
+		statuses = append(statuses, event.Status)
+		completed = append(completed, event.Completed)
+		require.Equal(t, "run", event.RunID)
+		require.Equal(t, 4, event.Total)
+	}
+	require.Equal(t, []ActionStatus{
+		ActionRunning, ActionSucceeded, // echo one
+		ActionRunning,                  // task nested
+		ActionRunning, ActionSucceeded, // echo two
+		ActionRunning, ActionSucceeded, // echo three
+		ActionSucceeded, // task nested
+		ActionSucceeded, // the run
+	}, statuses)
+	require.Equal(t, []int{0, 1, 1, 1, 2, 2, 3, 4, 4}, completed)
+	require.Equal(t, "nested", events[3].Task)
+	require.Equal(t, "echo two", events[3].Name)
+	require.Equal(t, 50.0, events[4].Percent)
+	require.Equal(t, 100.0, events[8].Percent)
+	require.Empty(t, events[8].Task)
+
+	// The next run of the task estimates its progress from this one
+	r.results.Status = ActionSucceeded
+	require.NoError(t, recordRun(r.results, 5))
+	r.results.Task = "default"
+	tracker := r.newProgressTracker(tasks[0])
+	require.Len(t, tracker.durations, 4)
+}
@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
+	"github.com/defenseunicorns/pkg/helpers/v2"
+)
+
+// versionSegmentRegex matches a path segment of an include URL that is a version (i.e. v1.2.0 or 0.3.1-rc.1)
+var versionSegmentRegex = regexp.MustCompile(`^v?\d+(\.\d+)+([-+][0-9A-Za-z.-]+)?$`)
+
+// IncludeSource records where an included tasks file used by a run came from
+type IncludeSource struct {
+	Name    string `json:"name"`
+	Source  string `json:"source"`
+	Version string `json:"version,omitempty"`
+	Digest  string `json:"digest"`
+	Pinned  bool   `json:"pinned,omitempty"`
+}
+
+// recordInclude records the source, version and digest of an included tasks file (once per include)
+func (r *Runner) recordInclude(name, absLocation, location, digest string) {
+	for _, include := range r.results.Includes {
+		if include.Name == name && include.Source == absLocation {
+			return
+		}
+	}
+	_, pinnedDigest := utils.SplitIncludeDigest(location)
+	r.results.Includes = append(r.results.Includes, IncludeSource{
+		Name:    name,
+		Source:  absLocation,
+		Version: includeVersion(absLocation),
+		Digest:  digest,
+		Pinned:  pinnedDigest != "",
+	})
+}
+
+// printIncludes prints the source, version and digest of every included tasks file so it is clear what a run used
+func (r *Runner) printIncludes() {
+	for _, include := range r.results.Includes {
+		details := []string{}
+		if include.Version != "" {
+			details = append(details, "version "+include.Version)
+		}
+		if include.Pinned {
+			details = append(details, "pinned "+include.Digest)
+		} else {
+			details = append(details, include.Digest)
+		}
+		message.SLog.Info(fmt.Sprintf("Using include %s from %s (%s)", include.Name, include.Source, strings.Join(details, ", ")))
+	}
+}
+
+// includePrefix returns the prefix for the log lines of a task's actions: the namespace of the include the task came
+// from along with its source file (and version if it has one), or "" for the tasks of the root tasks file
+func (r *Runner) includePrefix(taskName string) string {
+	location, ok := r.taskFileLocations[taskName]
+	idx := strings.LastIndex(taskName, ":")
+	if !ok || idx < 0 {
+		return ""
+	}
+
+	// The full source of every include is printed before the run starts, so URLs are shortened to their file name
+	source := relativeLocation(location)
+	if u, err := url.Parse(location); err == nil && helpers.IsURL(location) {
+		source = path.Base(u.Path)
+	}
+	for _, include := range r.results.Includes {
+		if include.Source == location && include.Version != "" {
+			source += "@" + include.Version
+			break
+		}
+	}
+	return fmt.Sprintf("[%s: %s]", taskName[:idx], source)
+}
+
+// includeVersion returns the version of a remote include from its URL: the ref query parameter (as used by GitLab),
+// the tag of a refs/tags/<tag> or releases/download/<tag> path, or the first path segment that looks like a version
+func includeVersion(location string) string {
+	if !helpers.IsURL(location) {
+		return ""
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return ""
+	}
+	if ref := u.Query().Get("ref"); ref != "" {
+		return ref
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i+2 < len(segments); i++ {
+		if (segments[i] == "refs" && segments[i+1] == "tags") || (segments[i] == "releases" && segments[i+1] == "download") {
+			return segments[i+2]
+		}
+	}
+	for _, segment := range segments {
+		if versionSegmentRegex.MatchString(segment) {
+			return segment
+		}
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_includeVersion(t *testing.T) {
+	tests := []struct {
+		location string
+		want     string
+	}{
+		{location: "./tasks/build.yaml", want: ""},
+		{location: "https://raw.githubusercontent.com/defenseunicorns/uds-common/v0.13.1/tasks/lint.yaml", want: "v0.13.1"},
+		{location: "https://raw.githubusercontent.com/defenseunicorns/uds-common/refs/tags/release-2024/tasks/lint.yaml", want: "release-2024"},
+		{location: "https://github.com/defenseunicorns/tasks/releases/download/v1.2.0/tasks.yaml", want: "v1.2.0"},
+		{location: "https://gitlab.com/api/v4/projects/1/repository/files/tasks.yaml/raw?ref=1.4.0-rc.1", want: "1.4.0-rc.1"},
+		{location: "https://example.com/tasks/main/tasks.yaml", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			require.Equal(t, tt.want, includeVersion(tt.location))
+		})
+	}
+}
+
+func TestRunner_recordInclude(t *testing.T) {
+	dir := t.TempDir()
+	contents := []byte("tasks:\n  - name: build\n    actions:\n      - cmd: echo build\n")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "build.yaml"), contents, 0600))
+
+	r := &Runner{
+		existingTaskIncludeNameLocation: map[string]string{},
+		variableConfig:                  GetMaruVariableConfig(),
+	}
+	includes := []map[string]string{{"build": "./build.yaml"}}
+	require.NoError(t, r.importTasks(includes, filepath.Join(dir, "tasks.yaml"), nil))
+	// Importing the same include again does not record it twice
+	require.NoError(t, r.importTasks(includes, filepath.Join(dir, "tasks.yaml"), nil))
+
+	require.Equal(t, []IncludeSource{{
+		Name:   "build",
+		Source: filepath.Join(dir, "build.yaml"),
+		Digest: utils.Digest(contents),
+	}}, r.results.Includes)
+}
+
+func TestRunner_includePrefix(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	remote := "https://raw.githubusercontent.com/defenseunicorns/uds-common/v0.13.1/tasks/lint.yaml"
+	r := &Runner{
+		taskFileLocations: map[string]string{
+			"build:compile":    filepath.Join(wd, "tasks", "build.yaml"),
+			"lint:yaml":        remote,
+			"lint:shell:check": "https://example.com/tasks/shell.yaml",
+		},
+		results: RunResults{Includes: []IncludeSource{{Name: "lint", Source: remote, Version: "v0.13.1"}}},
+	}
+
+	require.Equal(t, "", r.includePrefix("default"))
+	require.Equal(t, "[build: "+filepath.Join("tasks", "build.yaml")+"]", r.includePrefix("build:compile"))
+	require.Equal(t, "[lint: lint.yaml@v0.13.1]", r.includePrefix("lint:yaml"))
+	require.Equal(t, "[lint:shell: shell.yaml]", r.includePrefix("lint:shell:check"))
+}
@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/defenseunicorns/pkg/helpers/v2"
+)
+
+// ActionStatus is the outcome of an action in a run
+type ActionStatus string
+
+const (
+	// ActionSucceeded means the action ran successfully
+	ActionSucceeded ActionStatus = "succeeded"
+	// ActionFailed means the action ran and failed
+	ActionFailed ActionStatus = "failed"
+	// ActionSkipped means the action did not run because its `if` condition was false
+	ActionSkipped ActionStatus = "skipped"
+	// ActionRunning means the action has started (only used in progress events)
+	ActionRunning ActionStatus = "running"
+)
+
+// ActionResult records the outcome of a single action in a run
+type ActionResult struct {
+	Task      string       `json:"task"`
+	Action    int          `json:"action"`
+	Name      string       `json:"name"`
+	Status    ActionStatus `json:"status"`
+	Reason    string       `json:"reason,omitempty"`
+	Cause     FailureCause `json:"cause,omitempty"`
+	Hint      string       `json:"hint,omitempty"`
+	Duration  float64      `json:"durationSeconds"`
+	Output    string       `json:"output,omitempty"`
+	Artifacts []string     `json:"artifacts,omitempty"`
+}
+
+// maxRecordedOutput is the number of bytes at the end of an action's output that are kept in its result
+const maxRecordedOutput = 4096
+
+// sensitiveValue replaces the values of sensitive variables in the run results
+const sensitiveValue = message.SanitizedValue
+
+// RunResults records the outcome of every action that was reached in a run
+type RunResults struct {
+	RunID     string            `json:"runId"`
+	Task      string            `json:"task"`
+	Status    ActionStatus      `json:"status"`
+	Actions   []ActionResult    `json:"actions"`
+	Variables map[string]string `json:"variables,omitempty"`
+	Includes  []IncludeSource   `json:"includes,omitempty"`
+}
+
+// actionName returns a short human readable name for an action
+func actionName(action types.Action) string {
+	switch {
+	case action.BaseAction != nil && action.Description != "":
+		return action.Description
+	case action.TaskReference != "":
+		return fmt.Sprintf("task %s", action.TaskReference)
+	case action.Patch != nil:
+		return fmt.Sprintf("patch %s", action.Patch.File)
+	case action.Tunnel != nil:
+		return fmt.Sprintf("tunnel via %s", action.Tunnel.Via)
+	case action.Pause != nil && action.Pause.Reason != "":
+		return fmt.Sprintf("pause (%s)", action.Pause.Reason)
+	case action.Pause != nil:
+		return "pause"
+	case action.BaseAction != nil && action.Wait != nil:
+		return "wait"
+	case action.BaseAction != nil:
+		return helpers.Truncate(action.Cmd, 60, false)
+	default:
+		return ""
+	}
+}
+
+// recordAction records the outcome of an action
+func (r *Runner) recordAction(task types.Task, idx int, action types.Action, skipped bool, output string, duration time.Duration, err error) {
+	if len(output) > maxRecordedOutput {
+		output = strings.ToValidUTF8(output[len(output)-maxRecordedOutput:], "")
+	}
+	result := ActionResult{
+		Task:     task.Name,
+		Action:   idx,
+		Name:     message.Mask(actionName(action)),
+		Status:   ActionSucceeded,
+		Duration: duration.Seconds(),
+		Output:   message.Mask(output),
+	}
+	switch {
+	case err != nil:
+		result.Status = ActionFailed
+		result.Reason = message.Mask(err.Error())
+		if cause := failureCauseOf(err); cause != "" {
+			result.Cause, result.Hint = cause, cause.Hint()
+		}
+	case skipped:
+		result.Status = ActionSkipped
+		result.Reason = "if condition evaluated to false"
+	}
+	r.results.Actions = append(r.results.Actions, result)
+}
+
+// counts returns the number of actions that succeeded, were skipped and failed
+func (rr RunResults) counts() (succeeded int, skipped int, failed int) {
+	for _, action := range rr.Actions {
+		switch action.Status {
+		case ActionSucceeded:
+			succeeded++
+		case ActionSkipped:
+			skipped++
+		case ActionFailed:
+			failed++
+		}
+	}
+	return succeeded, skipped, failed
+}
+
+// finishResults sets the overall status of the run, prints a summary, records the run in the run history and writes the
+// results file (if configured)
+func (r *Runner) finishResults(runErr error, resultsFile string) error {
+	r.results.Status = ActionSucceeded
+	if runErr != nil {
+		r.results.Status = ActionFailed
+	}
+
+	if r.variableConfig != nil {
+		r.results.Variables = map[string]string{}
+		for name, variable := range r.variableConfig.GetSetVariables() {
+			if variable.Extra.Sensitive {
+				r.results.Variables[name] = sensitiveValue
+				continue
+			}
+			r.results.Variables[name] = variable.Value
+		}
+	}
+
+	succeeded, skipped, failed := r.results.counts()
+	if !r.dryRun && len(r.results.Actions) > 0 {
+		message.SLog.Info(fmt.Sprintf("Ran %d actions: %d succeeded, %d skipped, %d failed",
+			len(r.results.Actions), succeeded, skipped, failed))
+	}
+
+	if !r.dryRun && len(r.results.Actions) > 0 && config.RunHistory > 0 {
+		if err := recordRun(r.results, config.RunHistory); err != nil {
+			message.SLog.Warn(fmt.Sprintf("Unable to record run %s in the run history: %s", r.results.RunID, err.Error()))
+		}
+	}
+
+	if resultsFile == "" {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(r.results, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(resultsFile, b, helpers.ReadWriteUser)
+}
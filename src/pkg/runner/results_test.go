@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_results(t *testing.T) {
+	task := types.Task{
+		Name: "build",
+		Actions: []types.Action{
+			{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: "echo one"}},
+			{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: "echo two"}, If: "false"},
+			{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: "exit 1", Description: "broken"}},
+			{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: "echo never"}},
+		},
+	}
+	r := &Runner{
+		runID:          "test-run",
+		variableConfig: GetMaruVariableConfig(),
+		results:        RunResults{RunID: "test-run", Task: task.Name},
+	}
+	r.variableConfig.SetVariable("DOMAIN", "uds.dev", "", variables.ExtraVariableInfo{})
+	r.variableConfig.SetVariable("DB_PASSWORD", "secret", "", variables.ExtraVariableInfo{Sensitive: true})
+
+	err := r.executeTask(context.TODO(), task, nil)
+	require.Error(t, err)
+
+	resultsFile := filepath.Join(t.TempDir(), "results.json")
+	require.NoError(t, r.finishResults(err, resultsFile))
+
+	b, err := os.ReadFile(resultsFile)
+	require.NoError(t, err)
+	var results RunResults
+	require.NoError(t, json.Unmarshal(b, &results))
+
+	require.Equal(t, "test-run", results.RunID)
+	require.Equal(t, ActionFailed, results.Status)
+	require.Len(t, results.Actions, 3)
+	require.Equal(t, ActionSucceeded, results.Actions[0].Status)
+	require.Equal(t, "echo one", results.Actions[0].Name)
+	require.Equal(t, "one", results.Actions[0].Output)
+	require.Equal(t, ActionSkipped, results.Actions[1].Status)
+	require.Equal(t, "if condition evaluated to false", results.Actions[1].Reason)
+	require.Equal(t, ActionFailed, results.Actions[2].Status)
+	require.Equal(t, "broken", results.Actions[2].Name)
+	require.Equal(t, `command "broken" failed after 0 retries`, results.Actions[2].Reason)
+	require.Equal(t, "uds.dev", results.Variables["DOMAIN"])
+	require.Equal(t, "**sanitized**", results.Variables["DB_PASSWORD"])
+}
+
+func TestRunner_results_sensitiveFiles(t *testing.T) {
+	t.Cleanup(message.ClearSensitive)
+	secret := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(secret, []byte("hunter2-password\n"), 0600))
+	task := types.Task{
+		Name: "login",
+		Actions: []types.Action{
+			{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: `echo "using ${{ fileContents "` + secret + `" }}"`}},
+			{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: `echo ${{ fileContents "` + secret + `.missing" }}`}},
+		},
+	}
+	r := &Runner{
+		variableConfig: GetMaruVariableConfig(),
+		results:        RunResults{Task: task.Name},
+	}
+
+	// The contents of the file are masked in the output, and a file that can't be read fails the action
+	err := r.executeTask(context.TODO(), task, nil)
+	require.ErrorContains(t, err, "secret.missing: no such file or directory")
+	require.Len(t, r.results.Actions, 2)
+	require.Equal(t, "using **sanitized**", r.results.Actions[0].Output)
+	require.Equal(t, ActionFailed, r.results.Actions[1].Status)
+}
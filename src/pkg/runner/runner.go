@@ -5,10 +5,18 @@
 package runner
 
 import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/url"
+	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/defenseunicorns/maru-runner/src/config"
 	"github.com/defenseunicorns/maru-runner/src/message"
@@ -18,35 +26,95 @@ import (
 	"github.com/defenseunicorns/pkg/helpers/v2"
 )
 
+const (
+	// RunIDEnv is the environment variable holding the unique ID of the current run
+	RunIDEnv = "MARU_RUN_ID"
+	// TaskNameEnv is the environment variable holding the name of the task the current action belongs to
+	TaskNameEnv = "MARU_TASK_NAME"
+	// ActionIndexEnv is the environment variable holding the (zero-based) index of the current action within its task
+	ActionIndexEnv = "MARU_ACTION_INDEX"
+	// AttemptEnv is the environment variable holding the (one-based) attempt number of the current action
+	AttemptEnv = "MARU_ATTEMPT"
+	// MaxAttemptsEnv is the environment variable holding the total number of attempts the current action will be given
+	MaxAttemptsEnv = "MARU_MAX_ATTEMPTS"
+	// IdempotencyKeyEnv is the environment variable holding a key that is shared by every attempt of the current action
+	IdempotencyKeyEnv = "MARU_IDEMPOTENCY_KEY"
+	// OutputFileEnv is the environment variable holding the path of a file the current task's actions can write NAME=value
+	// lines to, which set variables once the task finishes
+	OutputFileEnv = "MARU_OUTPUT"
+	// GeneratedTasksEnv is the environment variable holding the path of a file the current action can write tasks to,
+	// which are run as soon as the action finishes
+	GeneratedTasksEnv = "MARU_TASKS"
+)
+
 // Runner holds the necessary data to run tasks from a tasks file
 type Runner struct {
+	runID                           string
 	tasksFile                       types.TasksFile
 	existingTaskIncludeNameLocation map[string]string
+	includeDeclaringLocations       map[string]string
+	taskFileLocations               map[string]string
+	taskTemplateDelims              map[string]*types.TemplateDelims
+	embeddedTasks                   map[string]bool
+	includedTasksFiles              map[string]types.TasksFile
+	sessionVariables                map[string]bool
 	auth                            map[string]string
 	envFilePath                     string
 	variableConfig                  *variables.VariableConfig[variables.ExtraVariableInfo]
 	dryRun                          bool
 	currStackSize                   int
+	tunnels                         [][]*tunnel
+	ownerReported                   bool
+	actionOccurrences               map[string]int
+	usesNamespaces                  map[string]string
+	stepping                        bool
+	stepReader                      *bufio.Reader
+	progress                        *progressTracker
+	trace                           *tracer
+	watchedFiles                    map[string]*watchedFile
+	results                         RunResults
 }
 
 // Run runs a task from tasks file
 func Run(tasksFile types.TasksFile, taskName string, setVariables map[string]string, dryRun bool, auth map[string]string) error {
+	return RunWithContext(context.Background(), tasksFile, taskName, setVariables, dryRun, auth)
+}
+
+// RunWithContext runs a task from tasks file like Run, stopping early if the given context is cancelled or its deadline
+// is exceeded
+func RunWithContext(ctx context.Context, tasksFile types.TasksFile, taskName string, setVariables map[string]string, dryRun bool, auth map[string]string) error {
 	if dryRun {
 		message.SLog.Info("Dry-run has been set - only printing the commands that would run:")
 	}
 
+	// Tasks with bad names are reported along with any other problems found before the run starts
+	nameErrs := splitErrors(validateTaskNames(config.TaskFileLocation, tasksFile))
+
+	if err := validateOnChange(config.OnChange); err != nil {
+		return err
+	}
+
+	// Fill in any variables remembered by the session
+	if config.Session != "" {
+		session, err := LoadSession(config.Session)
+		if err != nil {
+			return errors.Join(append(nameErrs, err)...)
+		}
+		setVariables = withSession(setVariables, session)
+	}
+
 	// Populate the variables loaded in the root task file
 	rootVariables := tasksFile.Variables
 	rootVariableConfig := GetMaruVariableConfig()
 	err := rootVariableConfig.PopulateVariables(rootVariables, setVariables)
 	if err != nil {
-		return err
+		return errors.Join(append(nameErrs, err)...)
 	}
 
 	// Check to see if running an included task directly
 	tasksFile, taskName, err = loadIncludedTaskFile(tasksFile, taskName, rootVariableConfig.GetSetVariables(), auth)
 	if err != nil {
-		return err
+		return errors.Join(append(nameErrs, err)...)
 	}
 
 	// Populate the variables from the root and included file (if these are the same it will just use the same list)
@@ -56,36 +124,97 @@ func Run(tasksFile types.TasksFile, taskName string, setVariables map[string]str
 	combinedVariableConfig := GetMaruVariableConfig()
 	err = combinedVariableConfig.PopulateVariables(combinedVariables, setVariables)
 	if err != nil {
-		return err
+		return errors.Join(append(nameErrs, err)...)
+	}
+
+	// Read any variables that come from the cluster (variables that were set take precedence)
+	if err := resolveK8sVariables(ctx, combinedVariables, combinedVariableConfig, setVariables, dryRun); err != nil {
+		return errors.Join(append(nameErrs, err)...)
 	}
 
 	// Create the runner client to execute the task file
+	runID := newRunID()
 	runner := Runner{
+		runID:                           runID,
 		tasksFile:                       tasksFile,
 		existingTaskIncludeNameLocation: map[string]string{},
+		taskFileLocations:               map[string]string{},
 		auth:                            auth,
 		variableConfig:                  combinedVariableConfig,
 		dryRun:                          dryRun,
+		stepping:                        config.Step,
+		results:                         RunResults{RunID: runID, Task: taskName},
+	}
+
+	for _, v := range combinedVariables {
+		runner.markSessionVariables(v.Variable)
 	}
 
+	runner.embeddedTasks = embeddedTaskNames(tasksFile)
+
 	task, err := runner.getTask(taskName)
 	if err != nil {
-		return err
+		return errors.Join(append(nameErrs, err)...)
 	}
 
-	// Check that this task is a valid task we can call (i.e. has defaults for any inputs since those cannot be set on the CLI)
-	if err := validateActionableTaskCall(task.Name, task.Inputs, nil); err != nil {
-		return err
-	}
+	// Catch every problem before running anything (rather than stopping at the first) so they can all be fixed at once,
+	// starting with whether this task can be called (i.e. has defaults for any inputs since those cannot be set on the CLI)
+	errs := append(nameErrs, splitErrors(validateActionableTaskCall(task.Name, task.Inputs, nil))...)
 
+	// References into includes that cannot be imported are not checked since they would only repeat that the include
+	// is broken
 	if err = runner.processTaskReferences(task, runner.tasksFile, setVariables); err != nil {
-		return err
+		return errors.Join(uniqueErrors(append(errs, splitErrors(err)...))...)
+	}
+
+	// Import the files of any uses references (the task is fetched again to pick up its resolved references)
+	if usesErrs := runner.resolveUses([]string{task.Name}, setVariables); len(usesErrs) > 0 {
+		return errors.Join(append(errs, usesErrs...)...)
+	}
+	if task, err = runner.getTask(taskName); err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+
+	errs = append(errs, runner.validateTasks([]types.Task{task}, false)...)
+	if len(errs) > 0 {
+		return errors.Join(errs...)
 	}
 
-	err = runner.executeTask(task, nil)
+	runner.printIncludes()
+	runner.warnDeprecatedSyntax()
+	runner.watchTasksFiles()
+
+	runner.progress = runner.newProgressTracker(task)
+	if config.TraceFile != "" {
+		runner.trace = newTracer(runID, taskName)
+	}
+	err = runner.executeTask(ctx, task, nil)
+	runner.runFinished(err)
+	if config.Session != "" && !dryRun {
+		if sessionErr := runner.saveSession(config.Session); sessionErr != nil {
+			message.SLog.Warn(fmt.Sprintf("Unable to save session %s: %s", config.Session, sessionErr.Error()))
+		}
+	}
+	// The trace is written even when the results file can't be, since it is what shows where the run went wrong
+	resultsErr := runner.finishResults(err, config.ResultsFile)
+	if runner.trace != nil {
+		if traceErr := runner.trace.write(config.TraceFile, runner.results); traceErr != nil {
+			resultsErr = errors.Join(resultsErr, fmt.Errorf("unable to write the trace file: %w", traceErr))
+		}
+	}
+	if resultsErr != nil {
+		return errors.Join(err, resultsErr)
+	}
 	return err
 }
 
+// newRunID returns a unique, time-sortable ID for a run
+func newRunID() string {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return fmt.Sprintf("%s-%s", time.Now().UTC().Format("20060102T150405Z"), hex.EncodeToString(suffix))
+}
+
 // GetMaruVariableConfig gets the variable configuration for Maru
 func GetMaruVariableConfig() *variables.VariableConfig[variables.ExtraVariableInfo] {
 	prompt := func(_ variables.InteractiveVariable[variables.ExtraVariableInfo]) (value string, err error) {
@@ -95,8 +224,7 @@ func GetMaruVariableConfig() *variables.VariableConfig[variables.ExtraVariableIn
 }
 
 func (r *Runner) processIncludes(tasksFile types.TasksFile, setVariables map[string]string, action types.Action) error {
-	if strings.Contains(action.TaskReference, ":") {
-		taskReferenceName := strings.Split(action.TaskReference, ":")[0]
+	if taskReferenceName, _ := splitTaskReference(action.TaskReference); taskReferenceName != "" {
 		for _, include := range tasksFile.Includes {
 			if include[taskReferenceName] != "" {
 				referencedIncludes := []map[string]string{include}
@@ -111,74 +239,103 @@ func (r *Runner) processIncludes(tasksFile types.TasksFile, setVariables map[str
 	return nil
 }
 
+// importTasks imports the tasks of the given includes (and of the includes they include), carrying on past any that
+// cannot be imported so every broken include is reported (joined) at once
 func (r *Runner) importTasks(includes []map[string]string, currentFileLocation string, setVariables map[string]string) error {
-	// iterate through includes, open the file, and unmarshal it into a Task
-	var includeKey string
-	var includeLocation string
+	var errs []error
 	for _, include := range includes {
-		if len(include) > 1 {
-			return fmt.Errorf("included item %s must have only one key", include)
-		}
-		// grab first and only value from include map
-		for k, v := range include {
-			includeKey = k
-			includeLocation = v
-			break
+		if err := r.importInclude(include, currentFileLocation, setVariables); err != nil {
+			errs = append(errs, splitErrors(err)...)
 		}
+	}
+	return errors.Join(errs...)
+}
 
-		includeLocation = utils.TemplateString(r.variableConfig.GetSetVariables(), includeLocation)
+// importInclude opens an included file, unmarshals its tasks and imports them (along with the files it includes)
+func (r *Runner) importInclude(include map[string]string, currentFileLocation string, setVariables map[string]string) error {
+	var includeKey string
+	var includeLocation string
+	if len(include) > 1 {
+		return fmt.Errorf("included item %s must have only one key", include)
+	}
+	// grab first and only value from include map
+	for k, v := range include {
+		includeKey = k
+		includeLocation = v
+		break
+	}
 
-		absIncludeFileLocation, tasksFile, err := LoadIncludeTask(currentFileLocation, includeLocation, r.auth)
-		if err != nil {
-			return fmt.Errorf("unable to read included file: %w", err)
+	includeLocation = utils.TemplateString(r.variableConfig.GetSetVariables(), includeLocation)
+
+	absIncludeFileLocation, tasksFile, digest, err := loadIncludeTask(currentFileLocation, includeLocation, r.auth)
+	if err != nil {
+		return fmt.Errorf("unable to read included file: %w", err)
+	}
+	r.recordInclude(includeKey, absIncludeFileLocation, includeLocation, digest)
+	// If we arrive here we assume this was a new include due to the later check
+	r.existingTaskIncludeNameLocation[includeKey] = absIncludeFileLocation
+	if r.includeDeclaringLocations == nil {
+		r.includeDeclaringLocations = map[string]string{}
+	}
+	r.includeDeclaringLocations[includeKey] = currentFileLocation
+	if r.includedTasksFiles != nil {
+		r.includedTasksFiles[absIncludeFileLocation] = tasksFile
+	}
+	if r.taskFileLocations == nil {
+		r.taskFileLocations = map[string]string{}
+	}
+
+	// prefix task names and actions with the includes key
+	for i, t := range tasksFile.Tasks {
+		tasksFile.Tasks[i].Name = joinTaskReference(includeKey, t.Name)
+		r.taskFileLocations[tasksFile.Tasks[i].Name] = absIncludeFileLocation
+		if tasksFile.TemplateDelims != nil {
+			if r.taskTemplateDelims == nil {
+				r.taskTemplateDelims = map[string]*types.TemplateDelims{}
+			}
+			r.taskTemplateDelims[tasksFile.Tasks[i].Name] = tasksFile.TemplateDelims
 		}
-		// If we arrive here we assume this was a new include due to the later check
-		r.existingTaskIncludeNameLocation[includeKey] = absIncludeFileLocation
-
-		// prefix task names and actions with the includes key
-		for i, t := range tasksFile.Tasks {
-			tasksFile.Tasks[i].Name = includeKey + ":" + t.Name
-			if len(tasksFile.Tasks[i].Actions) > 0 {
-				for j, a := range tasksFile.Tasks[i].Actions {
-					if a.TaskReference != "" && !strings.Contains(a.TaskReference, ":") {
-						tasksFile.Tasks[i].Actions[j].TaskReference = includeKey + ":" + a.TaskReference
-					}
+		if len(tasksFile.Tasks[i].Actions) > 0 {
+			for j, a := range tasksFile.Tasks[i].Actions {
+				if include, _ := splitTaskReference(a.TaskReference); a.TaskReference != "" && include == "" {
+					tasksFile.Tasks[i].Actions[j].TaskReference = joinTaskReference(includeKey, a.TaskReference)
 				}
 			}
 		}
+	}
 
-		r.tasksFile.Tasks = append(r.tasksFile.Tasks, tasksFile.Tasks...)
+	r.tasksFile.Tasks = append(r.tasksFile.Tasks, tasksFile.Tasks...)
 
-		r.mergeVariablesFromIncludedTask(tasksFile)
+	r.mergeVariablesFromIncludedTask(tasksFile)
 
-		// recursively import tasks from included files
-		if tasksFile.Includes != nil {
-			newIncludes := []map[string]string{}
-			var newIncludeKey string
-			var newIncludeLocation string
-			for _, newInclude := range tasksFile.Includes {
-				for k, v := range newInclude {
-					newIncludeKey = k
-					newIncludeLocation = v
-					break
-				}
-				if existingLocation, exists := r.existingTaskIncludeNameLocation[newIncludeKey]; !exists {
-					newIncludes = append(newIncludes, map[string]string{newIncludeKey: newIncludeLocation})
-				} else {
-					newIncludeLocation = utils.TemplateString(r.variableConfig.GetSetVariables(), newIncludeLocation)
-					newAbsIncludeFileLocation, err := includeTaskAbsLocation(absIncludeFileLocation, newIncludeLocation)
-					if err != nil {
-						return err
-					}
-					if existingLocation != newAbsIncludeFileLocation {
-						return fmt.Errorf("task include %q attempted to be redefined from %q to %q", newIncludeKey, existingLocation, newAbsIncludeFileLocation)
-					}
-				}
+	// recursively import tasks from included files
+	if tasksFile.Includes != nil {
+		var errs []error
+		newIncludes := []map[string]string{}
+		var newIncludeKey string
+		var newIncludeLocation string
+		for _, newInclude := range tasksFile.Includes {
+			for k, v := range newInclude {
+				newIncludeKey = k
+				newIncludeLocation = v
+				break
 			}
-			if err := r.importTasks(newIncludes, absIncludeFileLocation, setVariables); err != nil {
-				return err
+			if existingLocation, exists := r.existingTaskIncludeNameLocation[newIncludeKey]; !exists {
+				newIncludes = append(newIncludes, map[string]string{newIncludeKey: newIncludeLocation})
+			} else {
+				newIncludeLocation = utils.TemplateString(r.variableConfig.GetSetVariables(), newIncludeLocation)
+				newAbsIncludeFileLocation, err := includeTaskAbsLocation(absIncludeFileLocation, newIncludeLocation)
+				if err != nil {
+					errs = append(errs, err)
+				} else if existingLocation != newAbsIncludeFileLocation {
+					errs = append(errs, fmt.Errorf("task include %q attempted to be redefined from %q to %q", newIncludeKey, existingLocation, newAbsIncludeFileLocation))
+				}
 			}
 		}
+		if err := r.importTasks(newIncludes, absIncludeFileLocation, setVariables); err != nil {
+			errs = append(errs, splitErrors(err)...)
+		}
+		return errors.Join(errs...)
 	}
 	return nil
 }
@@ -186,6 +343,7 @@ func (r *Runner) importTasks(includes []map[string]string, currentFileLocation s
 func (r *Runner) mergeVariablesFromIncludedTask(tasksFile types.TasksFile) {
 	// grab variables from included file
 	for _, v := range tasksFile.Variables {
+		r.markSessionVariables(v.Variable)
 		if _, ok := r.variableConfig.GetSetVariable(v.Name); !ok {
 			r.variableConfig.SetVariable(v.Name, v.Default, v.Pattern, v.Extra)
 		}
@@ -194,10 +352,11 @@ func (r *Runner) mergeVariablesFromIncludedTask(tasksFile types.TasksFile) {
 
 func loadIncludedTaskFile(taskFile types.TasksFile, taskName string, setVariables variables.SetVariableMap[variables.ExtraVariableInfo], auth map[string]string) (types.TasksFile, string, error) {
 	// Check if running task directly from included task file
-	includedTask := strings.Split(taskName, ":")
-	if len(includedTask) == 2 {
-		includeName := includedTask[0]
-		includeTaskName := includedTask[1]
+	includeName, includeTaskName := splitTaskReference(taskName)
+	if strings.Contains(includeTaskName, ":") {
+		return taskFile, taskName, fmt.Errorf("invalid task name: %s", taskName)
+	}
+	if includeName != "" {
 		// Get referenced include file
 		for _, includes := range taskFile.Includes {
 			if includeFileLocation, ok := includes[includeName]; ok {
@@ -208,8 +367,6 @@ func loadIncludedTaskFile(taskFile types.TasksFile, taskName string, setVariable
 				return includedTasksFile, includeTaskName, err
 			}
 		}
-	} else if len(includedTask) > 2 {
-		return taskFile, taskName, fmt.Errorf("invalid task name: %s", taskName)
 	}
 	return taskFile, taskName, nil
 }
@@ -217,6 +374,9 @@ func loadIncludedTaskFile(taskFile types.TasksFile, taskName string, setVariable
 func includeTaskAbsLocation(currentFileLocation, includeFileLocation string) (string, error) {
 	var absIncludeFileLocation string
 
+	// Pinned digests are not part of the location itself
+	includeFileLocation, _ = utils.SplitIncludeDigest(includeFileLocation)
+
 	if !helpers.IsURL(includeFileLocation) {
 		if helpers.IsURL(currentFileLocation) {
 			currentURL, err := url.Parse(currentFileLocation)
@@ -241,22 +401,34 @@ func includeTaskAbsLocation(currentFileLocation, includeFileLocation string) (st
 
 // LoadIncludeTask loads an included task file either from a remote or local file
 func LoadIncludeTask(currentFileLocation, includeFileLocation string, auth map[string]string) (string, types.TasksFile, error) {
+	absIncludeFileLocation, includedTasksFile, _, err := loadIncludeTask(currentFileLocation, includeFileLocation, auth)
+	return absIncludeFileLocation, includedTasksFile, err
+}
+
+// loadIncludeTask loads an included task file in the same way as LoadIncludeTask, also returning the digest of its contents
+func loadIncludeTask(currentFileLocation, includeFileLocation string, auth map[string]string) (string, types.TasksFile, string, error) {
 	var includedTasksFile types.TasksFile
 
+	_, pinnedDigest := utils.SplitIncludeDigest(includeFileLocation)
+
 	absIncludeFileLocation, err := includeTaskAbsLocation(currentFileLocation, includeFileLocation)
 	if err != nil {
-		return absIncludeFileLocation, includedTasksFile, err
+		return absIncludeFileLocation, includedTasksFile, "", err
 	}
 
 	// If the file is in fact a URL we need to download and load the YAML
+	var digest string
 	if helpers.IsURL(absIncludeFileLocation) {
-		err = utils.ReadRemoteYaml(absIncludeFileLocation, &includedTasksFile, auth)
+		digest, err = utils.ReadRemoteYamlDigest(absIncludeFileLocation, pinnedDigest, &includedTasksFile, auth)
 	} else {
 		// Set TasksFile to the local included task file
-		err = utils.ReadYaml(absIncludeFileLocation, &includedTasksFile)
+		digest, err = utils.ReadYamlDigest(absIncludeFileLocation, &includedTasksFile)
+	}
+	if err == nil {
+		err = validateTaskNames(absIncludeFileLocation, includedTasksFile)
 	}
 
-	return absIncludeFileLocation, includedTasksFile, err
+	return absIncludeFileLocation, includedTasksFile, digest, err
 }
 
 func (r *Runner) getTask(taskName string) (types.Task, error) {
@@ -265,19 +437,72 @@ func (r *Runner) getTask(taskName string) (types.Task, error) {
 			return task, nil
 		}
 	}
-	return types.Task{}, fmt.Errorf("task name %s not found", taskName)
+	return types.Task{}, &TaskNotFoundError{Name: taskName}
+}
+
+// templateDelims returns the template delimiters of the file that a task was defined in
+func (r *Runner) templateDelims(taskName string) *types.TemplateDelims {
+	if delims, ok := r.taskTemplateDelims[taskName]; ok {
+		return delims
+	}
+	if _, included := r.taskFileLocations[taskName]; included {
+		return nil
+	}
+	// Embedded tasks keep the default delimiters whatever the tasks file they were merged into uses
+	if r.embeddedTasks[taskName] {
+		return nil
+	}
+	return r.tasksFile.TemplateDelims
+}
+
+// templateOptions returns how an action of a task is templated: with the delimiters of the file the task is from and
+// with files read relative to the action's dir (or else the directory of the file the task is from)
+func (r *Runner) templateOptions(taskName string, action types.Action) utils.TemplateOptions {
+	opts := utils.TemplateOptions{Delims: r.templateDelims(taskName)}
+	if action.BaseAction != nil && action.Dir != nil && *action.Dir != "" {
+		opts.Dir = utils.TemplateString(r.variableConfig.GetSetVariables(), *action.Dir)
+	} else if location := r.taskFileLocation(taskName); !helpers.IsURL(location) {
+		opts.Dir = filepath.Dir(location)
+	}
+	return opts
 }
 
-func (r *Runner) executeTask(task types.Task, withs map[string]string) error {
+func (r *Runner) executeTask(ctx context.Context, task types.Task, withs map[string]string) (err error) {
 	if r.currStackSize > config.MaxStack {
 		return fmt.Errorf("task looping exceeded max configured task stack of %d", config.MaxStack)
 	}
 
+	taskStart := time.Now()
+	defer func() {
+		r.trace.traceTask(task, taskStart, err)
+	}()
+
+	// Tasks are the boundaries a change to the tasks files is noticed at
+	if err := r.checkTasksFiles(task.Name); err != nil {
+		return err
+	}
+
 	r.currStackSize++
 	defer func() {
 		r.currStackSize--
 	}()
 
+	// Log lines of the actions of included tasks say which include (and file) they came from
+	previousPrefix := message.SetPrefix(r.includePrefix(task.Name))
+	defer message.SetPrefix(previousPrefix)
+
+	// Any tunnels opened by this task's actions stay open until the task finishes
+	r.tunnels = append(r.tunnels, nil)
+	defer r.closeTunnels()
+
+	// Apply the task timeout (if any) on top of the parent context so it bounds every action in the task
+	if task.MaxTotalSeconds > 0 {
+		var cancel context.CancelFunc
+		duration := time.Duration(task.MaxTotalSeconds) * time.Second
+		ctx, cancel = context.WithTimeoutCause(ctx, duration, fmt.Errorf("task %s timed out after %d seconds", task.Name, task.MaxTotalSeconds))
+		defer cancel()
+	}
+
 	defaultEnv := []string{}
 	for name, inputParam := range task.Inputs {
 		d := inputParam.Default
@@ -287,19 +512,74 @@ func (r *Runner) executeTask(task types.Task, withs map[string]string) error {
 		defaultEnv = append(defaultEnv, utils.FormatEnvVar(name, d))
 	}
 
+	// Each call of a task gets its own output file, which is folded into the variables once the task succeeds
+	outputFile, err := newOutputFile()
+	if err != nil {
+		return err
+	}
+	defer os.Remove(outputFile)
+	generatedTasksFile, err := newGeneratedTasksFile()
+	if err != nil {
+		return err
+	}
+	defer os.Remove(generatedTasksFile)
+
 	// load the tasks env file into the runner, can override previous task's env files
 	if task.EnvPath != "" {
 		r.envFilePath = task.EnvPath
 	}
 
-	for _, action := range task.Actions {
-		action.Env = utils.MergeEnv(action.Env, defaultEnv)
-		if err := r.performAction(action, withs, task.Inputs); err != nil {
+	for idx, action := range task.Actions {
+		// Don't start any new actions once the context is done
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+
+		metadataEnv := []string{
+			fmt.Sprintf("%s=%s", RunIDEnv, r.runID),
+			fmt.Sprintf("%s=%s", TaskNameEnv, task.Name),
+			fmt.Sprintf("%s=%d", ActionIndexEnv, idx),
+			fmt.Sprintf("%s=%s", IdempotencyKeyEnv, r.idempotencyKey(task.Name, idx)),
+			fmt.Sprintf("%s=%s", OutputFileEnv, outputFile),
+			fmt.Sprintf("%s=%s", GeneratedTasksEnv, generatedTasksFile),
+		}
+		action.Env = utils.MergeEnv(metadataEnv, utils.MergeEnv(action.Env, defaultEnv))
+		if err := r.step(task, idx, action); err != nil {
+			return err
+		}
+		r.actionStarted(task, idx, action)
+		start := time.Now()
+		skipped, output, err := r.performAction(ctx, action, withs, task.Inputs, r.templateOptions(task.Name, action))
+		r.recordAction(task, idx, action, skipped, output, time.Since(start), err)
+		r.trace.traceAction(r.results.Actions[len(r.results.Actions)-1], start)
+		r.actionFinished(task, idx, action)
+		if err != nil {
+			if artifacts := r.collectArtifacts(task, idx, action, withs, output); len(artifacts) > 0 {
+				r.results.Actions[len(r.results.Actions)-1].Artifacts = artifacts
+			}
+			r.reportTaskOwner(task)
+			return err
+		}
+		if err := r.runGeneratedTasks(ctx, task, idx, generatedTasksFile); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return r.foldOutputFile(task.Name, outputFile)
+}
+
+// idempotencyKey returns a key for the next run of an action that stays the same when the action is retried (so external
+// APIs can deduplicate retried requests) but differs between runs and between calls of the same task within a run
+func (r *Runner) idempotencyKey(taskName string, idx int) string {
+	if r.actionOccurrences == nil {
+		r.actionOccurrences = map[string]int{}
+	}
+	action := fmt.Sprintf("%s[%d]", taskName, idx)
+	occurrence := r.actionOccurrences[action]
+	r.actionOccurrences[action]++
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%d", r.runID, action, occurrence)))
+	return hex.EncodeToString(sum[:16])
 }
 
 func (r *Runner) processTaskReferences(task types.Task, tasksFile types.TasksFile, setVariables map[string]string) error {
@@ -312,23 +592,31 @@ func (r *Runner) processTaskReferences(task types.Task, tasksFile types.TasksFil
 		r.currStackSize--
 	}()
 
-	// Filtering unique task actions allows for rerunning tasks in the same execution
+	// Filtering unique task actions allows for rerunning tasks in the same execution (every reference is processed so
+	// all of the includes that cannot be imported are reported at once)
+	var errs []error
 	uniqueTaskActions := getUniqueTaskActions(task.Actions)
 	for _, action := range uniqueTaskActions {
 		if r.processAction(task, action) {
 			// process includes for action, which will import all tasks for include file
 			if err := r.processIncludes(tasksFile, setVariables, action); err != nil {
-				return err
+				errs = append(errs, splitErrors(err)...)
+				continue
 			}
 
+			// Unknown tasks are left to validateTasks, which reports where they are referenced
 			newTask, err := r.getTask(action.TaskReference)
 			if err != nil {
-				return err
+				var notFound *TaskNotFoundError
+				if !errors.As(err, &notFound) {
+					errs = append(errs, err)
+				}
+				continue
 			}
 			if err = r.processTaskReferences(newTask, tasksFile, setVariables); err != nil {
-				return err
+				errs = append(errs, splitErrors(err)...)
 			}
 		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
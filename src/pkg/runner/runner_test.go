@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_idempotencyKey(t *testing.T) {
+	r := &Runner{runID: "run-a"}
+	first := r.idempotencyKey("deploy", 0)
+	require.Len(t, first, 32)
+
+	// Calling the same task again (or running another action) gets a new key
+	require.NotEqual(t, first, r.idempotencyKey("deploy", 0))
+	require.NotEqual(t, first, r.idempotencyKey("deploy", 1))
+
+	// The keys are stable for a run, but differ between runs
+	require.Equal(t, first, (&Runner{runID: "run-a"}).idempotencyKey("deploy", 0))
+	require.NotEqual(t, first, (&Runner{runID: "run-b"}).idempotencyKey("deploy", 0))
+}
+
+func TestRunner_templateOptions(t *testing.T) {
+	config.TaskFileLocation = filepath.Join("project", "tasks.yaml")
+	t.Cleanup(func() { config.TaskFileLocation = "" })
+
+	r := &Runner{
+		variableConfig: GetMaruVariableConfig(),
+		taskFileLocations: map[string]string{
+			"lib:build":    filepath.Join("project", "lib", "tasks.yaml"),
+			"remote:build": "https://example.com/tasks.yaml",
+		},
+	}
+	dir := "build"
+	withDir := types.Action{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Dir: &dir}}
+
+	// Files are read relative to the action's dir, or else the tasks file it is from
+	require.Equal(t, "build", r.templateOptions("lib:build", withDir).Dir)
+	require.Equal(t, "project", r.templateOptions("default", types.Action{}).Dir)
+	require.Equal(t, filepath.Join("project", "lib"), r.templateOptions("lib:build", types.Action{}).Dir)
+	require.Equal(t, "", r.templateOptions("remote:build", types.Action{}).Dir)
+}
@@ -0,0 +1,175 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/pkg/helpers/v2"
+)
+
+// sessionNamePattern limits session names to characters that are safe to use as a file name
+var sessionNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// SessionDir returns the directory that sessions are stored in
+func SessionDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".maru", "sessions"), nil
+}
+
+// sessionPath returns the file a session is stored in
+func sessionPath(name string) (string, error) {
+	if !sessionNamePattern.MatchString(name) || strings.Trim(name, ".") == "" {
+		return "", fmt.Errorf("invalid session name %q (must only contain letters, numbers, '_', '.' and '-')", name)
+	}
+	dir, err := SessionDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".json"), nil
+}
+
+// LoadSession returns the variables remembered by a session (a session that does not exist yet has no variables)
+func LoadSession(name string) (map[string]string, error) {
+	path, err := sessionPath(name)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if b, err = utils.Unseal(b); err != nil {
+		return nil, fmt.Errorf("unable to read session %q: %w", name, err)
+	}
+	session := map[string]string{}
+	if err := json.Unmarshal(b, &session); err != nil {
+		return nil, fmt.Errorf("unable to read session %q: %w", name, err)
+	}
+	return session, nil
+}
+
+// SaveSession stores the variables of a session, replacing any it remembered before
+func SaveSession(name string, session map[string]string) error {
+	path, err := sessionPath(name)
+	if err != nil {
+		return err
+	}
+	if err := helpers.CreateDirectory(filepath.Dir(path), helpers.ReadWriteExecuteUser); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+	if b, err = utils.Seal(b); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, helpers.ReadWriteUser)
+}
+
+// RemoveSession deletes a session
+func RemoveSession(name string) error {
+	path, err := sessionPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("session %q does not exist", name)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListSessions returns the names of the stored sessions
+func ListSessions() ([]string, error) {
+	dir, err := SessionDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".json"); ok && !entry.IsDir() {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// withSession returns the given set variables filled in with the variables remembered by a session (variables that are
+// already set take precedence over the session)
+func withSession(setVariables map[string]string, session map[string]string) map[string]string {
+	merged := map[string]string{}
+	for name, value := range session {
+		merged[name] = value
+	}
+	for name, value := range setVariables {
+		merged[name] = value
+	}
+	return merged
+}
+
+// markSessionVariables records the names of the given variables that are remembered by sessions
+func (r *Runner) markSessionVariables(vars ...variables.Variable[variables.ExtraVariableInfo]) {
+	for _, v := range vars {
+		if !v.Extra.Session {
+			continue
+		}
+		if r.sessionVariables == nil {
+			r.sessionVariables = map[string]bool{}
+		}
+		r.sessionVariables[v.Name] = true
+	}
+}
+
+// saveSession remembers the current values of the session variables in the given session
+func (r *Runner) saveSession(name string) error {
+	// Variables set by actions can also be remembered
+	for _, task := range r.tasksFile.Tasks {
+		for _, action := range task.Actions {
+			if action.BaseAction != nil {
+				r.markSessionVariables(action.SetVariables...)
+			}
+		}
+	}
+	if len(r.sessionVariables) == 0 {
+		return nil
+	}
+
+	session, err := LoadSession(name)
+	if err != nil {
+		return err
+	}
+	for variableName := range r.sessionVariables {
+		if v, ok := r.variableConfig.GetSetVariable(variableName); ok {
+			session[variableName] = v.Value
+		}
+	}
+	return SaveSession(name, session)
+}
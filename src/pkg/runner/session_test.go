@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+)
+
+func TestSessions(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	keyring.MockInit()
+
+	session, err := LoadSession("dev")
+	require.NoError(t, err)
+	require.Empty(t, session)
+
+	require.NoError(t, SaveSession("dev", map[string]string{"CLUSTER": "mine"}))
+	session, err = LoadSession("dev")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"CLUSTER": "mine"}, session)
+
+	names, err := ListSessions()
+	require.NoError(t, err)
+	require.Equal(t, []string{"dev"}, names)
+
+	require.NoError(t, RemoveSession("dev"))
+	require.EqualError(t, RemoveSession("dev"), `session "dev" does not exist`)
+
+	for _, name := range []string{"../dev", "", "..", "dev/prod"} {
+		_, err = LoadSession(name)
+		require.ErrorContains(t, err, "invalid session name", name)
+	}
+}
+
+func TestWithSession(t *testing.T) {
+	setVariables := map[string]string{"CLUSTER": "theirs"}
+	merged := withSession(setVariables, map[string]string{"CLUSTER": "mine", "KUBECONFIG": "/tmp/kubeconfig"})
+	require.Equal(t, map[string]string{"CLUSTER": "theirs", "KUBECONFIG": "/tmp/kubeconfig"}, merged)
+	require.Equal(t, map[string]string{"CLUSTER": "theirs"}, setVariables)
+}
+
+func TestRunner_saveSession(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	keyring.MockInit()
+	require.NoError(t, SaveSession("dev", map[string]string{"FROM_ANOTHER_FILE": "kept"}))
+
+	session := variables.ExtraVariableInfo{Session: true}
+	r := &Runner{
+		variableConfig: GetMaruVariableConfig(),
+		tasksFile: types.TasksFile{Tasks: []types.Task{{
+			Name: "default",
+			Actions: []types.Action{{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{
+				Cmd:          "echo output",
+				SetVariables: []variables.Variable[variables.ExtraVariableInfo]{{Name: "OUTPUT", Extra: session}},
+			}}},
+		}}},
+	}
+	r.markSessionVariables(
+		variables.Variable[variables.ExtraVariableInfo]{Name: "CLUSTER", Extra: session},
+		variables.Variable[variables.ExtraVariableInfo]{Name: "OTHER"},
+		variables.Variable[variables.ExtraVariableInfo]{Name: "NEVER_SET", Extra: session},
+	)
+	r.variableConfig.SetVariable("CLUSTER", "mine", "", session)
+	r.variableConfig.SetVariable("OTHER", "other", "", variables.ExtraVariableInfo{})
+	r.variableConfig.SetVariable("OUTPUT", "output", "", variables.ExtraVariableInfo{})
+
+	require.NoError(t, r.saveSession("dev"))
+	saved, err := LoadSession("dev")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"FROM_ANOTHER_FILE": "kept", "CLUSTER": "mine", "OUTPUT": "output"}, saved)
+}
@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/pterm/pterm"
+)
+
+// stepInput is where step mode reads its commands from
+var stepInput io.Reader = os.Stdin
+
+// errStepQuit is returned when the run is stopped from step mode
+var errStepQuit = errors.New("run stopped in step mode")
+
+// stepHelp describes the commands that can be given in step mode
+const stepHelp = `  <enter>, c          run the action
+  v, vars [NAME]      print the variables (or only the given variable)
+  set NAME=value      change (or add) a variable for the rest of the run
+  unset NAME          remove a variable for the rest of the run
+  r, resume           run the rest of the run without stepping
+  q, quit             stop the run
+  h, help             print this help`
+
+// step pauses before an action in step mode, letting the variables be inspected and changed until the action is run or
+// the run is stopped. Changes are made to the variables of the run, so they last for the rest of it.
+func (r *Runner) step(task types.Task, idx int, action types.Action) error {
+	if !r.stepping {
+		return nil
+	}
+	if r.stepReader == nil {
+		r.stepReader = bufio.NewReader(stepInput)
+	}
+
+	message.SLog.Info(fmt.Sprintf("Step: next is action %d of task %s (%s)", idx, task.Name, actionName(action)))
+	for {
+		fmt.Fprint(os.Stderr, "step (h for help)> ")
+		line, err := r.stepReader.ReadString('\n')
+		if err != nil && line == "" {
+			// Run the rest without stepping once there is nothing more to read (i.e. stdin was closed)
+			fmt.Fprintln(os.Stderr)
+			r.stepping = false
+			return nil
+		}
+
+		command, args, _ := strings.Cut(strings.TrimSpace(line), " ")
+		args = strings.TrimSpace(args)
+		switch command {
+		case "", "c", "continue":
+			return nil
+		case "r", "resume":
+			r.stepping = false
+			return nil
+		case "q", "quit":
+			return errStepQuit
+		case "v", "vars":
+			fmt.Fprintln(os.Stderr, formatStepVariables(r.variableConfig.GetSetVariables(), args))
+		case "set":
+			if err := r.stepSetVariable(args); err != nil {
+				message.SLog.Warn(err.Error())
+			}
+		case "unset":
+			name := strings.ToUpper(args)
+			if _, ok := r.variableConfig.GetSetVariable(name); !ok {
+				message.SLog.Warn(fmt.Sprintf("Variable %s is not set", name))
+				continue
+			}
+			delete(r.variableConfig.GetSetVariables(), name)
+			message.SLog.Info(fmt.Sprintf("Removed variable %s for the rest of the run", name))
+		case "h", "help", "?":
+			fmt.Fprintln(os.Stderr, stepHelp)
+		default:
+			message.SLog.Warn(fmt.Sprintf("Unknown step command %q (h for help)", command))
+		}
+	}
+}
+
+// stepSetVariable sets a variable from a NAME=value argument, keeping the pattern and settings of an existing variable
+func (r *Runner) stepSetVariable(arg string) error {
+	name, value, ok := strings.Cut(arg, "=")
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if !ok || name == "" {
+		return fmt.Errorf("set needs a variable in the form NAME=value, not %q", arg)
+	}
+
+	pattern, extra := "", variables.ExtraVariableInfo{}
+	if existing, ok := r.variableConfig.GetSetVariable(name); ok {
+		pattern, extra = existing.Pattern, existing.Extra
+	}
+	if pattern != "" && !regexp.MustCompile(pattern).MatchString(value) {
+		return fmt.Errorf("value for variable %q does not match pattern %q", name, pattern)
+	}
+	r.variableConfig.SetVariable(name, value, pattern, extra)
+	message.SLog.Info(fmt.Sprintf("Set variable %s for the rest of the run", name))
+	return nil
+}
+
+// formatStepVariables formats the variables (or only the named variable) of a run as a table, hiding sensitive values
+func formatStepVariables(setVariables variables.SetVariableMap[variables.ExtraVariableInfo], name string) string {
+	rows := [][]string{{"Name", "Value"}}
+	for _, key := range sortedKeys(setVariables) {
+		if name != "" && !strings.EqualFold(key, name) {
+			continue
+		}
+		value := setVariables[key].Value
+		if setVariables[key].Extra.Sensitive {
+			value = sensitiveValue
+		}
+		rows = append(rows, []string{key, value})
+	}
+	if len(rows) == 1 {
+		if name != "" {
+			return fmt.Sprintf("Variable %s is not set", name)
+		}
+		return "No variables are set"
+	}
+	table, err := pterm.DefaultTable.WithHasHeader().WithData(rows).Srender()
+	if err != nil {
+		return err.Error()
+	}
+	return table
+}
@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"bufio"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_step(t *testing.T) {
+	newRunner := func(input string) *Runner {
+		variableConfig := GetMaruVariableConfig()
+		variableConfig.SetVariable("GREETING", "hello", "", variables.ExtraVariableInfo{})
+		variableConfig.SetVariable("NUMBER", "1", "^[0-9]+$", variables.ExtraVariableInfo{})
+		return &Runner{variableConfig: variableConfig, stepping: true, stepReader: bufio.NewReader(strings.NewReader(input))}
+	}
+	task := types.Task{
+		Name: "greet",
+		Actions: []types.Action{
+			{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: "echo ${GREETING} ${NUMBER}"}},
+			{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: "echo ${GREETING} again"}},
+		},
+	}
+
+	t.Run("changes last for the rest of the run", func(t *testing.T) {
+		r := newRunner("v\nset greeting=hi\nset NUMBER=two\n\nunset number\nc\n")
+		require.NoError(t, r.executeTask(context.Background(), task, nil))
+		require.Len(t, r.results.Actions, 2)
+		require.Equal(t, "hi 1", r.results.Actions[0].Output)
+		require.Equal(t, "hi again", r.results.Actions[1].Output)
+		_, ok := r.variableConfig.GetSetVariable("NUMBER")
+		require.False(t, ok)
+	})
+
+	t.Run("resume stops stepping", func(t *testing.T) {
+		r := newRunner("r\nq\n")
+		require.NoError(t, r.executeTask(context.Background(), task, nil))
+		require.Len(t, r.results.Actions, 2)
+		require.False(t, r.stepping)
+	})
+
+	t.Run("quit stops the run", func(t *testing.T) {
+		r := newRunner("\nq\n")
+		require.ErrorIs(t, r.executeTask(context.Background(), task, nil), errStepQuit)
+		require.Len(t, r.results.Actions, 1)
+	})
+
+	t.Run("the end of the input stops stepping", func(t *testing.T) {
+		r := newRunner("set GREETING=bye")
+		require.NoError(t, r.executeTask(context.Background(), task, nil))
+		require.Equal(t, "bye again", r.results.Actions[1].Output)
+		require.False(t, r.stepping)
+	})
+}
+
+func Test_formatStepVariables(t *testing.T) {
+	setVariables := variables.SetVariableMap[variables.ExtraVariableInfo]{
+		"DOMAIN":   {Variable: variables.Variable[variables.ExtraVariableInfo]{Name: "DOMAIN"}, Value: "uds.dev"},
+		"PASSWORD": {Variable: variables.Variable[variables.ExtraVariableInfo]{Name: "PASSWORD", Extra: variables.ExtraVariableInfo{Sensitive: true}}, Value: "secret"},
+	}
+
+	table := formatStepVariables(setVariables, "")
+	require.Contains(t, table, "uds.dev")
+	require.Contains(t, table, sensitiveValue)
+	require.NotContains(t, table, "secret")
+	require.NotContains(t, formatStepVariables(setVariables, "domain"), "PASSWORD")
+	require.Equal(t, "Variable MISSING is not set", formatStepVariables(setVariables, "MISSING"))
+	require.Equal(t, "No variables are set", formatStepVariables(nil, ""))
+}
@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/pkg/exec"
+)
+
+// posixStrictPrologue makes a POSIX shell stop at the first failing command (including a failure anywhere in a pipeline
+// when the shell supports pipefail) or the first use of an unset variable, and report the status the script exited with
+const posixStrictPrologue = `set -eu; if (set -o pipefail) 2>/dev/null; then set -o pipefail; fi; ` +
+	`trap 'maru_status=$?; if [ "$maru_status" -ne 0 ]; then echo "shellStrict: script exited with status $maru_status" >&2; fi; exit "$maru_status"' EXIT; `
+
+// powershellStrictPrologue makes PowerShell treat uninitialized variables as errors and stop when a native command fails
+// (errors from cmdlets already stop the script as every command is run with $ErrorActionPreference = 'Stop')
+const powershellStrictPrologue = `Set-StrictMode -Version Latest; $PSNativeCommandUseErrorActionPreference = $true; `
+
+// strictCommand wraps a command in the strict mode prologue of the shell it will be run in, which is the one set in the
+// config (if any) or the default (the prologue is kept on the first line so the shell's own line numbers still match
+// the command)
+func strictCommand(cmd, shell string) (string, error) {
+	name := shellName(shell)
+	var prologue string
+	switch {
+	case exec.IsPowerShell(name):
+		prologue = strictPrologue(config.ShellStrictPowerShellPrologue, powershellStrictPrologue)
+	case name == "cmd" || name == "fish":
+		return "", fmt.Errorf("shellStrict is not supported for the %s shell", name)
+	default:
+		prologue = strictPrologue(config.ShellStrictPrologue, posixStrictPrologue)
+	}
+	if strings.ContainsAny(prologue, "\r\n") {
+		return "", fmt.Errorf("the shellStrict prologue %q must be a single line so that line numbers still match the command", prologue)
+	}
+	return prologue + cmd, nil
+}
+
+// strictPrologue returns the configured prologue (ending in a separator so the command can follow it on the same line)
+// or the default if none is configured
+func strictPrologue(configured, defaultPrologue string) string {
+	configured = strings.TrimSpace(configured)
+	if configured == "" {
+		return defaultPrologue
+	}
+	return strings.TrimSuffix(configured, ";") + "; "
+}
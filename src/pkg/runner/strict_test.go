@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/defenseunicorns/pkg/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_strictCommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		shell      string
+		wantPrefix string
+		wantErrMsg string
+	}{
+		{name: "sh", shell: "sh", wantPrefix: posixStrictPrologue},
+		{name: "bash by path", shell: "/usr/bin/bash", wantPrefix: posixStrictPrologue},
+		{name: "pwsh", shell: "pwsh", wantPrefix: powershellStrictPrologue},
+		{name: "powershell exe", shell: "powershell.exe", wantPrefix: powershellStrictPrologue},
+		{name: "cmd", shell: "cmd", wantErrMsg: "shellStrict is not supported for the cmd shell"},
+		{name: "fish", shell: "fish", wantErrMsg: "shellStrict is not supported for the fish shell"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := strictCommand("echo hello", tt.shell)
+			if tt.wantErrMsg != "" {
+				require.EqualError(t, err, tt.wantErrMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, strings.HasPrefix(got, tt.wantPrefix))
+			require.True(t, strings.HasSuffix(got, "; echo hello"))
+		})
+	}
+}
+
+func Test_strictCommand_configuredPrologue(t *testing.T) {
+	t.Cleanup(func() {
+		config.ShellStrictPrologue = ""
+		config.ShellStrictPowerShellPrologue = ""
+	})
+
+	config.ShellStrictPrologue = "set -euo pipefail;"
+	config.ShellStrictPowerShellPrologue = "Set-StrictMode -Version 2"
+	got, err := strictCommand("echo hello", "bash")
+	require.NoError(t, err)
+	require.Equal(t, "set -euo pipefail; echo hello", got)
+	got, err = strictCommand("echo hello", "pwsh")
+	require.NoError(t, err)
+	require.Equal(t, "Set-StrictMode -Version 2; echo hello", got)
+
+	// A prologue over several lines would throw off the line numbers of the command
+	config.ShellStrictPrologue = "set -e\nset -u"
+	_, err = strictCommand("echo hello", "sh")
+	require.ErrorContains(t, err, "must be a single line")
+}
+
+func TestRunAction_shellStrict(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("strict mode scripts are written for POSIX shells")
+	}
+
+	tests := []struct {
+		name       string
+		cmd        string
+		shell      string
+		strict     bool
+		want       string
+		wantErrMsg string
+	}{
+		{
+			name: "unset variables are empty without strict mode",
+			cmd:  "echo \"[${MARU_STRICT_UNSET}]\"",
+			want: "[]",
+		},
+		{
+			name:       "unset variables fail in strict mode",
+			cmd:        "echo \"[${MARU_STRICT_UNSET}]\"",
+			strict:     true,
+			wantErrMsg: "failed after 0 retries",
+		},
+		{
+			name:  "failing pipelines continue without strict mode",
+			cmd:   "false | cat\necho done",
+			shell: "bash",
+			want:  "done",
+		},
+		{
+			name:       "failing pipelines fail in strict mode with pipefail",
+			cmd:        "false | cat\necho done",
+			shell:      "bash",
+			strict:     true,
+			wantErrMsg: "failed after 0 retries",
+		},
+		{
+			name:   "successful scripts are unaffected by strict mode",
+			cmd:    "echo one\necho two",
+			strict: true,
+			want:   "one\ntwo",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variableConfig := GetMaruVariableConfig()
+			action := types.BaseAction[variables.ExtraVariableInfo]{
+				Cmd:          tt.cmd,
+				Shell:        &exec.ShellPreference{Linux: tt.shell, Darwin: tt.shell},
+				ShellStrict:  &tt.strict,
+				SetVariables: []variables.Variable[variables.ExtraVariableInfo]{{Name: "OUTPUT"}},
+			}
+
+			err := RunActionWithContext(context.TODO(), &action, "", variableConfig, false)
+			if tt.wantErrMsg != "" {
+				require.ErrorContains(t, err, tt.wantErrMsg)
+				return
+			}
+			require.NoError(t, err)
+			output, ok := variableConfig.GetSetVariable("OUTPUT")
+			require.True(t, ok)
+			require.Equal(t, tt.want, output.Value)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/defenseunicorns/pkg/helpers/v2"
+)
+
+// traceThread is the thread every event is recorded on (tasks and their actions run one after another, so they nest
+// by time in a single row)
+const traceThread = 1
+
+// TraceEvent is an event in the Chrome trace event format (see "Trace Event Format" in the Chromium docs), which can
+// be loaded into chrome://tracing or https://ui.perfetto.dev
+type TraceEvent struct {
+	Name      string         `json:"name"`
+	Category  string         `json:"cat,omitempty"`
+	Phase     string         `json:"ph"`
+	Timestamp int64          `json:"ts"`
+	Duration  int64          `json:"dur,omitempty"`
+	PID       int            `json:"pid"`
+	TID       int            `json:"tid"`
+	Args      map[string]any `json:"args,omitempty"`
+}
+
+// Trace is the trace of a run, with timestamps and durations in microseconds since the run started
+type Trace struct {
+	TraceEvents     []TraceEvent      `json:"traceEvents"`
+	DisplayTimeUnit string            `json:"displayTimeUnit"`
+	OtherData       map[string]string `json:"otherData,omitempty"`
+}
+
+// tracer records a complete event for every task and action of a run
+type tracer struct {
+	start  time.Time
+	pid    int
+	events []TraceEvent
+}
+
+// newTracer returns a tracer for a run of a task, naming the process after the run
+func newTracer(runID, taskName string) *tracer {
+	t := &tracer{start: time.Now(), pid: os.Getpid()}
+	t.events = append(t.events,
+		TraceEvent{Name: "process_name", Phase: "M", PID: t.pid, TID: traceThread, Args: map[string]any{"name": fmt.Sprintf("maru run %s (%s)", taskName, runID)}},
+		TraceEvent{Name: "thread_name", Phase: "M", PID: t.pid, TID: traceThread, Args: map[string]any{"name": "tasks"}},
+	)
+	return t
+}
+
+// complete records an event that started at start and finished now
+func (t *tracer) complete(name, category string, start time.Time, args map[string]any) {
+	t.events = append(t.events, TraceEvent{
+		Name:      name,
+		Category:  category,
+		Phase:     "X",
+		Timestamp: start.Sub(t.start).Microseconds(),
+		// Chrome drops complete events without a duration, so anything shorter than a microsecond is rounded up
+		Duration: max(time.Since(start).Microseconds(), 1),
+		PID:      t.pid,
+		TID:      traceThread,
+		Args:     args,
+	})
+}
+
+// traceTask records a task that started at start and finished with err
+func (t *tracer) traceTask(task types.Task, start time.Time, err error) {
+	if t == nil {
+		return
+	}
+	args := map[string]any{"status": ActionSucceeded}
+	if err != nil {
+		args["status"] = ActionFailed
+		args["error"] = message.Mask(err.Error())
+	}
+	t.complete(task.Name, "task", start, args)
+}
+
+// traceAction records an action that started at start using its recorded result
+func (t *tracer) traceAction(result ActionResult, start time.Time) {
+	if t == nil {
+		return
+	}
+	args := map[string]any{"task": result.Task, "action": result.Action, "status": result.Status}
+	if result.Reason != "" {
+		args["reason"] = result.Reason
+	}
+	t.complete(result.Name, "action", start, args)
+}
+
+// write writes the trace to a file
+func (t *tracer) write(path string, results RunResults) error {
+	trace := Trace{
+		TraceEvents:     t.events,
+		DisplayTimeUnit: "ms",
+		OtherData:       map[string]string{"runId": results.RunID, "task": results.Task, "status": string(results.Status)},
+	}
+	b, err := json.MarshalIndent(trace, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, helpers.ReadWriteUser)
+}
@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_executeTask_trace(t *testing.T) {
+	cmd := func(cmd string) types.Action {
+		return types.Action{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: cmd}}
+	}
+	tasks := []types.Task{
+		{Name: "default", Actions: []types.Action{
+			cmd("sleep 0.01"),
+			{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{}, TaskReference: "nested"},
+		}},
+		{Name: "nested", Actions: []types.Action{cmd("exit 1")}},
+	}
+	r := &Runner{variableConfig: GetMaruVariableConfig(), tasksFile: types.TasksFile{Tasks: tasks}}
+	r.trace = newTracer("run-id", "default")
+
+	require.Error(t, r.executeTask(context.Background(), tasks[0], nil))
+
+	path := filepath.Join(t.TempDir(), "trace.json")
+	require.NoError(t, r.trace.write(path, RunResults{RunID: "run-id", Task: "default", Status: ActionFailed}))
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var trace Trace
+	require.NoError(t, json.Unmarshal(b, &trace))
+	require.Equal(t, "failed", trace.OtherData["status"])
+
+	// Metadata, then every event in the order it finished (the nested task finishes before the action that called it)
+	require.Len(t, trace.TraceEvents, 7)
+	require.Equal(t, "M", trace.TraceEvents[0].Phase)
+	require.Equal(t, "maru run default (run-id)", trace.TraceEvents[0].Args["name"])
+	events := trace.TraceEvents[2:]
+	names := []string{}
+	for _, event := range events {
+		require.Equal(t, "X", event.Phase)
+		require.Positive(t, event.Duration)
+		names = append(names, event.Category+":"+event.Name)
+	}
+	require.Equal(t, []string{"action:sleep 0.01", "action:exit 1", "task:nested", "action:task nested", "task:default"}, names)
+	require.Equal(t, "failed", events[1].Args["status"])
+	require.Equal(t, "succeeded", events[0].Args["status"])
+
+	// Each event nests inside the events that were running when it started (allowing for rounding to microseconds)
+	within := func(inner, outer TraceEvent) {
+		require.GreaterOrEqual(t, inner.Timestamp, outer.Timestamp)
+		require.LessOrEqual(t, inner.Timestamp+inner.Duration, outer.Timestamp+outer.Duration+2)
+	}
+	within(events[0], events[4])
+	within(events[1], events[2])
+	within(events[2], events[3])
+	within(events[3], events[4])
+	require.GreaterOrEqual(t, events[0].Duration, int64(10000))
+}
+
+func Test_tracer_traceTask_masked(t *testing.T) {
+	t.Cleanup(message.ClearSensitive)
+	message.AddSensitive("hunter22")
+
+	trace := newTracer("run-id", "login")
+	trace.traceTask(types.Task{Name: "login"}, time.Now(), errors.New(`command "login --password hunter22" failed`))
+	require.Equal(t, `command "login --password `+message.SanitizedValue+`" failed`, trace.events[len(trace.events)-1].Args["error"])
+}
+
+func TestRun_traceWithoutResults(t *testing.T) {
+	dir := t.TempDir()
+	config.TraceFile = filepath.Join(dir, "trace.json")
+	config.ResultsFile = filepath.Join(dir, "missing", "results.json")
+	runHistory := config.RunHistory
+	config.RunHistory = 0
+	t.Cleanup(func() {
+		config.TraceFile = ""
+		config.ResultsFile = ""
+		config.RunHistory = runHistory
+	})
+
+	tasksFile := types.TasksFile{Tasks: []types.Task{
+		{Name: "default", Actions: []types.Action{{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: "exit 1"}}}},
+	}}
+
+	// The trace is still written when the results file can't be
+	err := RunWithContext(context.Background(), tasksFile, "default", nil, false, nil)
+	require.ErrorContains(t, err, "results.json")
+	b, err := os.ReadFile(config.TraceFile)
+	require.NoError(t, err)
+	var trace Trace
+	require.NoError(t, json.Unmarshal(b, &trace))
+	require.Equal(t, "failed", trace.OtherData["status"])
+}
@@ -0,0 +1,184 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	osexec "os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
+	"github.com/defenseunicorns/maru-runner/src/types"
+)
+
+const (
+	// tunnelReadyTimeout is how long to wait for a tunnel's local port to accept connections (unless maxTotalSeconds is set)
+	tunnelReadyTimeout = 30 * time.Second
+	// tunnelPollInterval is how often to check whether a tunnel's local port accepts connections
+	tunnelPollInterval = 100 * time.Millisecond
+)
+
+// sshCommand builds the ssh command used to open tunnels (swapped out in tests)
+var sshCommand = func(ctx context.Context, args ...string) *osexec.Cmd {
+	return osexec.CommandContext(ctx, "ssh", args...)
+}
+
+// tunnel is an open SSH tunnel that is closed when the task that opened it finishes
+type tunnel struct {
+	description string
+	cancel      context.CancelFunc
+	exited      <-chan error
+}
+
+// close stops the ssh process behind the tunnel and waits for it to exit
+func (t *tunnel) close() {
+	t.cancel()
+	<-t.exited
+	message.SLog.Debug(fmt.Sprintf("Closed %s", t.description))
+}
+
+// closeTunnels closes the tunnels opened by the innermost running task (in reverse order)
+func (r *Runner) closeTunnels() {
+	tunnels := r.tunnels[len(r.tunnels)-1]
+	r.tunnels = r.tunnels[:len(r.tunnels)-1]
+	for idx := len(tunnels) - 1; idx >= 0; idx-- {
+		tunnels[idx].close()
+	}
+}
+
+// tunnelArgs returns the ssh arguments for a tunnel (a local forward when remote is set, otherwise a SOCKS proxy), ending
+// the options before the via host so that it is never read as one
+func tunnelArgs(tun types.ActionTunnel) ([]string, string, error) {
+	if tun.Via == "" {
+		return nil, "", errors.New("tunnel is missing a via host")
+	}
+	if tun.Local <= 0 || tun.Local > 65535 {
+		return nil, "", fmt.Errorf("tunnel local port %d is not a valid port", tun.Local)
+	}
+
+	args := []string{"-N", "-o", "ExitOnForwardFailure=yes", "-o", "BatchMode=yes"}
+	local := net.JoinHostPort("127.0.0.1", strconv.Itoa(tun.Local))
+	if tun.Remote == "" {
+		args = append(args, "-D", local, "--", tun.Via)
+		return args, fmt.Sprintf("SOCKS proxy on %s via %s", local, tun.Via), nil
+	}
+
+	if _, _, err := net.SplitHostPort(tun.Remote); err != nil {
+		return nil, "", fmt.Errorf("tunnel remote %q must be in the form host:port", tun.Remote)
+	}
+	args = append(args, "-L", fmt.Sprintf("%s:%s", local, tun.Remote), "--", tun.Via)
+	return args, fmt.Sprintf("tunnel from %s to %s via %s", local, tun.Remote, tun.Via), nil
+}
+
+// openTunnel starts an ssh tunnel in the background and waits for its local port to accept connections
+func (r *Runner) openTunnel(ctx context.Context, action types.Action) error {
+	if len(r.tunnels) == 0 {
+		return errors.New("tunnel actions can only be run as part of a task")
+	}
+
+	tun := *action.Tunnel
+	tun.Via = utils.TemplateString(r.variableConfig.GetSetVariables(), tun.Via)
+	tun.Remote = utils.TemplateString(r.variableConfig.GetSetVariables(), tun.Remote)
+
+	args, description, err := tunnelArgs(tun)
+	if err != nil {
+		return err
+	}
+
+	if r.dryRun {
+		message.SLog.Info(fmt.Sprintf("Dry-running %q", "Open "+description))
+		return nil
+	}
+
+	readyTimeout := tunnelReadyTimeout
+	if action.BaseAction != nil && action.MaxTotalSeconds != nil && *action.MaxTotalSeconds > 0 {
+		readyTimeout = time.Duration(*action.MaxTotalSeconds) * time.Second
+	}
+
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(tun.Local))
+
+	// Something else listening on the local port would look like the tunnel is ready, so the port must be free
+	if listener, err := net.Listen("tcp", address); err != nil {
+		return fmt.Errorf("unable to open %s as the local port is already in use: %w", description, err)
+	} else if err := listener.Close(); err != nil {
+		return err
+	}
+
+	spinner := message.NewProgressSpinner("Opening %s", description)
+
+	// The tunnel lives beyond this action (until the task finishes) so it gets its own cancel func
+	tunnelCtx, cancel := context.WithCancel(ctx)
+	var stderr bytes.Buffer
+	cmd := sshCommand(tunnelCtx, args...)
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = cmdWaitDelay
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		spinner.Failf("Failed to open %s", description)
+		return err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	deadline := time.After(readyTimeout)
+	ticker := time.NewTicker(tunnelPollInterval)
+	defer ticker.Stop()
+
+	sshExited := func(err error) error {
+		cancel()
+		spinner.Failf("Failed to open %s", description)
+		return fmt.Errorf("ssh exited before the tunnel was ready (%v): %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	for {
+		conn, dialErr := net.DialTimeout("tcp", address, tunnelPollInterval)
+		if dialErr == nil {
+			_ = conn.Close()
+		}
+
+		// ssh exiting means the tunnel failed even if something (else) accepted the connection
+		select {
+		case err := <-exited:
+			return sshExited(err)
+		default:
+		}
+		if dialErr == nil {
+			break
+		}
+
+		select {
+		case err := <-exited:
+			return sshExited(err)
+		case <-deadline:
+			cancel()
+			<-exited
+			spinner.Failf("Failed to open %s", description)
+			return fmt.Errorf("%s was not ready after %s", description, readyTimeout)
+		case <-ctx.Done():
+			cancel()
+			<-exited
+			spinner.Failf("Failed to open %s", description)
+			return context.Cause(ctx)
+		case <-ticker.C:
+		}
+	}
+
+	t := &tunnel{description: description, cancel: cancel, exited: exited}
+	r.tunnels[len(r.tunnels)-1] = append(r.tunnels[len(r.tunnels)-1], t)
+	spinner.Successf("Opened %s", description)
+	return nil
+}
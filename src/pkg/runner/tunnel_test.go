@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	osexec "os/exec"
+	"strings"
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHelperSSHProcess stands in for ssh, listening on the forwarded (or SOCKS) address until it is killed
+func TestHelperSSHProcess(t *testing.T) {
+	if os.Getenv("MARU_TEST_SSH_HELPER") == "" {
+		return
+	}
+
+	args := os.Args
+	if args[len(args)-1] == "broken" {
+		fmt.Fprintln(os.Stderr, "ssh: Could not resolve hostname broken")
+		os.Exit(255)
+	}
+	for idx, arg := range args {
+		if arg == "-L" || arg == "-D" {
+			parts := strings.Split(args[idx+1], ":")
+			listener, err := net.Listen("tcp", strings.Join(parts[:2], ":"))
+			if err != nil {
+				os.Exit(255)
+			}
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					os.Exit(1)
+				}
+				_ = conn.Close()
+			}
+		}
+	}
+	os.Exit(2)
+}
+
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func Test_tunnelArgs(t *testing.T) {
+	args, description, err := tunnelArgs(types.ActionTunnel{Via: "bastion", Local: 5432, Remote: "db:5432"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"-N", "-o", "ExitOnForwardFailure=yes", "-o", "BatchMode=yes", "-L", "127.0.0.1:5432:db:5432", "--", "bastion"}, args)
+	require.Equal(t, "tunnel from 127.0.0.1:5432 to db:5432 via bastion", description)
+
+	args, description, err = tunnelArgs(types.ActionTunnel{Via: "bastion", Local: 1080})
+	require.NoError(t, err)
+	require.Equal(t, []string{"-N", "-o", "ExitOnForwardFailure=yes", "-o", "BatchMode=yes", "-D", "127.0.0.1:1080", "--", "bastion"}, args)
+	require.Equal(t, "SOCKS proxy on 127.0.0.1:1080 via bastion", description)
+
+	// A via host that looks like an option comes after the end of the options
+	args, _, err = tunnelArgs(types.ActionTunnel{Via: "-oProxyCommand=sh", Local: 1080})
+	require.NoError(t, err)
+	require.Equal(t, []string{"--", "-oProxyCommand=sh"}, args[len(args)-2:])
+
+	_, _, err = tunnelArgs(types.ActionTunnel{Local: 5432})
+	require.EqualError(t, err, "tunnel is missing a via host")
+
+	_, _, err = tunnelArgs(types.ActionTunnel{Via: "bastion", Local: 70000})
+	require.EqualError(t, err, "tunnel local port 70000 is not a valid port")
+
+	_, _, err = tunnelArgs(types.ActionTunnel{Via: "bastion", Local: 5432, Remote: "db"})
+	require.EqualError(t, err, `tunnel remote "db" must be in the form host:port`)
+}
+
+func TestRunner_openTunnel(t *testing.T) {
+	sshCommand = func(ctx context.Context, args ...string) *osexec.Cmd {
+		cmd := osexec.CommandContext(ctx, os.Args[0], append([]string{"-test.run=TestHelperSSHProcess", "--"}, args...)...)
+		cmd.Env = append(os.Environ(), "MARU_TEST_SSH_HELPER=1")
+		return cmd
+	}
+	t.Cleanup(func() {
+		sshCommand = func(ctx context.Context, args ...string) *osexec.Cmd {
+			return osexec.CommandContext(ctx, "ssh", args...)
+		}
+	})
+
+	newAction := func(tun types.ActionTunnel) types.Action {
+		return types.Action{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{}, Tunnel: &tun}
+	}
+
+	t.Run("tunnel stays open until the task finishes", func(t *testing.T) {
+		r := &Runner{variableConfig: GetMaruVariableConfig(), tunnels: [][]*tunnel{nil}}
+		port := freePort(t)
+		address := fmt.Sprintf("127.0.0.1:%d", port)
+
+		err := r.openTunnel(context.Background(), newAction(types.ActionTunnel{Via: "bastion", Local: port, Remote: "db:5432"}))
+		require.NoError(t, err)
+		require.Len(t, r.tunnels[0], 1)
+
+		conn, err := net.Dial("tcp", address)
+		require.NoError(t, err)
+		_ = conn.Close()
+
+		r.closeTunnels()
+		require.Empty(t, r.tunnels)
+		_, err = net.Dial("tcp", address)
+		require.Error(t, err)
+	})
+
+	t.Run("ssh failure is reported", func(t *testing.T) {
+		r := &Runner{variableConfig: GetMaruVariableConfig(), tunnels: [][]*tunnel{nil}}
+		err := r.openTunnel(context.Background(), newAction(types.ActionTunnel{Via: "broken", Local: freePort(t)}))
+		require.ErrorContains(t, err, "Could not resolve hostname broken")
+		require.Empty(t, r.tunnels[0])
+	})
+
+	t.Run("port that is already in use is reported", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+		port := listener.Addr().(*net.TCPAddr).Port
+
+		r := &Runner{variableConfig: GetMaruVariableConfig(), tunnels: [][]*tunnel{nil}}
+		err = r.openTunnel(context.Background(), newAction(types.ActionTunnel{Via: "bastion", Local: port, Remote: "db:5432"}))
+		require.ErrorContains(t, err, "the local port is already in use")
+		require.Empty(t, r.tunnels[0])
+	})
+
+	t.Run("tunnels require a task", func(t *testing.T) {
+		r := &Runner{variableConfig: GetMaruVariableConfig()}
+		err := r.openTunnel(context.Background(), newAction(types.ActionTunnel{Via: "bastion", Local: freePort(t)}))
+		require.EqualError(t, err, "tunnel actions can only be run as part of a task")
+	})
+}
@@ -0,0 +1,228 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
+	"github.com/defenseunicorns/maru-runner/src/types"
+)
+
+// usesSchemes are the location schemes that a `uses` reference can have
+var usesSchemes = []string{"file://", "http://", "https://"}
+
+// splitTaskReference splits a reference to a task in the form [<include>:]<task> into the include (or namespace of a
+// file imported with `uses`) the task is in and the task's name, which is empty for a task in the same file. Every
+// reference to a task is resolved through it (rather than each caller splitting names on its own), and since task names
+// cannot contain colons the include is everything before the first one.
+func splitTaskReference(reference string) (string, string) {
+	if include, task, found := strings.Cut(reference, ":"); found {
+		return include, task
+	}
+	return "", reference
+}
+
+// joinTaskReference returns the reference to a task in an include (or to the task itself if the include is empty)
+func joinTaskReference(include, task string) string {
+	if include == "" {
+		return task
+	}
+	return include + ":" + task
+}
+
+// UsesReference is a parsed `uses` reference to a task in another file
+type UsesReference struct {
+	// Location is the file the task is defined in (a path for file:// references, otherwise a URL)
+	Location string
+	// Task is the name of the task within the file
+	Task string
+	// Digest is the digest the file is pinned to (if any)
+	Digest string
+}
+
+// ParseUses parses a `uses` reference in the form <location>:<task>[@sha256:<digest>] where the location is a file://
+// path or an http(s) URL. The task is everything after the last colon of the location, so locations can contain colons
+// (i.e. URL schemes and ports) but task names cannot. Only digests can be pinned, as files have no versions to resolve
+// (i.e. @v2) outside of a registry, and oci:// references are not supported.
+func ParseUses(uses string) (UsesReference, error) {
+	var ref UsesReference
+
+	reference, digest := utils.SplitIncludeDigest(uses)
+	ref.Digest = digest
+
+	scheme := ""
+	for _, s := range usesSchemes {
+		if strings.HasPrefix(reference, s) {
+			scheme = s
+			break
+		}
+	}
+	if scheme == "" {
+		if before, _, found := strings.Cut(reference, "://"); found {
+			return ref, fmt.Errorf("uses %q has an unsupported scheme %q (must be one of %s)", uses, before, strings.Join(usesSchemes, ", "))
+		}
+		return ref, fmt.Errorf("uses %q must start with one of %s", uses, strings.Join(usesSchemes, ", "))
+	}
+
+	idx := strings.LastIndex(reference, ":")
+	if idx < len(scheme) {
+		return ref, fmt.Errorf("uses %q is missing a task (i.e. %s./tasks.yaml:build)", uses, scheme)
+	}
+	ref.Location, ref.Task = reference[:idx], reference[idx+1:]
+	if ref.Task == "" || strings.Contains(ref.Task, "/") {
+		return ref, fmt.Errorf("uses %q is missing a task (i.e. %s./tasks.yaml:build)", uses, scheme)
+	}
+	if task, version, found := strings.Cut(ref.Task, "@"); found {
+		return ref, fmt.Errorf("uses %q pins task %s to version %q, but only digests can be pinned (i.e. @sha256:<digest>)", uses, task, version)
+	}
+
+	if scheme == "file://" {
+		ref.Location = strings.TrimPrefix(ref.Location, scheme)
+		if ref.Location == "" {
+			return ref, fmt.Errorf("uses %q is missing a file", uses)
+		}
+	}
+	return ref, nil
+}
+
+// includeLocation returns the reference's location in the form used by includes (with the digest pinned if set)
+func (ref UsesReference) includeLocation() string {
+	if ref.Digest == "" {
+		return ref.Location
+	}
+	return ref.Location + "@" + ref.Digest
+}
+
+// resolveUses imports the files of any `uses` references in the given tasks (and every task they transitively
+// reference) and points those actions at the imported tasks, returning a problem for each reference that could not be
+// resolved. Each file is imported once under a namespace named after the file (i.e. build.yaml:compile), which is
+// suffixed with a number if another file or include already has that name.
+func (r *Runner) resolveUses(taskNames []string, setVariables map[string]string) []error {
+	var errs []error
+	visited := map[string]bool{}
+
+	for len(taskNames) > 0 {
+		taskName := taskNames[0]
+		taskNames = taskNames[1:]
+		if visited[taskName] {
+			continue
+		}
+		visited[taskName] = true
+
+		idx := slices.IndexFunc(r.tasksFile.Tasks, func(t types.Task) bool { return t.Name == taskName })
+		if idx < 0 {
+			continue
+		}
+		// imports append to the tasks so the task is looked up by index rather than held onto
+		for j := range r.tasksFile.Tasks[idx].Actions {
+			action := r.tasksFile.Tasks[idx].Actions[j]
+			if action.Uses != "" {
+				if action.TaskReference != "" {
+					errs = append(errs, r.newValidationError(r.tasksFile.Tasks[idx], j, errors.New("uses and task cannot both be set")))
+					continue
+				}
+				namespace, referencedTask, err := r.importUses(taskName, utils.TemplateString(r.variableConfig.GetSetVariables(), action.Uses), setVariables)
+				if err != nil {
+					errs = append(errs, r.newValidationError(r.tasksFile.Tasks[idx], j, err))
+					continue
+				}
+				action.TaskReference = joinTaskReference(namespace, referencedTask)
+				action.Uses = ""
+				r.tasksFile.Tasks[idx].Actions[j] = action
+			}
+			if action.TaskReference != "" && !strings.Contains(action.TaskReference, "${") {
+				taskNames = append(taskNames, action.TaskReference)
+			}
+		}
+	}
+
+	return errs
+}
+
+// resolveAllUses resolves the `uses` references of every loaded task, including the tasks of files that are imported
+// along the way
+func (r *Runner) resolveAllUses(setVariables map[string]string) []error {
+	var errs []error
+	resolved := 0
+	for resolved < len(r.tasksFile.Tasks) {
+		taskNames := []string{}
+		for _, task := range r.tasksFile.Tasks[resolved:] {
+			taskNames = append(taskNames, task.Name)
+		}
+		resolved = len(r.tasksFile.Tasks)
+		errs = append(errs, r.resolveUses(taskNames, setVariables)...)
+	}
+	return errs
+}
+
+// importUses imports the file of a `uses` reference made by the given task (if it was not already imported), returning
+// the namespace its tasks were imported under along with the name of the referenced task
+func (r *Runner) importUses(taskName, uses string, setVariables map[string]string) (string, string, error) {
+	ref, err := ParseUses(uses)
+	if err != nil {
+		return "", "", err
+	}
+
+	// file:// references are relative to the file the referencing task was defined in
+	currentFileLocation := config.TaskFileLocation
+	if location, ok := r.taskFileLocations[taskName]; ok {
+		currentFileLocation = location
+	}
+	absLocation, err := includeTaskAbsLocation(currentFileLocation, ref.Location)
+	if err != nil {
+		return "", "", err
+	}
+
+	if r.usesNamespaces == nil {
+		r.usesNamespaces = map[string]string{}
+	}
+	namespace, ok := r.usesNamespaces[absLocation]
+	if !ok {
+		namespace = r.newUsesNamespace(absLocation)
+		r.usesNamespaces[absLocation] = namespace
+		if err := r.importTasks([]map[string]string{{namespace: ref.includeLocation()}}, currentFileLocation, setVariables); err != nil {
+			return "", "", err
+		}
+	}
+	if _, err := r.getTask(joinTaskReference(namespace, ref.Task)); err != nil {
+		return "", "", fmt.Errorf("task %s not found in %s", ref.Task, ref.Location)
+	}
+	return namespace, ref.Task, nil
+}
+
+// newUsesNamespace returns an unused namespace for the tasks of a file imported through `uses`
+func (r *Runner) newUsesNamespace(absLocation string) string {
+	// Namespaces can't contain colons as they separate the namespace from the task
+	base := strings.ReplaceAll(path.Base(strings.ReplaceAll(absLocation, "\\", "/")), ":", "-")
+
+	taken := func(namespace string) bool {
+		if _, ok := r.existingTaskIncludeNameLocation[namespace]; ok {
+			return true
+		}
+		for _, include := range r.tasksFile.Includes {
+			if _, ok := include[namespace]; ok {
+				return true
+			}
+		}
+		for _, existing := range r.usesNamespaces {
+			if existing == namespace {
+				return true
+			}
+		}
+		return false
+	}
+
+	namespace := base
+	for n := 2; taken(namespace); n++ {
+		namespace = fmt.Sprintf("%s-%d", base, n)
+	}
+	return namespace
+}
@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUses(t *testing.T) {
+	digest := "sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		uses string
+		want UsesReference
+		err  string
+	}{
+		{uses: "file://./build.yaml:compile", want: UsesReference{Location: "./build.yaml", Task: "compile"}},
+		{uses: "file:///abs/build.yaml:compile", want: UsesReference{Location: "/abs/build.yaml", Task: "compile"}},
+		{uses: "https://example.com:8443/tasks.yaml:deploy", want: UsesReference{Location: "https://example.com:8443/tasks.yaml", Task: "deploy"}},
+		{uses: "https://example.com/tasks.yaml:deploy@" + digest, want: UsesReference{Location: "https://example.com/tasks.yaml", Task: "deploy", Digest: digest}},
+		{uses: "build.yaml:compile", err: `uses "build.yaml:compile" must start with one of file://, http://, https://`},
+		{uses: "oci://ghcr.io/tasks:deploy", err: `uses "oci://ghcr.io/tasks:deploy" has an unsupported scheme "oci"`},
+		{uses: "https://example.com/tasks.yaml:deploy@v2", err: `uses "https://example.com/tasks.yaml:deploy@v2" pins task deploy to version "v2", but only digests can be pinned`},
+		{uses: "file://./build.yaml", err: "is missing a task"},
+		{uses: "https://example.com:8443/tasks.yaml", err: "is missing a task"},
+		{uses: "file://./build.yaml:", err: "is missing a task"},
+		{uses: "file://:compile", err: "is missing a file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uses, func(t *testing.T) {
+			ref, err := ParseUses(tt.uses)
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, ref)
+		})
+	}
+}
+
+func Test_splitTaskReference(t *testing.T) {
+	include, task := splitTaskReference("build.yaml:compile")
+	require.Equal(t, "build.yaml", include)
+	require.Equal(t, "compile", task)
+	require.Equal(t, "build.yaml:compile", joinTaskReference(include, task))
+
+	include, task = splitTaskReference("compile")
+	require.Empty(t, include)
+	require.Equal(t, "compile", task)
+	require.Equal(t, "compile", joinTaskReference(include, task))
+}
+
+func TestRunner_resolveUses(t *testing.T) {
+	config.TaskFileLocation = "../../test/tasks/uses/tasks.yaml"
+	t.Cleanup(func() { config.TaskFileLocation = "" })
+
+	var tasksFile types.TasksFile
+	require.NoError(t, utils.ReadYaml(config.TaskFileLocation, &tasksFile))
+	r := &Runner{
+		tasksFile:                       tasksFile,
+		existingTaskIncludeNameLocation: map[string]string{},
+		taskFileLocations:               map[string]string{},
+		variableConfig:                  GetMaruVariableConfig(),
+	}
+
+	// Only the tasks reachable from the given tasks are resolved
+	require.Empty(t, r.resolveUses([]string{"default"}, nil))
+
+	task, err := r.getTask("default")
+	require.NoError(t, err)
+	// build.yaml is already the name of an include so the namespace is suffixed
+	require.Equal(t, "build.yaml-2:compile", task.Actions[0].TaskReference)
+	require.Equal(t, "build.yaml-2:package", task.Actions[1].TaskReference)
+	require.Empty(t, task.Actions[0].Uses)
+
+	// References are relative to the file they are made in
+	task, err = r.getTask("build.yaml-2:package")
+	require.NoError(t, err)
+	require.Equal(t, "deploy.yaml:upload", task.Actions[1].TaskReference)
+	_, err = r.getTask("deploy.yaml:upload")
+	require.NoError(t, err)
+
+	errs := r.resolveAllUses(nil)
+	require.Len(t, errs, 1)
+	require.EqualError(t, errs[0], `../../test/tasks/uses/tasks.yaml: task "missing": actions[0]: task nope not found in ./lib/build.yaml`)
+}
@@ -0,0 +1,309 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/maru-runner/src/types"
+)
+
+// ValidationError describes a single problem found while statically validating the task references in a tasks file
+type ValidationError struct {
+	File   string
+	Task   string
+	Action int
+	Err    error
+}
+
+// Error returns the problem in a compiler-like "file: task: action: problem" format
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: task %q: actions[%d]: %s", e.File, e.Task, e.Action, e.Err.Error())
+}
+
+// Unwrap returns the underlying problem
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// TaskNotFoundError is returned for a reference to a task that does not exist
+type TaskNotFoundError struct {
+	Name string
+}
+
+// Error implements the error interface
+func (e *TaskNotFoundError) Error() string {
+	return fmt.Sprintf("task name %s not found", e.Name)
+}
+
+// UnusedIncludes controls how Validate treats includes that are never referenced by any task
+type UnusedIncludes string
+
+const (
+	// UnusedIncludesWarn logs a warning for each unused include
+	UnusedIncludesWarn UnusedIncludes = "warn"
+	// UnusedIncludesError reports each unused include as a problem
+	UnusedIncludesError UnusedIncludes = "error"
+	// UnusedIncludesIgnore does not check for unused includes
+	UnusedIncludesIgnore UnusedIncludes = "ignore"
+)
+
+// Validate loads a tasks file along with all of its includes and checks that every task reference resolves and that
+// every `with` key matches a declared input of the referenced task, returning all problems found (joined). Includes
+// that no task references are warned about, reported or ignored depending on unusedIncludes.
+func Validate(tasksFile types.TasksFile, setVariables map[string]string, auth map[string]string, unusedIncludes UnusedIncludes) error {
+	switch unusedIncludes {
+	case UnusedIncludesWarn, UnusedIncludesError, UnusedIncludesIgnore:
+	case "":
+		unusedIncludes = UnusedIncludesWarn
+	default:
+		return fmt.Errorf("unknown unused includes behavior %q (must be one of warn, error or ignore)", unusedIncludes)
+	}
+
+	// Tasks with bad names are still checked so every problem is reported at once
+	errs := splitErrors(validateTaskNames(config.TaskFileLocation, tasksFile))
+
+	variableConfig := GetMaruVariableConfig()
+	if err := variableConfig.PopulateVariables(tasksFile.Variables, setVariables); err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+
+	runner := Runner{
+		tasksFile:                       tasksFile,
+		existingTaskIncludeNameLocation: map[string]string{},
+		taskFileLocations:               map[string]string{},
+		auth:                            auth,
+		variableConfig:                  variableConfig,
+		dryRun:                          true,
+	}
+
+	// Broken includes are reported along with the problems in the tasks that could be loaded (references to the tasks of
+	// a broken include are left out since they would only repeat that the include is broken)
+	importErrs := splitErrors(runner.importTasks(tasksFile.Includes, config.TaskFileLocation, setVariables))
+	errs = append(errs, importErrs...)
+	for _, err := range runner.validate(setVariables) {
+		if len(importErrs) > 0 && runner.refersToBrokenInclude(err) {
+			continue
+		}
+		errs = append(errs, err)
+	}
+
+	if unusedIncludes != UnusedIncludesIgnore {
+		for _, err := range runner.unusedIncludes() {
+			if unusedIncludes == UnusedIncludesError {
+				errs = append(errs, err)
+			} else {
+				message.SLog.Warn(err.Error())
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validate checks the variables and task references of a tasks file whose includes have been imported, returning every
+// problem found
+func (r *Runner) validate(setVariables map[string]string) []error {
+	var errs []error
+	for _, v := range r.tasksFile.Variables {
+		if _, err := ParseK8sReference(v.Extra.FromK8s); v.Extra.FromK8s != "" && err != nil {
+			errs = append(errs, fmt.Errorf("%s: variable %s: %w", config.TaskFileLocation, v.Name, err))
+		}
+	}
+	errs = append(errs, r.resolveAllUses(setVariables)...)
+	errs = append(errs, r.validateTasks(r.tasksFile.Tasks, true)...)
+	return errs
+}
+
+// refersToBrokenInclude returns whether a problem is a reference to a task of an include that could not be imported
+func (r *Runner) refersToBrokenInclude(err error) bool {
+	var notFound *TaskNotFoundError
+	if !errors.As(err, &notFound) {
+		return false
+	}
+	namespace, _ := splitTaskReference(notFound.Name)
+	if namespace == "" {
+		return false
+	}
+	_, imported := r.existingTaskIncludeNameLocation[namespace]
+	return !imported
+}
+
+// validateTaskNames checks that every task in a tasks file has a name that can be referenced, returning a problem
+// (joined) for each one that cannot. Task names cannot be empty, contain colons (which separate an include's name from
+// its task) or whitespace, or start with a dash (which would be read as a flag on the command line).
+func validateTaskNames(location string, tasksFile types.TasksFile) error {
+	var errs []error
+	for idx, task := range tasksFile.Tasks {
+		var problem string
+		switch {
+		case task.Name == "":
+			problem = "is missing a name"
+		case strings.Contains(task.Name, ":"):
+			problem = "cannot contain a colon (colons separate the name of an include from its task)"
+		case strings.IndexFunc(task.Name, unicode.IsSpace) >= 0:
+			problem = "cannot contain whitespace"
+		case strings.HasPrefix(task.Name, "-"):
+			problem = "cannot start with a dash"
+		default:
+			continue
+		}
+		errs = append(errs, fmt.Errorf("%s: tasks[%d]: task name %q %s", location, idx, task.Name, problem))
+	}
+	return errors.Join(errs...)
+}
+
+// unusedIncludes returns a problem for each include (at any depth) whose namespace no task references
+func (r *Runner) unusedIncludes() []error {
+	used := map[string]bool{}
+	for _, task := range r.tasksFile.Tasks {
+		for _, action := range task.Actions {
+			namespace, _ := splitTaskReference(action.TaskReference)
+			if namespace == "" {
+				continue
+			}
+			// a templated namespace could refer to any include so they can't be reported as unused
+			if strings.Contains(namespace, "${") {
+				return nil
+			}
+			used[namespace] = true
+		}
+	}
+
+	unused := []string{}
+	for name := range r.existingTaskIncludeNameLocation {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	slices.Sort(unused)
+
+	errs := []error{}
+	for _, name := range unused {
+		// report the problem against the file that declares the include, which for nested includes is an included file
+		location, ok := r.includeDeclaringLocations[name]
+		if !ok {
+			location = config.TaskFileLocation
+		}
+		errs = append(errs, fmt.Errorf("%s: include %q (%s) is never referenced by any task", location, name, r.existingTaskIncludeNameLocation[name]))
+	}
+	return errs
+}
+
+// validateTasks statically checks the task references in the given tasks and every task they (transitively) reference.
+// When strict is false, only problems that would certainly fail at runtime are reported (unknown `with` keys are left
+// to the runtime warning and conditional actions are skipped since they may never run).
+func (r *Runner) validateTasks(tasks []types.Task, strict bool) []error {
+	var errs []error
+	visited := map[string]bool{}
+
+	for len(tasks) > 0 {
+		task := tasks[0]
+		tasks = tasks[1:]
+		if visited[task.Name] {
+			continue
+		}
+		visited[task.Name] = true
+
+		for idx, action := range task.Actions {
+			// templated references can only be resolved at runtime
+			if action.TaskReference == "" || strings.Contains(action.TaskReference, "${") {
+				continue
+			}
+
+			referencedTask, err := r.getTask(action.TaskReference)
+			if err != nil {
+				errs = append(errs, r.newValidationError(task, idx, err))
+				continue
+			}
+			tasks = append(tasks, referencedTask)
+
+			if !strict && action.If != "" {
+				continue
+			}
+			for _, err := range checkTaskCall(referencedTask, action.With, strict) {
+				errs = append(errs, r.newValidationError(task, idx, err))
+			}
+		}
+	}
+
+	return errs
+}
+
+// checkTaskCall checks that the given withs satisfy the required inputs of a task (and optionally that they are all declared)
+func checkTaskCall(task types.Task, withs map[string]string, strict bool) []error {
+	var errs []error
+
+	if missing := missingInputs(task.Inputs, withs); len(missing) > 0 {
+		errs = append(errs, fmt.Errorf("task %s is missing required inputs: %s", task.Name, strings.Join(missing, ", ")))
+	}
+
+	if strict {
+		unknown := []string{}
+		for withKey := range withs {
+			if _, ok := task.Inputs[withKey]; !ok {
+				unknown = append(unknown, withKey)
+			}
+		}
+		slices.Sort(unknown)
+		for _, withKey := range unknown {
+			errs = append(errs, fmt.Errorf("task %s does not have an input named %s", task.Name, withKey))
+		}
+	}
+
+	return errs
+}
+
+// splitErrors returns the problems joined into an error (or just the error if it is a single problem) so problems from
+// several checks can be joined into one flat report
+func splitErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs := []error{}
+		for _, e := range joined.Unwrap() {
+			errs = append(errs, splitErrors(e)...)
+		}
+		return errs
+	}
+	return []error{err}
+}
+
+// uniqueErrors drops problems that repeat an earlier problem (i.e. a broken include that several tasks reference)
+func uniqueErrors(errs []error) []error {
+	seen := map[string]bool{}
+	unique := []error{}
+	for _, err := range errs {
+		if !seen[err.Error()] {
+			seen[err.Error()] = true
+			unique = append(unique, err)
+		}
+	}
+	return unique
+}
+
+func (r *Runner) newValidationError(task types.Task, action int, err error) error {
+	return &ValidationError{
+		File:   r.taskFileLocation(task.Name),
+		Task:   task.Name,
+		Action: action,
+		Err:    err,
+	}
+}
+
+// taskFileLocation returns the file a task was loaded from
+func (r *Runner) taskFileLocation(taskName string) string {
+	if location, ok := r.taskFileLocations[taskName]; ok {
+		return location
+	}
+	return config.TaskFileLocation
+}
@@ -0,0 +1,204 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_validateTasks(t *testing.T) {
+	tasks := []types.Task{
+		{
+			Name: "entry",
+			Actions: []types.Action{
+				{TaskReference: "needs-input"},
+				{TaskReference: "needs-input", With: map[string]string{"required": "a", "extra": "b"}},
+				{TaskReference: "missing"},
+				{TaskReference: "needs-input", If: "${{ eq .variables.FOO \"bar\" }}"},
+				{TaskReference: "${{ .inputs.task }}"},
+			},
+		},
+		{
+			Name: "needs-input",
+			Inputs: map[string]types.InputParameter{
+				"required":     {Required: true},
+				"has-default":  {Required: true, Default: "default"},
+				"not-required": {},
+			},
+		},
+	}
+	r := &Runner{tasksFile: types.TasksFile{Tasks: tasks}}
+
+	config.TaskFileLocation = "tasks.yaml"
+	t.Cleanup(func() { config.TaskFileLocation = "" })
+
+	strictErrs := r.validateTasks(tasks[:1], true)
+	require.Len(t, strictErrs, 4)
+	require.EqualError(t, strictErrs[0], `tasks.yaml: task "entry": actions[0]: task needs-input is missing required inputs: required`)
+	require.EqualError(t, strictErrs[1], `tasks.yaml: task "entry": actions[1]: task needs-input does not have an input named extra`)
+	require.EqualError(t, strictErrs[2], `tasks.yaml: task "entry": actions[2]: task name missing not found`)
+	require.EqualError(t, strictErrs[3], `tasks.yaml: task "entry": actions[3]: task needs-input is missing required inputs: required`)
+
+	var validationErr *ValidationError
+	require.True(t, errors.As(strictErrs[0], &validationErr))
+	require.Equal(t, 0, validationErr.Action)
+
+	// Runtime validation ignores unknown inputs and conditional actions
+	errs := r.validateTasks(tasks[:1], false)
+	require.Len(t, errs, 2)
+	require.EqualError(t, errs[0], strictErrs[0].Error())
+	require.EqualError(t, errs[1], strictErrs[2].Error())
+}
+
+func TestCheckTaskCall_emptyWith(t *testing.T) {
+	task := types.Task{Name: "needs-input", Inputs: map[string]types.InputParameter{"required": {Required: true}}}
+	withs := map[string]string{"required": ""}
+
+	// Static validation applies the same rule as the check made when the task is called
+	errs := checkTaskCall(task, withs, true)
+	require.Len(t, errs, 1)
+	require.EqualError(t, errs[0], validateActionableTaskCall(task.Name, task.Inputs, withs).Error())
+}
+
+func TestRunner_unusedIncludes(t *testing.T) {
+	r := &Runner{
+		tasksFile: types.TasksFile{Tasks: []types.Task{
+			{Name: "default", Actions: []types.Action{{TaskReference: "used:build"}, {TaskReference: "local"}}},
+			{Name: "used:build", Actions: []types.Action{{TaskReference: "nested:build"}}},
+		}},
+		existingTaskIncludeNameLocation: map[string]string{
+			"used":   "used.yaml",
+			"nested": "nested.yaml",
+			"unused": "unused.yaml",
+		},
+	}
+
+	config.TaskFileLocation = "tasks.yaml"
+	t.Cleanup(func() { config.TaskFileLocation = "" })
+
+	errs := r.unusedIncludes()
+	require.Len(t, errs, 1)
+	require.EqualError(t, errs[0], `tasks.yaml: include "unused" (unused.yaml) is never referenced by any task`)
+
+	// A templated namespace could reference any include
+	r.tasksFile.Tasks[0].Actions[1].TaskReference = "${{ .inputs.include }}:build"
+	require.Empty(t, r.unusedIncludes())
+}
+
+func TestValidate_nestedUnusedInclude(t *testing.T) {
+	dir := t.TempDir()
+	config.TaskFileLocation = filepath.Join(dir, "tasks.yaml")
+	t.Cleanup(func() { config.TaskFileLocation = "" })
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib.yaml"), []byte("includes:\n  - helpers: ./helpers.yaml\ntasks:\n  - name: build\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "helpers.yaml"), []byte("tasks:\n  - name: help\n"), 0600))
+
+	tasksFile := types.TasksFile{
+		Includes: []map[string]string{{"lib": "./lib.yaml"}},
+		Tasks:    []types.Task{{Name: "default", Actions: []types.Action{{TaskReference: "lib:build"}}}},
+	}
+
+	// The unused nested include is reported against the included file that declares it
+	err := Validate(tasksFile, nil, nil, UnusedIncludesError)
+	problems := splitErrors(err)
+	require.Len(t, problems, 1, err)
+	libLocation, err := filepath.Abs(filepath.Join(dir, "lib.yaml"))
+	require.NoError(t, err)
+	helpersLocation, err := filepath.Abs(filepath.Join(dir, "helpers.yaml"))
+	require.NoError(t, err)
+	require.EqualError(t, problems[0], fmt.Sprintf("%s: include %q (%s) is never referenced by any task", libLocation, "helpers", helpersLocation))
+}
+
+func Test_validateTaskNames(t *testing.T) {
+	tasksFile := types.TasksFile{Tasks: []types.Task{
+		{Name: "build"},
+		{Name: "lib:build"},
+		{Name: "build all"},
+		{Name: "-build"},
+		{Name: ""},
+		{Name: "build-all_2.0"},
+	}}
+
+	err := validateTaskNames("tasks.yaml", tasksFile)
+	require.EqualError(t, err, strings.Join([]string{
+		`tasks.yaml: tasks[1]: task name "lib:build" cannot contain a colon (colons separate the name of an include from its task)`,
+		`tasks.yaml: tasks[2]: task name "build all" cannot contain whitespace`,
+		`tasks.yaml: tasks[3]: task name "-build" cannot start with a dash`,
+		`tasks.yaml: tasks[4]: task name "" is missing a name`,
+	}, "\n"))
+
+	require.NoError(t, validateTaskNames("tasks.yaml", types.TasksFile{Tasks: tasksFile.Tasks[:1]}))
+}
+
+func TestValidate_everyProblem(t *testing.T) {
+	dir := t.TempDir()
+	config.TaskFileLocation = filepath.Join(dir, "tasks.yaml")
+	t.Cleanup(func() { config.TaskFileLocation = "" })
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib.yaml"), []byte("tasks:\n  - name: build\n"), 0600))
+
+	tasksFile := types.TasksFile{
+		Includes: []map[string]string{
+			{"lib": "./lib.yaml"},
+			{"broken": "./missing.yaml"},
+			{"also-broken": "./also-missing.yaml"},
+		},
+		Tasks: []types.Task{
+			{Name: "bad name", Actions: []types.Action{{TaskReference: "lib:build"}}},
+			{Name: "default", Actions: []types.Action{
+				{TaskReference: "broken:build"},
+				{TaskReference: "also-broken:build"},
+				{TaskReference: "lib:nope"},
+				{TaskReference: "needs-input"},
+			}},
+			{Name: "needs-input", Inputs: map[string]types.InputParameter{"b": {Required: true}, "a": {Required: true}}},
+		},
+	}
+
+	// Every problem is reported in one pass (but not the references into the includes that cannot be read)
+	err := Validate(tasksFile, nil, nil, UnusedIncludesIgnore)
+	problems := splitErrors(err)
+	require.Len(t, problems, 5, err)
+	require.Contains(t, problems[0].Error(), `task name "bad name" cannot contain whitespace`)
+	require.Contains(t, problems[1].Error(), "unable to read included file")
+	require.Contains(t, problems[1].Error(), "missing.yaml")
+	require.Contains(t, problems[2].Error(), "also-missing.yaml")
+	require.EqualError(t, problems[3], config.TaskFileLocation+`: task "default": actions[2]: task name lib:nope not found`)
+	require.EqualError(t, problems[4], config.TaskFileLocation+`: task "default": actions[3]: task needs-input is missing required inputs: a, b`)
+}
+
+func TestRun_everyProblem(t *testing.T) {
+	config.TaskFileLocation = filepath.Join(t.TempDir(), "tasks.yaml")
+	t.Cleanup(func() { config.TaskFileLocation = "" })
+
+	marker := filepath.Join(t.TempDir(), "ran")
+	tasksFile := types.TasksFile{
+		Tasks: []types.Task{
+			{Name: "default", Actions: []types.Action{
+				{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: "touch " + marker}},
+				{TaskReference: "missing"},
+				{TaskReference: "needs-input"},
+			}},
+			{Name: "-flag"},
+			{Name: "needs-input", Inputs: map[string]types.InputParameter{"a": {Required: true}}},
+		},
+	}
+
+	err := RunWithContext(context.Background(), tasksFile, "default", nil, false, nil)
+	problems := splitErrors(err)
+	require.Len(t, problems, 3, err)
+	require.Contains(t, problems[0].Error(), `task name "-flag" cannot start with a dash`)
+	require.Contains(t, problems[1].Error(), "task name missing not found")
+	require.Contains(t, problems[2].Error(), "task needs-input is missing required inputs: a")
+	require.NoFileExists(t, marker)
+}
@@ -0,0 +1,301 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package runner provides functions for running tasks in a tasks.yaml
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	goyaml "github.com/goccy/go-yaml"
+)
+
+// VariableKind is whether a VariableUsage describes a runner variable or a task input
+type VariableKind string
+
+const (
+	// VariableKindVariable is a runner variable (shared by every task in the run)
+	VariableKindVariable VariableKind = "variable"
+	// VariableKindInput is an input of a single task
+	VariableKindInput VariableKind = "input"
+)
+
+// SetOnCommandLine is the location recorded for variables given with --set (or a MARU_ environment variable)
+const SetOnCommandLine = "--set"
+
+var (
+	envReferencePattern      = regexp.MustCompile(`\$\{(\w+)\}`)
+	shellReferencePattern    = regexp.MustCompile(`\$(\w+)`)
+	variableReferencePattern = regexp.MustCompile(`\.variables\.(\w+)|index\s+\.variables\s+"([^"]+)"`)
+	inputReferencePattern    = regexp.MustCompile(`\.inputs\.(\w+)|index\s+\.inputs\s+"([^"]+)"`)
+)
+
+// VariableUsage describes where a variable (or task input) is defined, defaulted, set and consumed in a task tree.
+// Locations are a file for definitions at the top of a tasks file, a task name for input declarations, a task name and
+// action index (i.e. build[2]) for actions, or SetOnCommandLine.
+type VariableUsage struct {
+	Name      string
+	Kind      VariableKind
+	Task      string
+	Defined   []string
+	Defaulted []string
+	Set       []string
+	Consumed  []string
+}
+
+// Unused returns whether the variable is never consumed
+func (u VariableUsage) Unused() bool {
+	return len(u.Consumed) == 0
+}
+
+// Undefined returns whether the variable is never defined (inputs must be declared by their task, while variables can
+// also be defined by setting them)
+func (u VariableUsage) Undefined() bool {
+	if u.Kind == VariableKindInput {
+		return len(u.Defined) == 0
+	}
+	return len(u.Defined) == 0 && len(u.Set) == 0
+}
+
+// AnalyzeVariables loads a tasks file along with all of its includes and reports where every variable and task input
+// is defined, defaulted, set and consumed (without running anything). Variables are returned sorted by name followed
+// by inputs sorted by task and then name.
+func AnalyzeVariables(tasksFile types.TasksFile, setVariables map[string]string, auth map[string]string) ([]VariableUsage, error) {
+	if err := validateTaskNames(config.TaskFileLocation, tasksFile); err != nil {
+		return nil, err
+	}
+
+	variableConfig := GetMaruVariableConfig()
+	if err := variableConfig.PopulateVariables(tasksFile.Variables, setVariables); err != nil {
+		return nil, err
+	}
+
+	runner := Runner{
+		tasksFile:                       tasksFile,
+		existingTaskIncludeNameLocation: map[string]string{},
+		taskFileLocations:               map[string]string{},
+		includedTasksFiles:              map[string]types.TasksFile{},
+		auth:                            auth,
+		variableConfig:                  variableConfig,
+		dryRun:                          true,
+	}
+
+	if err := runner.importTasks(tasksFile.Includes, config.TaskFileLocation, setVariables); err != nil {
+		return nil, err
+	}
+	if errs := runner.resolveAllUses(setVariables); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return runner.analyzeVariables(setVariables), nil
+}
+
+// variableUsages collects the usages of variables and inputs as a task tree is walked
+type variableUsages struct {
+	variables map[string]*VariableUsage
+	inputs    map[string]*VariableUsage
+}
+
+func (u *variableUsages) variable(name string) *VariableUsage {
+	if _, ok := u.variables[name]; !ok {
+		u.variables[name] = &VariableUsage{Name: name, Kind: VariableKindVariable}
+	}
+	return u.variables[name]
+}
+
+func (u *variableUsages) input(task, name string) *VariableUsage {
+	key := task + "\x00" + name
+	if _, ok := u.inputs[key]; !ok {
+		u.inputs[key] = &VariableUsage{Name: name, Kind: VariableKindInput, Task: task}
+	}
+	return u.inputs[key]
+}
+
+func (r *Runner) analyzeVariables(setVariables map[string]string) []VariableUsage {
+	usages := variableUsages{variables: map[string]*VariableUsage{}, inputs: map[string]*VariableUsage{}}
+
+	// Variables at the top of the root and included files
+	locations := []string{config.TaskFileLocation}
+	for location := range r.includedTasksFiles {
+		locations = append(locations, location)
+	}
+	slices.Sort(locations[1:])
+	files := map[string]types.TasksFile{config.TaskFileLocation: r.tasksFile}
+	for location, tasksFile := range r.includedTasksFiles {
+		files[location] = tasksFile
+	}
+	for _, location := range locations {
+		for _, v := range files[location].Variables {
+			usage := usages.variable(v.Name)
+			usage.Defined = append(usage.Defined, relativeLocation(location))
+			if v.Default != "" {
+				usage.Defaulted = append(usage.Defaulted, relativeLocation(location))
+			}
+		}
+	}
+
+	for name := range setVariables {
+		usage := usages.variable(name)
+		usage.Set = append(usage.Set, SetOnCommandLine)
+	}
+
+	// Inputs declared by tasks, and variables and inputs set by actions
+	for _, task := range r.tasksFile.Tasks {
+		for name, input := range task.Inputs {
+			usage := usages.input(task.Name, name)
+			usage.Defined = append(usage.Defined, task.Name)
+			if input.Default != "" {
+				usage.Defaulted = append(usage.Defaulted, task.Name)
+			}
+		}
+
+		for idx, action := range task.Actions {
+			location := fmt.Sprintf("%s[%d]", task.Name, idx)
+			if action.BaseAction != nil {
+				for _, v := range action.SetVariables {
+					usage := usages.variable(v.Name)
+					usage.Set = append(usage.Set, location)
+				}
+			}
+			if action.TaskReference != "" && !strings.Contains(action.TaskReference, "${") {
+				if referencedTask, err := r.getTask(action.TaskReference); err == nil {
+					for name := range action.With {
+						usage := usages.input(referencedTask.Name, name)
+						usage.Set = append(usage.Set, location)
+					}
+				}
+			}
+		}
+	}
+
+	// Templated include locations consume variables
+	for _, location := range locations {
+		for _, include := range files[location].Includes {
+			for _, includeLocation := range include {
+				for _, match := range envReferencePattern.FindAllStringSubmatch(includeLocation, -1) {
+					usages.consumeVariable(match[1], relativeLocation(location), true)
+				}
+			}
+		}
+	}
+
+	// Actions consume variables and inputs through templates, ${NAME} references and the environment of commands
+	for _, task := range r.tasksFile.Tasks {
+		inputEnvNames := map[string]string{}
+		for name := range task.Inputs {
+			inputEnvNames[strings.TrimSuffix(utils.FormatEnvVar(name, ""), "=")] = name
+		}
+
+		for idx, action := range task.Actions {
+			location := fmt.Sprintf("%s[%d]", task.Name, idx)
+
+			b, err := goyaml.Marshal(action)
+			if err != nil {
+				continue
+			}
+			text := string(b)
+
+			for _, match := range envReferencePattern.FindAllStringSubmatch(text, -1) {
+				if input, ok := inputEnvNames[match[1]]; ok {
+					usages.consumeInput(task.Name, input, location)
+					continue
+				}
+				usages.consumeVariable(match[1], location, true)
+			}
+			for _, match := range variableReferencePattern.FindAllStringSubmatch(text, -1) {
+				usages.consumeVariable(match[1]+match[2], location, false)
+			}
+			for _, match := range inputReferencePattern.FindAllStringSubmatch(text, -1) {
+				usages.consumeInput(task.Name, match[1]+match[2], location)
+			}
+
+			if action.BaseAction != nil {
+				for _, match := range shellReferencePattern.FindAllStringSubmatch(action.Cmd, -1) {
+					if input, ok := inputEnvNames[match[1]]; ok {
+						usages.consumeInput(task.Name, input, location)
+					} else if _, ok := usages.variables[match[1]]; ok {
+						usages.consumeVariable(match[1], location, false)
+					}
+				}
+			}
+		}
+	}
+
+	result := []VariableUsage{}
+	for _, usage := range usages.variables {
+		result = append(result, *usage)
+	}
+	slices.SortFunc(result, func(a, b VariableUsage) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	inputs := []VariableUsage{}
+	for _, usage := range usages.inputs {
+		inputs = append(inputs, *usage)
+	}
+	slices.SortFunc(inputs, func(a, b VariableUsage) int {
+		if c := strings.Compare(a.Task, b.Task); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return append(result, inputs...)
+}
+
+// consumeVariable records that a variable was consumed at a location. Environment style references (${NAME}) to names
+// that maru sets automatically or that are set in the environment (and are not maru variables) are ignored since they
+// are resolved by the shell rather than by maru.
+func (u *variableUsages) consumeVariable(name, location string, envReference bool) {
+	if _, known := u.variables[name]; !known && envReference {
+		if isAutomaticEnv(name) {
+			return
+		}
+		if _, ok := os.LookupEnv(name); ok {
+			return
+		}
+	}
+	usage := u.variable(name)
+	if !slices.Contains(usage.Consumed, location) {
+		usage.Consumed = append(usage.Consumed, location)
+	}
+}
+
+func (u *variableUsages) consumeInput(task, name, location string) {
+	usage := u.input(task, name)
+	if !slices.Contains(usage.Consumed, location) {
+		usage.Consumed = append(usage.Consumed, location)
+	}
+}
+
+// isAutomaticEnv returns whether an environment variable is set automatically by maru for every action
+func isAutomaticEnv(name string) bool {
+	if _, ok := config.GetExtraEnv()[name]; ok {
+		return true
+	}
+	return slices.Contains([]string{"MARU", "MARU_ARCH", RunIDEnv, TaskNameEnv, ActionIndexEnv, AttemptEnv, MaxAttemptsEnv, IdempotencyKeyEnv, OutputFileEnv, GeneratedTasksEnv}, name)
+}
+
+// relativeLocation shortens a tasks file location to be relative to the current directory when possible
+func relativeLocation(location string) string {
+	if !filepath.IsAbs(location) {
+		return location
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return location
+	}
+	if rel, err := filepath.Rel(wd, location); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return location
+}
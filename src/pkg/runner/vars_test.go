@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package runner
+
+import (
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_analyzeVariables(t *testing.T) {
+	cmd := func(cmd string, setVariables ...string) types.Action {
+		action := types.Action{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: cmd}}
+		for _, name := range setVariables {
+			action.SetVariables = append(action.SetVariables, variables.Variable[variables.ExtraVariableInfo]{Name: name})
+		}
+		return action
+	}
+
+	r := &Runner{
+		tasksFile: types.TasksFile{
+			Variables: []variables.InteractiveVariable[variables.ExtraVariableInfo]{
+				{Variable: variables.Variable[variables.ExtraVariableInfo]{Name: "DEFAULTED"}, Default: "value"},
+				{Variable: variables.Variable[variables.ExtraVariableInfo]{Name: "UNUSED"}},
+				{Variable: variables.Variable[variables.ExtraVariableInfo]{Name: "SHELL"}},
+			},
+			Tasks: []types.Task{
+				{
+					Name: "default",
+					Actions: []types.Action{
+						cmd("echo ${DEFAULTED} ${{ .variables.MISSING }} $MARU_ARCH", "OUTPUT"),
+						cmd("echo $SHELL ${OUTPUT} ${MARU_RUN_ID}"),
+						{TaskReference: "greet", With: map[string]string{"name": "maru", "extra": "value"}},
+					},
+				},
+				{
+					Name: "greet",
+					Inputs: map[string]types.InputParameter{
+						"name":     {Description: "who to greet"},
+						"greeting": {Description: "how to greet", Default: "hello"},
+						"unused":   {Description: "not used"},
+					},
+					Actions: []types.Action{
+						cmd("echo ${{ .inputs.greeting }} $INPUT_NAME"),
+					},
+				},
+			},
+		},
+	}
+
+	config.TaskFileLocation = "tasks.yaml"
+	t.Cleanup(func() { config.TaskFileLocation = "" })
+
+	usages := r.analyzeVariables(map[string]string{"CLI": "value"})
+	byName := map[string]VariableUsage{}
+	for _, usage := range usages {
+		byName[usage.Task+"/"+usage.Name] = usage
+	}
+	require.Len(t, byName, 10)
+
+	require.Equal(t, VariableUsage{Name: "DEFAULTED", Kind: VariableKindVariable, Defined: []string{"tasks.yaml"}, Defaulted: []string{"tasks.yaml"}, Consumed: []string{"default[0]"}}, byName["/DEFAULTED"])
+	require.Equal(t, []string{"default[1]"}, byName["/SHELL"].Consumed)
+	require.Equal(t, []string{"default[0]"}, byName["/OUTPUT"].Set)
+	require.Equal(t, []string{SetOnCommandLine}, byName["/CLI"].Set)
+	require.True(t, byName["/UNUSED"].Unused())
+	require.True(t, byName["/CLI"].Unused())
+	require.True(t, byName["/MISSING"].Undefined())
+	require.False(t, byName["/OUTPUT"].Undefined())
+
+	require.Equal(t, []string{"greet[0]"}, byName["greet/name"].Consumed)
+	require.Equal(t, []string{"default[2]"}, byName["greet/name"].Set)
+	require.Equal(t, []string{"greet"}, byName["greet/greeting"].Defaulted)
+	require.True(t, byName["greet/unused"].Unused())
+	require.True(t, byName["greet/extra"].Undefined())
+
+	// Variables are sorted by name before inputs sorted by task and name
+	require.Equal(t, "CLI", usages[0].Name)
+	require.Equal(t, VariableKindInput, usages[len(usages)-1].Kind)
+	require.Equal(t, "unused", usages[len(usages)-1].Name)
+}
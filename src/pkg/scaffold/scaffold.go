@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package scaffold creates the starting layout of new maru projects
+package scaffold
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/pkg/helpers/v2"
+)
+
+//go:embed all:templates
+var templates embed.FS
+
+// taskLibTemplate is the template directory for task libraries
+const taskLibTemplate = "templates/task-lib"
+
+// libraryNamePattern matches names that can be used as include names (and so as the name of a task library)
+var libraryNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// NewTaskLib creates the layout of a shared task library (its tasks, tests, docs, release workflow and lockfile) in
+// dir, returning the files that were created. It refuses to overwrite any existing files.
+func NewTaskLib(dir, name string) ([]string, error) {
+	if !libraryNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("task library name %q must be lowercase letters, numbers, dots, dashes and underscores", name)
+	}
+
+	replacer := strings.NewReplacer("__NAME__", name, "__MARU_VERSION__", maruVersion())
+
+	files := map[string][]byte{}
+	err := fs.WalkDir(templates, taskLibTemplate, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		contents, err := templates.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		rel := strings.TrimPrefix(file, taskLibTemplate+"/")
+		files[filepath.FromSlash(rel)] = []byte(replacer.Replace(string(contents)))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	existing := []string{}
+	for rel := range files {
+		names = append(names, rel)
+		if _, err := os.Stat(filepath.Join(dir, rel)); err == nil {
+			existing = append(existing, rel)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	if len(existing) > 0 {
+		slices.Sort(existing)
+		return nil, fmt.Errorf("refusing to overwrite existing files in %s: %s", dir, strings.Join(existing, ", "))
+	}
+
+	slices.Sort(names)
+	created := []string{}
+	for _, rel := range names {
+		target := filepath.Join(dir, rel)
+		if err := helpers.CreateDirectory(filepath.Dir(target), helpers.ReadWriteExecuteUser); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(target, files[rel], helpers.ReadWriteUser); err != nil {
+			return nil, err
+		}
+		created = append(created, target)
+	}
+	return created, nil
+}
+
+// maruVersion returns the released maru version that new projects should install (empty for development builds of
+// maru, in which case the latest release is installed)
+func maruVersion() string {
+	if strings.HasPrefix(config.CLIVersion, "v") {
+		return config.CLIVersion
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package scaffold
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/config/lang"
+	"github.com/defenseunicorns/maru-runner/src/pkg/utils"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTaskLib(t *testing.T) {
+	dir := t.TempDir()
+
+	created, err := NewTaskLib(dir, "platform-tasks")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, ".github", "workflows", "release.yaml"),
+		filepath.Join(dir, "README.md"),
+		filepath.Join(dir, "maru.lock.yaml"),
+		filepath.Join(dir, "tasks.yaml"),
+		filepath.Join(dir, "tests", "tasks.yaml"),
+	}, created)
+
+	var tasksFile types.TasksFile
+	require.NoError(t, utils.ReadYaml(filepath.Join(dir, "tasks.yaml"), &tasksFile))
+	require.Equal(t, "platform-tasks", tasksFile.Tasks[0].Metadata.Team)
+	require.NoError(t, utils.ReadYaml(filepath.Join(dir, "tests", "tasks.yaml"), &tasksFile))
+
+	readme, err := os.ReadFile(filepath.Join(dir, "README.md"))
+	require.NoError(t, err)
+	require.Contains(t, string(readme), "# platform-tasks")
+	require.NotContains(t, string(readme), "__NAME__")
+
+	var lock map[string]string
+	require.NoError(t, utils.ReadYaml(filepath.Join(dir, "maru.lock.yaml"), &lock))
+	require.Equal(t, map[string]string{"maruVersion": maruVersion()}, lock)
+
+	// Existing files are never overwritten
+	_, err = NewTaskLib(dir, "platform-tasks")
+	require.ErrorContains(t, err, "refusing to overwrite existing files in "+dir+": .github/workflows/release.yaml, README.md, maru.lock.yaml, tasks.yaml, tests/tasks.yaml")
+
+	_, err = NewTaskLib(t.TempDir(), "Platform Tasks")
+	require.ErrorContains(t, err, `task library name "Platform Tasks" must be`)
+}
+
+func Test_maruVersion(t *testing.T) {
+	version := config.CLIVersion
+	t.Cleanup(func() { config.CLIVersion = version })
+
+	config.CLIVersion = "v1.2.3"
+	require.Equal(t, "v1.2.3", maruVersion())
+	config.CLIVersion = "unset"
+	require.Empty(t, maruVersion())
+}
+
+func TestNewWrapper(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the wrapper script is a POSIX shell script")
+	}
+
+	binary := []byte("#!/bin/sh\necho \"pinned maru $*\"\n")
+	checksums := ""
+	for _, platform := range wrapperPlatforms {
+		checksums += fmt.Sprintf("%x  maru-runner_v1.2.3_%s\n", sha256.Sum256(binary), platform)
+	}
+	var downloads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1.2.3/checksums.txt":
+			_, _ = w.Write([]byte(checksums))
+		case strings.HasPrefix(r.URL.Path, "/v1.2.3/maru-runner_v1.2.3_"):
+			downloads.Add(1)
+			_, _ = w.Write(binary)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	created, err := NewWrapper(context.Background(), dir, "v1.2.3", server.URL+"/")
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, WrapperScript), filepath.Join(dir, WrapperProperties)}, created)
+
+	properties, err := os.ReadFile(filepath.Join(dir, WrapperProperties))
+	require.NoError(t, err)
+	require.Contains(t, string(properties), "MARU_VERSION=v1.2.3\nMARU_RELEASE_URL="+server.URL+"\n")
+	require.Contains(t, string(properties), fmt.Sprintf("MARU_SHA256_Linux_amd64=%x\n", sha256.Sum256(binary)))
+
+	// The pinned release is downloaded once and then run from the cache
+	t.Setenv("MARU_WRAPPER_HOME", t.TempDir())
+	for i := 0; i < 2; i++ {
+		out, err := exec.Command(filepath.Join(dir, WrapperScript), "run", "build").Output()
+		require.NoError(t, err)
+		require.Equal(t, "pinned maru run build\n", string(out))
+	}
+	require.Equal(t, int32(1), downloads.Load())
+
+	// A release that does not match its pinned checksum is never run
+	tampered := strings.ReplaceAll(string(properties), fmt.Sprintf("%x", sha256.Sum256(binary)), strings.Repeat("0", 64))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, WrapperProperties), []byte(tampered), 0600))
+	out, err := exec.Command(filepath.Join(dir, WrapperScript)).CombinedOutput()
+	require.Error(t, err)
+	require.Contains(t, string(out), "but .maru-wrapper pins "+strings.Repeat("0", 64))
+	require.NotContains(t, string(out), "pinned maru")
+
+	_, err = NewWrapper(context.Background(), dir, "v9.9.9", server.URL)
+	require.ErrorContains(t, err, "404 Not Found")
+	_, err = NewWrapper(context.Background(), dir, "v1.2.3; rm -rf /", server.URL)
+	require.ErrorContains(t, err, "is not a maru release version")
+	_, err = NewWrapper(context.Background(), dir, "v1.2.3", server.URL+"/$(whoami)")
+	require.ErrorContains(t, err, "is not a URL that maru releases can be downloaded from")
+
+	config.Offline = true
+	t.Cleanup(func() { config.Offline = false })
+	_, err = NewWrapper(context.Background(), dir, "v1.2.3", server.URL)
+	require.ErrorIs(t, err, lang.ErrOffline)
+}
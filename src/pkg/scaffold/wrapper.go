@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package scaffold creates the starting layout of new maru projects
+package scaffold
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/config/lang"
+	"github.com/defenseunicorns/pkg/helpers/v2"
+)
+
+const (
+	// DefaultReleaseURL is where maru releases are downloaded from by the wrapper
+	DefaultReleaseURL = "https://github.com/defenseunicorns/maru-runner/releases/download"
+	// WrapperScript is the name of the script that runs the pinned maru version
+	WrapperScript = "maruw"
+	// WrapperProperties is the name of the file that pins the maru version run by the wrapper
+	WrapperProperties = ".maru-wrapper"
+
+	// wrapperTemplate is the template of the wrapper script
+	wrapperTemplate = "templates/wrapper/maruw"
+	// checksumsTimeout is how long to wait for the checksums of a release
+	checksumsTimeout = 30 * time.Second
+)
+
+// wrapperPlatforms are the platforms maru is released for (as they are named in release artifacts)
+var wrapperPlatforms = []string{"Darwin_amd64", "Darwin_arm64", "Linux_amd64", "Linux_arm64"}
+
+var (
+	// versionPattern matches the release tags that can be pinned (these end up in a file that is sourced by a shell)
+	versionPattern = regexp.MustCompile(`^v?[0-9]+\.[0-9]+\.[0-9]+[0-9A-Za-z.+-]*$`)
+	// releaseURLPattern matches the release URLs that can be pinned (without characters a shell would interpret)
+	releaseURLPattern = regexp.MustCompile("^https?://[^\\s'\"`$;&|<>()\\\\]+$")
+	// checksumPattern matches a sha256 checksum
+	checksumPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// NewWrapper creates (or updates) the wrapper script in dir along with the file pinning the given maru version (the
+// version of this maru if empty) and the checksums of its release artifacts, which are fetched from releaseURL. It
+// returns the files that were written.
+func NewWrapper(ctx context.Context, dir, version, releaseURL string) ([]string, error) {
+	if version == "" {
+		version = maruVersion()
+	}
+	if version == "" {
+		return nil, errors.New("this is a development build of maru so the version to pin is required (i.e. --version v0.1.0)")
+	}
+	if !versionPattern.MatchString(version) {
+		return nil, fmt.Errorf("%q is not a maru release version (i.e. v0.1.0)", version)
+	}
+	releaseURL = strings.TrimSuffix(releaseURL, "/")
+	if !releaseURLPattern.MatchString(releaseURL) {
+		return nil, fmt.Errorf("%q is not a URL that maru releases can be downloaded from", releaseURL)
+	}
+
+	checksums, err := fetchChecksums(ctx, fmt.Sprintf("%s/%s/checksums.txt", releaseURL, version))
+	if err != nil {
+		return nil, err
+	}
+
+	var properties strings.Builder
+	fmt.Fprintf(&properties, "# The maru version run by ./%s (update it with 'maru new wrapper --version <version>')\n", WrapperScript)
+	fmt.Fprintf(&properties, "MARU_VERSION=%s\n", version)
+	fmt.Fprintf(&properties, "MARU_RELEASE_URL=%s\n", releaseURL)
+	for _, platform := range wrapperPlatforms {
+		artifact := fmt.Sprintf("maru-runner_%s_%s", version, platform)
+		checksum, ok := checksums[artifact]
+		if !ok {
+			return nil, fmt.Errorf("the checksums of maru %s do not include %s", version, artifact)
+		}
+		fmt.Fprintf(&properties, "MARU_SHA256_%s=%s\n", platform, checksum)
+	}
+
+	script, err := templates.ReadFile(wrapperTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := helpers.CreateDirectory(dir, helpers.ReadWriteExecuteUser); err != nil {
+		return nil, err
+	}
+	scriptPath := filepath.Join(dir, WrapperScript)
+	propertiesPath := filepath.Join(dir, WrapperProperties)
+	if err := os.WriteFile(scriptPath, script, helpers.ReadExecuteAllWriteUser); err != nil {
+		return nil, err
+	}
+	// WriteFile keeps the mode of an existing file, so make sure an older copy of the script can still be run
+	if err := os.Chmod(scriptPath, helpers.ReadExecuteAllWriteUser); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(propertiesPath, []byte(properties.String()), helpers.ReadAllWriteUser); err != nil {
+		return nil, err
+	}
+	return []string{scriptPath, propertiesPath}, nil
+}
+
+// fetchChecksums fetches the checksums.txt of a release, returning the sha256 checksum of each artifact by name
+func fetchChecksums(ctx context.Context, location string) (map[string]string, error) {
+	if config.Offline {
+		return nil, fmt.Errorf("unable to fetch %s: %w", location, lang.ErrOffline)
+	}
+	ctx, cancel := context.WithTimeout(ctx, checksumsTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize request for %s: %w", location, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to make request for %s: %w", location, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed getting %s: %s", location, resp.Status)
+	}
+
+	checksums := map[string]string{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && checksumPattern.MatchString(fields[0]) {
+			checksums[strings.TrimPrefix(fields[1], "*")] = fields[0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", location, err)
+	}
+	return checksums, nil
+}
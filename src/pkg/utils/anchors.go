@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package utils provides utility fns for maru
+package utils
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// aliasVisitor records the anchors and aliases of a YAML document in the order they appear
+type aliasVisitor struct {
+	anchors    map[string]bool
+	unresolved []error
+}
+
+// Visit records anchors as they are defined and reports aliases that refer to an anchor that has not been defined yet
+func (v *aliasVisitor) Visit(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case *ast.AnchorNode:
+		if n.Name != nil {
+			v.anchors[n.Name.String()] = true
+		}
+	case *ast.AliasNode:
+		if n.Value != nil && !v.anchors[n.Value.String()] {
+			position := n.GetToken().Position
+			v.unresolved = append(v.unresolved, fmt.Errorf("line %d: alias *%s does not refer to an anchor (anchors must be defined above their aliases in the same file)", position.Line, n.Value.String()))
+		}
+	}
+	return v
+}
+
+// CheckAliases returns a problem (joined) for every alias in the given YAML that does not refer to an anchor defined
+// before it in the same document. Anchors cannot be shared between files, so an included file cannot use an anchor from
+// the file that includes it (or vice versa).
+func CheckAliases(contents []byte) error {
+	file, err := parser.ParseBytes(contents, 0)
+	if err != nil {
+		// syntax errors are left to the unmarshal that follows which reports them with more context
+		return nil
+	}
+
+	var errs []error
+	for _, doc := range file.Docs {
+		if doc.Body == nil {
+			continue
+		}
+		visitor := &aliasVisitor{anchors: map[string]bool{}}
+		ast.Walk(visitor, doc.Body)
+		errs = append(errs, visitor.unresolved...)
+	}
+	return errors.Join(errs...)
+}
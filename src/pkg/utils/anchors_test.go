@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckAliases(t *testing.T) {
+	require.NoError(t, CheckAliases([]byte("a: &a 1\nb: *a\nc:\n  <<: &m {x: 1}\nd:\n  <<: *m\n")))
+
+	err := CheckAliases([]byte("a: *early\nb: &early 1\nc:\n  <<: *missing\n"))
+	require.EqualError(t, err, "line 1: alias *early does not refer to an anchor (anchors must be defined above their aliases in the same file)\n"+
+		"line 4: alias *missing does not refer to an anchor (anchors must be defined above their aliases in the same file)")
+
+	// Anchors do not carry over between documents
+	require.ErrorContains(t, CheckAliases([]byte("a: &a 1\n---\nb: *a\n")), "line 3: alias *a")
+}
+
+func TestReadYaml_anchors(t *testing.T) {
+	var tasksFile types.TasksFile
+	require.NoError(t, ReadYaml("../../test/tasks/anchors/lib.yaml", &tasksFile))
+	require.Len(t, tasksFile.Tasks, 1)
+
+	// Merge keys work for tasks and actions
+	task := tasksFile.Tasks[0]
+	require.Equal(t, "greet", task.Name)
+	require.Equal(t, "a shared description", task.Description)
+	require.Equal(t, "world", task.Inputs["who"].Default)
+	require.Equal(t, 1, *task.Actions[0].MaxRetries)
+	require.Equal(t, []string{"GREETING=hello"}, task.Actions[0].Env)
+
+	path := filepath.Join(t.TempDir(), "tasks.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("tasks:\n  - name: default\n    actions:\n      - <<: *missing\n        cmd: echo\n"), 0600))
+	err := ReadYaml(path, &tasksFile)
+	require.ErrorContains(t, err, "line 4: alias *missing does not refer to an anchor")
+}
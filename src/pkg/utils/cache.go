@@ -0,0 +1,346 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package utils provides utility fns for maru
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/pkg/helpers/v2"
+)
+
+// digestSuffixRegex matches a pinned sha256 digest at the end of an include location (i.e. https://example.com/tasks.yaml@sha256:<hex>)
+var digestSuffixRegex = regexp.MustCompile(`@(sha256:[a-f0-9]{64})$`)
+
+// includeCacheEntry is the metadata stored alongside a cached remote include
+type includeCacheEntry struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Digest       string `json:"digest"`
+}
+
+// SplitIncludeDigest splits a pinned digest off of an include location, returning the location and digest (if any)
+func SplitIncludeDigest(location string) (string, string) {
+	matches := digestSuffixRegex.FindStringSubmatch(location)
+	if matches == nil {
+		return location, ""
+	}
+	return strings.TrimSuffix(location, matches[0]), matches[1]
+}
+
+// Digest returns the sha256 digest of the given contents in the form sha256:<hex>
+func Digest(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// CacheEntry describes a single item in the cache
+type CacheEntry struct {
+	Kind     string
+	Key      string
+	URL      string
+	Size     int64
+	LastUsed time.Time
+	paths    []string
+}
+
+// CacheDir returns the directory maru caches files in (config.CacheDirectory, defaulting to $HOME/.maru/cache)
+func CacheDir() (string, error) {
+	if config.CacheDirectory != "" {
+		return config.CacheDirectory, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".maru", "cache"), nil
+}
+
+// includeCacheDir returns the directory used to cache remote includes
+func includeCacheDir() (string, error) {
+	cacheDir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "includes"), nil
+}
+
+// includeCacheKey returns the key a remote file is cached under. Files fetched with a token are keyed by a hash of the
+// token as well as the URL, so a copy fetched with credentials is only ever read back by runs with the same credentials.
+func includeCacheKey(location, token string) string {
+	if token != "" {
+		location += "\n" + Digest([]byte(token))
+	}
+	return strings.TrimPrefix(Digest([]byte(location)), "sha256:")
+}
+
+// includeCachePaths returns the paths of the cached body and metadata for a given include URL and token
+func includeCachePaths(location, token string) (string, string, error) {
+	dir, err := includeCacheDir()
+	if err != nil {
+		return "", "", err
+	}
+	key := includeCacheKey(location, token)
+	return filepath.Join(dir, key+".yaml"), filepath.Join(dir, key+".json"), nil
+}
+
+// readIncludeCache returns the cached contents and metadata for a given include URL (fetched with the given token),
+// verifying the contents against the recorded digest (and the pinned digest if one is provided)
+func readIncludeCache(location, token, digest string) ([]byte, *includeCacheEntry, error) {
+	bodyPath, metaPath, err := includeCachePaths(location, token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metaContents, err := os.ReadFile(metaPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	var entry includeCacheEntry
+	if err := json.Unmarshal(metaContents, &entry); err != nil {
+		return nil, nil, err
+	}
+
+	body, err := os.ReadFile(bodyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if actual := Digest(body); actual != entry.Digest {
+		return nil, nil, fmt.Errorf("cached copy of %s is corrupt: expected %s, got %s", location, entry.Digest, actual)
+	}
+	if digest != "" && entry.Digest != digest {
+		return nil, nil, fmt.Errorf("cached copy of %s does not match pinned digest %s", location, digest)
+	}
+
+	// Mark the entry as recently used so that it is evicted last
+	now := time.Now()
+	_ = os.Chtimes(bodyPath, now, now)
+
+	return body, &entry, nil
+}
+
+// IncludeCached returns whether there is a valid cached copy of a remote include (which can have a pinned digest) that
+// can be read with the given auth
+func IncludeCached(location string, auth map[string]string) bool {
+	location, digest := SplitIncludeDigest(location)
+	_, _, err := readIncludeCache(location, remoteFileToken(location, auth), digest)
+	return err == nil
+}
+
+// writeIncludeCache stores the contents and metadata for a given include URL (fetched with the given token)
+func writeIncludeCache(entry includeCacheEntry, token string, body []byte) error {
+	bodyPath, metaPath, err := includeCachePaths(entry.URL, token)
+	if err != nil {
+		return err
+	}
+
+	if err := helpers.CreateDirectory(filepath.Dir(bodyPath), helpers.ReadWriteExecuteUser); err != nil {
+		return err
+	}
+
+	metaContents, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	// Write the body first so that metadata never points at a missing file
+	if err := os.WriteFile(bodyPath, body, helpers.ReadWriteUser); err != nil {
+		return err
+	}
+	if err := os.WriteFile(metaPath, metaContents, helpers.ReadWriteUser); err != nil {
+		return err
+	}
+
+	return EvictCache(config.CacheMaxBytes)
+}
+
+// ListCache returns every entry in the cache, most recently used first
+func ListCache() ([]CacheEntry, error) {
+	dir, err := includeCacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	metaPaths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []CacheEntry{}
+	for _, metaPath := range metaPaths {
+		key := strings.TrimSuffix(filepath.Base(metaPath), ".json")
+		entries = append(entries, newCacheEntry("include", key, filepath.Join(dir, key+".yaml"), metaPath))
+	}
+
+	// Interrupted downloads are kept until they are resumed (or cleaned up)
+	partialMetaPaths, err := filepath.Glob(filepath.Join(dir, "partial", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, metaPath := range partialMetaPaths {
+		key := strings.TrimSuffix(filepath.Base(metaPath), ".json")
+		entries = append(entries, newCacheEntry("partial", key, filepath.Join(dir, "partial", key+".part"), metaPath))
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].LastUsed.After(entries[j].LastUsed)
+	})
+	return entries, nil
+}
+
+// newCacheEntry returns the cache entry made up of the given body and metadata files
+func newCacheEntry(kind, key, bodyPath, metaPath string) CacheEntry {
+	entry := CacheEntry{Kind: kind, Key: key, paths: []string{bodyPath, metaPath}}
+
+	if metaContents, err := os.ReadFile(metaPath); err == nil {
+		var meta struct {
+			URL string `json:"url"`
+		}
+		if json.Unmarshal(metaContents, &meta) == nil {
+			entry.URL = meta.URL
+		}
+	}
+	for _, path := range entry.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		entry.Size += info.Size()
+		if info.ModTime().After(entry.LastUsed) {
+			entry.LastUsed = info.ModTime()
+		}
+	}
+	return entry
+}
+
+// RemoveCacheEntry deletes an entry from the cache
+func RemoveCacheEntry(entry CacheEntry) error {
+	for _, path := range entry.paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// CleanCache deletes every cache entry that has not been used within olderThan (or every entry if olderThan is 0),
+// returning the number of entries and bytes removed
+func CleanCache(olderThan time.Duration) (int, int64, error) {
+	entries, err := ListCache()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	removed := 0
+	var freed int64
+	for _, entry := range entries {
+		if olderThan > 0 && time.Since(entry.LastUsed) < olderThan {
+			continue
+		}
+		if err := RemoveCacheEntry(entry); err != nil {
+			return removed, freed, err
+		}
+		removed++
+		freed += entry.Size
+	}
+	return removed, freed, nil
+}
+
+// EvictCache deletes the least recently used cache entries until the cache fits within maxBytes (if greater than 0)
+func EvictCache(maxBytes int64) error {
+	if maxBytes <= 0 {
+		return nil
+	}
+
+	entries, err := ListCache()
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size
+	}
+
+	// Entries are listed most recently used first so evict from the end
+	for idx := len(entries) - 1; idx >= 0 && total > maxBytes; idx-- {
+		if err := RemoveCacheEntry(entries[idx]); err != nil {
+			return err
+		}
+		message.SLog.Debug(fmt.Sprintf("evicted %s from the cache", entries[idx].URL))
+		total -= entries[idx].Size
+	}
+	return nil
+}
+
+// byteUnits are the suffixes accepted by ParseByteSize
+var byteUnits = map[string]int64{
+	"":    1,
+	"B":   1,
+	"KB":  1000,
+	"MB":  1000 * 1000,
+	"GB":  1000 * 1000 * 1000,
+	"KIB": 1 << 10,
+	"MIB": 1 << 20,
+	"GIB": 1 << 30,
+}
+
+// ParseByteSize parses a size such as 500MB, 1GiB or 1024 into a number of bytes
+func ParseByteSize(size string) (int64, error) {
+	size = strings.TrimSpace(size)
+	idx := strings.IndexFunc(size, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	number, unit := size, ""
+	if idx >= 0 {
+		number, unit = size[:idx], strings.ToUpper(strings.TrimSpace(size[idx:]))
+	}
+
+	multiplier, ok := byteUnits[unit]
+	if !ok || number == "" {
+		return 0, fmt.Errorf("invalid size %q (i.e. 500MB or 1GiB)", size)
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q (i.e. 500MB or 1GiB)", size)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// FormatByteSize formats a number of bytes in human readable binary units (i.e. 1.5 MiB)
+func FormatByteSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// verifyDigest checks the given contents against a pinned digest (if one is provided)
+func verifyDigest(location, digest string, body []byte) error {
+	if digest == "" {
+		return nil
+	}
+	if actual := Digest(body); actual != digest {
+		return fmt.Errorf("contents of %s do not match pinned digest: expected %s, got %s", location, digest, actual)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/config/lang"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+)
+
+func Test_SplitIncludeDigest(t *testing.T) {
+	digest := Digest([]byte("tasks: []"))
+
+	location, got := SplitIncludeDigest("https://example.com/tasks.yaml@" + digest)
+	require.Equal(t, "https://example.com/tasks.yaml", location)
+	require.Equal(t, digest, got)
+
+	location, got = SplitIncludeDigest("https://example.com/tasks.yaml")
+	require.Equal(t, "https://example.com/tasks.yaml", location)
+	require.Empty(t, got)
+}
+
+func Test_fetchRemoteFile(t *testing.T) {
+	config.CacheDirectory = t.TempDir()
+	t.Cleanup(func() { config.CacheDirectory = "" })
+
+	contents := []byte("tasks:\n  - name: default\n")
+	requests := 0
+	notModified := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write(contents)
+	}))
+
+	location := server.URL + "/tasks.yaml"
+
+	// The first request populates the cache
+	body, err := fetchRemoteFile(location, "", nil)
+	require.NoError(t, err)
+	require.Equal(t, contents, body)
+	require.Equal(t, 1, requests)
+
+	// The second request is conditional and served from the cache
+	body, err = fetchRemoteFile(location, "", nil)
+	require.NoError(t, err)
+	require.Equal(t, contents, body)
+	require.Equal(t, 2, requests)
+	require.Equal(t, 1, notModified)
+
+	// A pinned digest that matches the cache skips the network entirely
+	body, err = fetchRemoteFile(location, Digest(contents), nil)
+	require.NoError(t, err)
+	require.Equal(t, contents, body)
+	require.Equal(t, 2, requests)
+
+	// A pinned digest that does not match is rejected
+	_, err = fetchRemoteFile(location, Digest([]byte("other")), nil)
+	require.ErrorContains(t, err, "do not match pinned digest")
+
+	// The cached copy is used when the server is unreachable
+	server.Close()
+	body, err = fetchRemoteFile(location, "", nil)
+	require.NoError(t, err)
+	require.Equal(t, contents, body)
+}
+
+func Test_fetchRemoteFile_auth(t *testing.T) {
+	keyring.MockInit()
+	config.CacheDirectory = t.TempDir()
+	t.Cleanup(func() { config.CacheDirectory = "" })
+
+	contents := []byte("tasks:\n  - name: private\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write(contents)
+	}))
+	location := server.URL + "/tasks.yaml"
+	host := strings.TrimPrefix(server.URL, "http://")
+	auth := map[string]string{host: "token"}
+
+	body, err := fetchRemoteFile(location, "", auth)
+	require.NoError(t, err)
+	require.Equal(t, contents, body)
+	require.True(t, IncludeCached(location, auth))
+
+	// A copy fetched with a token is never read back without it (or with another token), even when the server is
+	// unreachable
+	require.False(t, IncludeCached(location, nil))
+	require.False(t, IncludeCached(location, map[string]string{host: "other"}))
+	server.Close()
+	_, err = fetchRemoteFile(location, "", nil)
+	require.ErrorContains(t, err, "unable to make request")
+	body, err = fetchRemoteFile(location, "", auth)
+	require.NoError(t, err)
+	require.Equal(t, contents, body)
+}
+
+func Test_fetchRemoteFile_offline(t *testing.T) {
+	config.CacheDirectory = t.TempDir()
+	t.Cleanup(func() {
+		config.CacheDirectory = ""
+		config.Offline = false
+	})
+
+	contents := []byte("tasks:\n  - name: default\n")
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write(contents)
+	}))
+	defer server.Close()
+	cached := server.URL + "/cached.yaml"
+	_, err := fetchRemoteFile(cached, "", nil)
+	require.NoError(t, err)
+	require.True(t, IncludeCached(cached, nil))
+	require.True(t, IncludeCached(cached+"@"+Digest(contents), nil))
+	require.False(t, IncludeCached(cached+"@"+Digest([]byte("other")), nil))
+
+	// Offline, the cached copy is used without asking the server and anything else fails right away
+	config.Offline = true
+	body, err := fetchRemoteFile(cached, "", nil)
+	require.NoError(t, err)
+	require.Equal(t, contents, body)
+	_, err = fetchRemoteFile(server.URL+"/missing.yaml", "", nil)
+	require.ErrorIs(t, err, lang.ErrOffline)
+	require.ErrorContains(t, err, "/missing.yaml is not in the cache (run once without --offline to cache it)")
+	require.ErrorIs(t, CheckRemoteFile(cached, nil), lang.ErrOffline)
+	require.Equal(t, 1, requests)
+}
+
+func Test_cacheEviction(t *testing.T) {
+	config.CacheDirectory = t.TempDir()
+	t.Cleanup(func() {
+		config.CacheDirectory = ""
+		config.CacheMaxBytes = 0
+	})
+
+	body := []byte("tasks: []\n")
+	for idx, url := range []string{"https://example.com/a.yaml", "https://example.com/b.yaml", "https://example.com/c.yaml"} {
+		require.NoError(t, writeIncludeCache(includeCacheEntry{URL: url, Digest: Digest(body)}, "", body))
+		// Space the entries out so that their last used times are distinct
+		bodyPath, metaPath, err := includeCachePaths(url, "")
+		require.NoError(t, err)
+		used := time.Now().Add(time.Duration(idx-10) * time.Minute)
+		require.NoError(t, os.Chtimes(bodyPath, used, used))
+		require.NoError(t, os.Chtimes(metaPath, used, used))
+	}
+
+	// Reading an entry marks it as the most recently used
+	_, _, err := readIncludeCache("https://example.com/a.yaml", "", "")
+	require.NoError(t, err)
+
+	entries, err := ListCache()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, "https://example.com/a.yaml", entries[0].URL)
+	require.Equal(t, "https://example.com/c.yaml", entries[1].URL)
+	require.Equal(t, "https://example.com/b.yaml", entries[2].URL)
+
+	// Evicting down to the size of two entries removes the least recently used one
+	require.NoError(t, EvictCache(entries[0].Size*2))
+	entries, err = ListCache()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "https://example.com/a.yaml", entries[0].URL)
+	require.Equal(t, "https://example.com/c.yaml", entries[1].URL)
+
+	// Cleaning only removes entries that have not been used recently unless asked to remove everything
+	removed, _, err := CleanCache(time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 0, removed)
+	removed, freed, err := CleanCache(0)
+	require.NoError(t, err)
+	require.Equal(t, 2, removed)
+	require.Equal(t, entries[0].Size*2, freed)
+}
+
+func Test_ParseByteSize(t *testing.T) {
+	for size, want := range map[string]int64{
+		"1024":   1024,
+		"500MB":  500 * 1000 * 1000,
+		"1GiB":   1 << 30,
+		"1.5 kb": 1500,
+		"0":      0,
+	} {
+		got, err := ParseByteSize(size)
+		require.NoError(t, err, size)
+		require.Equal(t, want, got, size)
+	}
+
+	for _, size := range []string{"", "MB", "12XB", "1.2.3GB"} {
+		_, err := ParseByteSize(size)
+		require.Error(t, err, size)
+	}
+
+	require.Equal(t, "512 B", FormatByteSize(512))
+	require.Equal(t, "1.5 KiB", FormatByteSize(1536))
+	require.Equal(t, "1.0 GiB", FormatByteSize(1<<30))
+}
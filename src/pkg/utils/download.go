@@ -0,0 +1,234 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package utils provides utility fns for maru
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/pkg/helpers/v2"
+)
+
+const (
+	// maxDownloadAttempts is the number of times an interrupted download is resumed before giving up
+	maxDownloadAttempts = 3
+	// downloadProgressMinBytes is the size above which a download reports its progress
+	downloadProgressMinBytes = 1 << 20
+	// downloadProgressInterval is how often a download reports its progress
+	downloadProgressInterval = 250 * time.Millisecond
+)
+
+// partialDownloadEntry is the metadata stored alongside a partially downloaded remote file so it can be resumed
+type partialDownloadEntry struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Size         int64  `json:"size,omitempty"`
+}
+
+// validator returns the value used in an If-Range header to check that the remote file has not changed since the
+// partial download started
+func (entry partialDownloadEntry) validator() string {
+	if entry.ETag != "" {
+		return entry.ETag
+	}
+	return entry.LastModified
+}
+
+// partialDownload is a download of a remote file into the cache that can be resumed if it is interrupted
+type partialDownload struct {
+	bodyPath string
+	metaPath string
+	entry    partialDownloadEntry
+	offset   int64
+}
+
+// partialDownloadPaths returns the paths of the partial body and metadata for a given URL and token
+func partialDownloadPaths(location, token string) (string, string, error) {
+	dir, err := includeCacheDir()
+	if err != nil {
+		return "", "", err
+	}
+	key := includeCacheKey(location, token)
+	return filepath.Join(dir, "partial", key+".part"), filepath.Join(dir, "partial", key+".json"), nil
+}
+
+// newPartialDownload returns the download of a URL, picking up any earlier partial download of it and adding the
+// headers to the request that resume it
+func newPartialDownload(req *http.Request, location, token string) (*partialDownload, error) {
+	bodyPath, metaPath, err := partialDownloadPaths(location, token)
+	if err != nil {
+		return nil, err
+	}
+	download := &partialDownload{bodyPath: bodyPath, metaPath: metaPath, entry: partialDownloadEntry{URL: location}}
+
+	metaContents, metaErr := os.ReadFile(metaPath)
+	info, statErr := os.Stat(bodyPath)
+	if metaErr != nil || statErr != nil || json.Unmarshal(metaContents, &download.entry) != nil ||
+		download.entry.URL != location || download.entry.validator() == "" || info.Size() == 0 {
+		download.entry = partialDownloadEntry{URL: location}
+		return download, nil
+	}
+
+	download.offset = info.Size()
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", download.offset))
+	req.Header.Set("If-Range", download.entry.validator())
+	// A conditional request for the whole file would return 304 rather than the rest of it
+	req.Header.Del("If-None-Match")
+	req.Header.Del("If-Modified-Since")
+	message.SLog.Debug(fmt.Sprintf("resuming download of %s from %s", location, FormatByteSize(download.offset)))
+	return download, nil
+}
+
+// resumable returns whether the download has progress that can be resumed
+func (d *partialDownload) resumable() bool {
+	return d.offset > 0
+}
+
+// discard removes any partial progress so the next request starts over
+func (d *partialDownload) discard() {
+	_ = os.Remove(d.bodyPath)
+	_ = os.Remove(d.metaPath)
+	d.offset = 0
+	d.entry = partialDownloadEntry{URL: d.entry.URL}
+}
+
+// read reads the body of a response into the partial file (appending to it if the server sent the rest of the file),
+// reporting progress for large files, and returns the whole file once it is complete
+func (d *partialDownload) read(resp *http.Response) ([]byte, error) {
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && d.resumable() && contentRangeStart(resp) == d.offset:
+		// The server sent the rest of the file
+	case resp.StatusCode == http.StatusPartialContent:
+		d.discard()
+		return nil, fmt.Errorf("failed getting %s: unexpected range %q", d.entry.URL, resp.Header.Get("Content-Range"))
+	default:
+		// The server sent the whole file (i.e. because it changed or does not support ranges) so start over
+		d.discard()
+		d.entry.ETag = resp.Header.Get("ETag")
+		d.entry.LastModified = resp.Header.Get("Last-Modified")
+		d.entry.Size = max(resp.ContentLength, 0)
+		// Only keep progress that can be resumed (the byte offsets of transparently decompressed bodies do not match the file)
+		if d.entry.validator() != "" && !resp.Uncompressed {
+			if err := d.writeMeta(); err != nil {
+				message.SLog.Debug(fmt.Sprintf("unable to record partial download of %s: %s", d.entry.URL, err.Error()))
+			}
+		}
+	}
+
+	if err := helpers.CreateDirectory(filepath.Dir(d.bodyPath), helpers.ReadWriteExecuteUser); err != nil {
+		return nil, err
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if !d.resumable() {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(d.bodyPath, flags, helpers.ReadWriteUser)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := newDownloadProgress(d.entry.URL, d.offset, d.entry.Size)
+	written, copyErr := io.Copy(io.MultiWriter(file, progress), resp.Body)
+	closeErr := file.Close()
+	d.offset += written
+	if copyErr != nil {
+		progress.fail()
+		return nil, fmt.Errorf("download interrupted after %s: %w", FormatByteSize(d.offset), copyErr)
+	}
+	if closeErr != nil {
+		progress.fail()
+		return nil, closeErr
+	}
+	progress.finish()
+
+	body, err := os.ReadFile(d.bodyPath)
+	d.discard()
+	return body, err
+}
+
+// writeMeta records the metadata needed to resume the download
+func (d *partialDownload) writeMeta() error {
+	if err := helpers.CreateDirectory(filepath.Dir(d.metaPath), helpers.ReadWriteExecuteUser); err != nil {
+		return err
+	}
+	metaContents, err := json.Marshal(d.entry)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(d.metaPath, metaContents, helpers.ReadWriteUser)
+}
+
+// contentRangeStart returns the first byte of a partial response (from its Content-Range header) or -1 if it has none
+func contentRangeStart(resp *http.Response) int64 {
+	contentRange := strings.TrimPrefix(resp.Header.Get("Content-Range"), "bytes ")
+	start, _, ok := strings.Cut(contentRange, "-")
+	if !ok {
+		return -1
+	}
+	offset, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return offset
+}
+
+// downloadProgress reports the progress of a large download with a spinner
+type downloadProgress struct {
+	location   string
+	downloaded int64
+	total      int64
+	spinner    helpers.ProgressWriter
+	lastUpdate time.Time
+}
+
+// newDownloadProgress returns a writer that reports the progress of a download that has already downloaded offset of
+// total bytes (0 if the total is unknown)
+func newDownloadProgress(location string, offset, total int64) *downloadProgress {
+	return &downloadProgress{location: location, downloaded: offset, total: total}
+}
+
+// Write records the bytes that were downloaded, starting the spinner once the download is large enough to need one
+func (p *downloadProgress) Write(b []byte) (int, error) {
+	p.downloaded += int64(len(b))
+	if p.spinner == nil && (p.downloaded >= downloadProgressMinBytes || p.total >= downloadProgressMinBytes) {
+		p.spinner = message.NewProgressSpinner("Downloading %s", p.location)
+	}
+	if p.spinner != nil && time.Since(p.lastUpdate) >= downloadProgressInterval {
+		p.spinner.Updatef("Downloading %s (%s)", p.location, p.String())
+		p.lastUpdate = time.Now()
+	}
+	return len(b), nil
+}
+
+// String returns how much has been downloaded (and of how much if the total is known)
+func (p *downloadProgress) String() string {
+	if p.total > 0 {
+		return fmt.Sprintf("%s of %s", FormatByteSize(p.downloaded), FormatByteSize(p.total))
+	}
+	return FormatByteSize(p.downloaded)
+}
+
+// finish marks the download as complete
+func (p *downloadProgress) finish() {
+	if p.spinner != nil {
+		p.spinner.Successf("Downloaded %s (%s)", p.location, FormatByteSize(p.downloaded))
+	}
+}
+
+// fail marks the download as interrupted
+func (p *downloadProgress) fail() {
+	if p.spinner != nil {
+		p.spinner.Failf("Download of %s interrupted after %s", p.location, p.String())
+	}
+}
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package utils
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_fetchRemoteFile_resume(t *testing.T) {
+	config.CacheDirectory = t.TempDir()
+	t.Cleanup(func() { config.CacheDirectory = "" })
+
+	contents := bytes.Repeat([]byte("# a large include\n"), 4096)
+	modified := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	etag := `"v1"`
+	var ranges []string
+	interrupt := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", etag)
+		if interrupt > 0 {
+			// Send half of the file then drop the connection
+			interrupt--
+			w.Header().Set("Content-Length", strconv.Itoa(len(contents)))
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(contents[:len(contents)/2])
+			conn, _, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			conn.Close()
+			return
+		}
+		http.ServeContent(w, r, "tasks.yaml", modified, bytes.NewReader(contents))
+	}))
+	defer server.Close()
+
+	t.Run("an interrupted download is resumed", func(t *testing.T) {
+		ranges = nil
+		interrupt = 1
+		location := server.URL + "/interrupted.yaml"
+		body, err := fetchRemoteFile(location, "", nil)
+		require.NoError(t, err)
+		require.Equal(t, contents, body)
+		require.Equal(t, []string{"", "bytes=" + strconv.Itoa(len(contents)/2) + "-"}, ranges)
+
+		// The partial download is removed once it completes
+		bodyPath, metaPath, err := partialDownloadPaths(location, "")
+		require.NoError(t, err)
+		require.NoFileExists(t, bodyPath)
+		require.NoFileExists(t, metaPath)
+	})
+
+	t.Run("a download that keeps failing is resumed on the next run", func(t *testing.T) {
+		ranges = nil
+		interrupt = maxDownloadAttempts
+		location := server.URL + "/failing.yaml"
+		_, err := fetchRemoteFile(location, "", nil)
+		require.ErrorContains(t, err, "download interrupted after")
+
+		entries, err := ListCache()
+		require.NoError(t, err)
+		require.True(t, slices.ContainsFunc(entries, func(entry CacheEntry) bool {
+			return entry.Kind == "partial" && entry.URL == location
+		}))
+
+		body, err := fetchRemoteFile(location, "", nil)
+		require.NoError(t, err)
+		require.Equal(t, contents, body)
+		require.True(t, strings.HasPrefix(ranges[len(ranges)-1], "bytes="))
+	})
+
+	t.Run("a file that changed is downloaded again", func(t *testing.T) {
+		ranges = nil
+		location := server.URL + "/changed.yaml"
+
+		// Leave a partial download of an older version of the file behind
+		bodyPath, metaPath, err := partialDownloadPaths(location, "")
+		require.NoError(t, err)
+		download := &partialDownload{bodyPath: bodyPath, metaPath: metaPath, entry: partialDownloadEntry{URL: location, ETag: `"v0"`}}
+		require.NoError(t, download.writeMeta())
+		require.NoError(t, os.WriteFile(bodyPath, []byte("stale"), 0600))
+
+		body, err := fetchRemoteFile(location, "", nil)
+		require.NoError(t, err)
+		require.Equal(t, contents, body)
+		require.Equal(t, []string{"bytes=5-"}, ranges)
+	})
+}
@@ -0,0 +1,42 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package utils provides utility fns for maru
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ansiRegex matches ANSI CSI sequences (colors, cursor movement), OSC sequences (titles, hyperlinks) and other
+// two-character escape sequences
+var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// SanitizeOutput returns captured command output as it was written or, when stripANSI is set, with invalid UTF-8
+// replaced, ANSI escape sequences removed and carriage return redraws (i.e. progress bars) collapsed down to the text
+// that was last drawn on each line
+func SanitizeOutput(output string, stripANSI bool) string {
+	if !stripANSI {
+		return output
+	}
+
+	output = strings.ToValidUTF8(output, "�")
+	output = ansiRegex.ReplaceAllString(output, "")
+
+	lines := strings.Split(output, "\n")
+	for idx, line := range lines {
+		// Keep the trailing \r of CRLF line endings while dropping anything a \r redraw overwrote
+		crlf := strings.HasSuffix(line, "\r")
+		line = strings.TrimSuffix(line, "\r")
+		if last := strings.LastIndex(line, "\r"); last >= 0 {
+			line = line[last+1:]
+		}
+		if crlf {
+			line += "\r"
+		}
+		lines[idx] = line
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SanitizeOutput(t *testing.T) {
+	tests := []struct {
+		name      string
+		output    string
+		stripANSI bool
+		want      string
+	}{
+		{
+			name:      "strips color codes",
+			output:    "\x1b[1;32mdeployed\x1b[0m",
+			stripANSI: true,
+			want:      "deployed",
+		},
+		{
+			name:      "strips OSC hyperlinks",
+			output:    "\x1b]8;;https://example.com\x07link\x1b]8;;\x07",
+			stripANSI: true,
+			want:      "link",
+		},
+		{
+			name:      "collapses progress bar redraws",
+			output:    "downloading\n[==  ] 50%\r[====] 100%\ndone",
+			stripANSI: true,
+			want:      "downloading\n[====] 100%\ndone",
+		},
+		{
+			name:      "keeps CRLF line endings",
+			output:    "one\r\ntwo\r\n",
+			stripANSI: true,
+			want:      "one\r\ntwo\r\n",
+		},
+		{
+			name:      "preserves ANSI when asked",
+			output:    "\x1b[31mred\x1b[0m\rover",
+			stripANSI: false,
+			want:      "\x1b[31mred\x1b[0m\rover",
+		},
+		{
+			name:      "replaces invalid UTF-8 when stripping",
+			output:    "bad\xff\xfebytes",
+			stripANSI: true,
+			want:      "bad�bytes",
+		},
+		{
+			name:      "preserves invalid UTF-8 byte for byte",
+			output:    "bad\xff\xfe\x1b[0mbytes\r\n",
+			stripANSI: false,
+			want:      "bad\xff\xfe\x1b[0mbytes\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, SanitizeOutput(tt.output, tt.stripANSI))
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package utils provides utility fns for maru
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/defenseunicorns/pkg/helpers/v2"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	goyaml "github.com/goccy/go-yaml"
+)
+
+// PatchFile deep merges (RFC 7386) and then applies JSON patch operations (RFC 6902) to a YAML or JSON file, writing
+// the result to output (or back to the file if output is empty) in the same format as the original file. The key order
+// of the file is kept, as are the comments of a YAML file on any value that is still at the same path.
+func PatchFile(path string, output string, merge any, ops []types.JSONPatchOperation) error {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	isJSON := strings.EqualFold(filepath.Ext(path), ".json")
+	doc := contents
+	comments := goyaml.CommentMap{}
+	if !isJSON {
+		var discard any
+		if err := goyaml.UnmarshalWithOptions(contents, &discard, goyaml.CommentToMap(comments)); err != nil {
+			return fmt.Errorf("unable to parse %s as YAML: %w", path, err)
+		}
+		if doc, err = goyaml.YAMLToJSON(contents); err != nil {
+			return fmt.Errorf("unable to parse %s as YAML: %w", path, err)
+		}
+	}
+
+	if merge != nil {
+		mergePatch, err := json.Marshal(merge)
+		if err != nil {
+			return err
+		}
+		if doc, err = jsonpatch.MergePatch(doc, mergePatch); err != nil {
+			return fmt.Errorf("unable to merge values into %s: %w", path, err)
+		}
+	}
+
+	if len(ops) > 0 {
+		opsJSON, err := patchOperationsJSON(ops)
+		if err != nil {
+			return fmt.Errorf("invalid patch operations for %s: %w", path, err)
+		}
+		patch, err := jsonpatch.DecodePatch(opsJSON)
+		if err != nil {
+			return fmt.Errorf("invalid patch operations for %s: %w", path, err)
+		}
+		if doc, err = patch.Apply(doc); err != nil {
+			return fmt.Errorf("unable to patch %s: %w", path, err)
+		}
+	}
+
+	var result []byte
+	if isJSON {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, doc, "", "  "); err != nil {
+			return err
+		}
+		indented.WriteString("\n")
+		result = indented.Bytes()
+	} else {
+		var value any
+		if err := goyaml.UnmarshalWithOptions(doc, &value, goyaml.UseOrderedMap()); err != nil {
+			return err
+		}
+		if result, err = goyaml.MarshalWithOptions(value, goyaml.WithComment(comments)); err != nil {
+			return err
+		}
+	}
+
+	if output == "" {
+		output = path
+	}
+	mode := os.FileMode(helpers.ReadWriteUser)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	return os.WriteFile(output, result, mode)
+}
+
+// patchOperationsJSON returns JSON patch operations as a JSON patch document, checking that each only sets the fields
+// its op uses. The value of add, replace and test is always included so that they can set or test for null.
+func patchOperationsJSON(ops []types.JSONPatchOperation) ([]byte, error) {
+	patch := []map[string]any{}
+	for i, op := range ops {
+		operation := map[string]any{"op": op.Op, "path": op.Path}
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.From != "" {
+				return nil, fmt.Errorf("operation %d (%s) cannot set from", i, op.Op)
+			}
+			operation["value"] = op.Value
+		case "move", "copy":
+			if op.From == "" {
+				return nil, fmt.Errorf("operation %d (%s) must set from", i, op.Op)
+			}
+			if op.Value != nil {
+				return nil, fmt.Errorf("operation %d (%s) cannot set value", i, op.Op)
+			}
+			operation["from"] = op.From
+		case "remove":
+			if op.From != "" || op.Value != nil {
+				return nil, fmt.Errorf("operation %d (remove) cannot set from or value", i)
+			}
+		default:
+			return nil, fmt.Errorf("operation %d has an unknown op %q", i, op.Op)
+		}
+		patch = append(patch, operation)
+	}
+	return json.Marshal(patch)
+}
@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PatchFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		contents   string
+		merge      any
+		ops        []types.JSONPatchOperation
+		want       string
+		wantErrMsg string
+	}{
+		{
+			name:     "deep merges into YAML preserving key order",
+			file:     "values.yaml",
+			contents: "image:\n  repository: app\n  tag: 1.0.0\nreplicas: 1\ndebug: true\n",
+			merge:    map[string]any{"image": map[string]any{"tag": "2.0.0"}, "debug": nil},
+			want:     "image:\n  repository: app\n  tag: 2.0.0\nreplicas: 1\n",
+		},
+		{
+			name:     "keeps the comments of values that are still there",
+			file:     "values.yaml",
+			contents: "# Values for the app\nimage:\n  # the image to deploy\n  repository: app\n  tag: 1.0.0 # bumped by CI\n# turn on debug logs\ndebug: true\n",
+			merge:    map[string]any{"image": map[string]any{"tag": "2.0.0", "pullPolicy": "Always"}, "debug": nil},
+			want:     "# Values for the app\nimage:\n  # the image to deploy\n  repository: app\n  tag: 2.0.0 # bumped by CI\n  pullPolicy: Always\n",
+		},
+		{
+			name:     "applies JSON patch operations to JSON",
+			file:     "config.json",
+			contents: `{"name": "app", "ports": [8080]}`,
+			ops: []types.JSONPatchOperation{
+				{Op: "add", Path: "/ports/-", Value: 9090},
+				{Op: "replace", Path: "/name", Value: "other"},
+			},
+			want: "{\n  \"name\": \"other\",\n  \"ports\": [\n    8080,\n    9090\n  ]\n}\n",
+		},
+		{
+			name:     "merges before applying operations",
+			file:     "values.yaml",
+			contents: "replicas: 1\n",
+			merge:    map[string]any{"replicas": 2},
+			ops:      []types.JSONPatchOperation{{Op: "test", Path: "/replicas", Value: 2}},
+			want:     "replicas: 2\n",
+		},
+		{
+			name:     "sets null values",
+			file:     "values.yaml",
+			contents: "image:\n  tag: 1.0.0\n",
+			ops: []types.JSONPatchOperation{
+				{Op: "replace", Path: "/image/tag", Value: nil},
+				{Op: "add", Path: "/debug", Value: nil},
+			},
+			want: "image:\n  tag: null\ndebug: null\n",
+		},
+		{
+			name:     "moves and removes values",
+			file:     "values.yaml",
+			contents: "old: 1\nextra: true\n",
+			ops: []types.JSONPatchOperation{
+				{Op: "move", Path: "/new", From: "/old"},
+				{Op: "remove", Path: "/extra"},
+			},
+			want: "new: 1\n",
+		},
+		{
+			name:       "mixes fields of different operations",
+			file:       "values.yaml",
+			contents:   "replicas: 1\n",
+			ops:        []types.JSONPatchOperation{{Op: "remove", Path: "/replicas", Value: 2}},
+			wantErrMsg: "operation 0 (remove) cannot set from or value",
+		},
+		{
+			name:       "move without from",
+			file:       "values.yaml",
+			contents:   "replicas: 1\n",
+			ops:        []types.JSONPatchOperation{{Op: "move", Path: "/count"}},
+			wantErrMsg: "operation 0 (move) must set from",
+		},
+		{
+			name:       "failed test operation",
+			file:       "values.yaml",
+			contents:   "replicas: 1\n",
+			ops:        []types.JSONPatchOperation{{Op: "test", Path: "/replicas", Value: 2}},
+			wantErrMsg: "unable to patch",
+		},
+		{
+			name:       "invalid operation",
+			file:       "values.yaml",
+			contents:   "replicas: 1\n",
+			ops:        []types.JSONPatchOperation{{Op: "frobnicate", Path: "/replicas"}},
+			wantErrMsg: "invalid patch operations",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			require.NoError(t, os.WriteFile(path, []byte(tt.contents), 0600))
+
+			err := PatchFile(path, "", tt.merge, tt.ops)
+			if tt.wantErrMsg != "" {
+				require.ErrorContains(t, err, tt.wantErrMsg)
+				return
+			}
+			require.NoError(t, err)
+
+			got, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, string(got))
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package utils provides utility fns for maru
+package utils
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Platform is an operating system and architecture to build for, named as they are by GOOS and GOARCH
+type Platform struct {
+	OS   string
+	Arch string
+}
+
+// String returns the platform as os/arch (i.e. linux/amd64)
+func (p Platform) String() string {
+	return p.OS + "/" + p.Arch
+}
+
+// Ext returns the file extension executables have on the platform (.exe on Windows, nothing anywhere else)
+func (p Platform) Ext() string {
+	if p.OS == "windows" {
+		return ".exe"
+	}
+	return ""
+}
+
+// Env returns the GOOS and GOARCH environment variables that build for the platform
+func (p Platform) Env() string {
+	return fmt.Sprintf("GOOS=%s GOARCH=%s", p.OS, p.Arch)
+}
+
+// PlatformPresets are the named sets of platforms that can be given to the platforms template function
+var PlatformPresets = map[string][]string{
+	"common":  {"linux/amd64", "linux/arm64", "darwin/amd64", "darwin/arm64", "windows/amd64"},
+	"linux":   {"linux/amd64", "linux/arm64"},
+	"darwin":  {"darwin/amd64", "darwin/arm64"},
+	"windows": {"windows/amd64", "windows/arm64"},
+	"unix":    {"linux/amd64", "linux/arm64", "darwin/amd64", "darwin/arm64"},
+}
+
+// PlatformError is returned by Platforms for a platform that is not an os/arch pair or a preset
+type PlatformError struct {
+	// Spec is the platform that was given ("" when no platforms were given)
+	Spec string
+}
+
+// Error returns the platform that is not valid along with the presets that are
+func (e *PlatformError) Error() string {
+	if e.Spec == "" {
+		return fmt.Sprintf("no platforms given, use os/arch pairs (i.e. linux/amd64) or one of the presets %s", strings.Join(presetNames(), ", "))
+	}
+	return fmt.Sprintf("invalid platform %q, must be os/arch (i.e. linux/amd64) or one of the presets %s", e.Spec, strings.Join(presetNames(), ", "))
+}
+
+// Platforms returns the platforms for the given presets (i.e. common) and os/arch pairs (i.e. linux/amd64), which can
+// also be given as a single comma separated list. Platforms are returned in the order given without duplicates.
+func Platforms(specs ...string) ([]Platform, error) {
+	platforms := []Platform{}
+	add := func(spec string) error {
+		os, arch, ok := strings.Cut(spec, "/")
+		if !ok || os == "" || arch == "" || strings.Contains(arch, "/") {
+			return &PlatformError{Spec: spec}
+		}
+		if platform := (Platform{OS: os, Arch: arch}); !slices.Contains(platforms, platform) {
+			platforms = append(platforms, platform)
+		}
+		return nil
+	}
+
+	for _, spec := range specs {
+		for _, spec := range strings.Split(spec, ",") {
+			spec = strings.TrimSpace(spec)
+			preset, ok := PlatformPresets[spec]
+			if !ok {
+				preset = []string{spec}
+			}
+			for _, spec := range preset {
+				if err := add(spec); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if len(platforms) == 0 {
+		return nil, &PlatformError{}
+	}
+	return platforms, nil
+}
+
+// HostPlatform returns the platform maru is running on
+func HostPlatform() Platform {
+	return Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+}
+
+// BinaryName returns the conventional name of a binary built for a platform: name_os_arch, with .exe on Windows
+func BinaryName(name string, platform Platform) string {
+	return fmt.Sprintf("%s_%s_%s%s", name, platform.OS, platform.Arch, platform.Ext())
+}
+
+// presetNames returns the names of the platform presets in order
+func presetNames() []string {
+	names := []string{}
+	for name := range PlatformPresets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package utils
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlatforms(t *testing.T) {
+	platforms, err := Platforms("linux", "darwin/arm64, windows/amd64", "linux/amd64")
+	require.NoError(t, err)
+	require.Equal(t, []Platform{
+		{OS: "linux", Arch: "amd64"},
+		{OS: "linux", Arch: "arm64"},
+		{OS: "darwin", Arch: "arm64"},
+		{OS: "windows", Arch: "amd64"},
+	}, platforms)
+
+	platforms, err = Platforms("common")
+	require.NoError(t, err)
+	require.Len(t, platforms, 5)
+
+	_, err = Platforms("linux/amd64", "freebsd")
+	require.EqualError(t, err, `invalid platform "freebsd", must be os/arch (i.e. linux/amd64) or one of the presets common, darwin, linux, unix, windows`)
+	_, err = Platforms("linux/arm/v7")
+	require.ErrorContains(t, err, `invalid platform "linux/arm/v7"`)
+	_, err = Platforms()
+	require.ErrorContains(t, err, "no platforms given")
+}
+
+func TestBinaryName(t *testing.T) {
+	require.Equal(t, "maru_linux_arm64", BinaryName("maru", Platform{OS: "linux", Arch: "arm64"}))
+	require.Equal(t, "maru_windows_amd64.exe", BinaryName("maru", Platform{OS: "windows", Arch: "amd64"}))
+	require.Equal(t, runtime.GOOS+"/"+runtime.GOARCH, HostPlatform().String())
+}
+
+func Test_TemplateTaskAction_platforms(t *testing.T) {
+	cmd := `${{- range platforms "linux" "windows/amd64" }}
+${{ .Env }} go build -o build/${{ binaryName .inputs.name . }} .
+${{- end }}`
+	action := types.Action{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: cmd}}
+
+	// Ranging changes the dot, so the inputs are reached through $
+	_, err := TemplateTaskAction(action, map[string]string{"name": "app"}, nil, variables.SetVariableMap[string]{})
+	require.Error(t, err)
+
+	action.Cmd = `${{- range platforms "linux" "windows/amd64" }}
+${{ .Env }} go build -o build/${{ binaryName $.inputs.name . }} .
+${{- end }}`
+	got, err := TemplateTaskAction(action, map[string]string{"name": "app"}, nil, variables.SetVariableMap[string]{})
+	require.NoError(t, err)
+	require.Equal(t, `GOOS=linux GOARCH=amd64 go build -o build/app_linux_amd64 .
+GOOS=linux GOARCH=arm64 go build -o build/app_linux_arm64 .
+GOOS=windows GOARCH=amd64 go build -o build/app_windows_amd64.exe .`, got.Cmd)
+}
@@ -0,0 +1,164 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+// Package utils provides utility fns for maru
+package utils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/message"
+	"github.com/defenseunicorns/pkg/helpers/v2"
+	"github.com/zalando/go-keyring"
+)
+
+const (
+	// stateKeyringUser is the keyring entry that holds the key used to encrypt stored run state
+	stateKeyringUser = "state-key"
+	// stateKeySize is the size of the AES-256 key used to encrypt stored run state
+	stateKeySize = 32
+)
+
+// sealedPrefix marks data that was encrypted with Seal (anything without it is treated as plaintext)
+var sealedPrefix = []byte("MARUSEALED1")
+
+var (
+	stateKeyMu sync.Mutex
+	stateKey   []byte
+)
+
+// StateKeyFile returns the file the state key is kept in when the OS keyring is not available
+func StateKeyFile() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".maru", "state.key"), nil
+}
+
+// getStateKey returns the key used to encrypt stored run state, creating it the first time it is needed. The key is
+// kept in the OS keyring when one is available, otherwise it is kept in a file that only the current user can read.
+func getStateKey() ([]byte, error) {
+	stateKeyMu.Lock()
+	defer stateKeyMu.Unlock()
+	if stateKey != nil {
+		return stateKey, nil
+	}
+
+	keyFile, err := StateKeyFile()
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := os.ReadFile(keyFile)
+	if errors.Is(err, os.ErrNotExist) {
+		value, keyringErr := keyring.Get(config.KeyringService, stateKeyringUser)
+		if keyringErr == nil {
+			encoded = []byte(value)
+		} else {
+			if !errors.Is(keyringErr, keyring.ErrNotFound) {
+				message.SLog.Debug(fmt.Sprintf("unable to read the state key from the keyring: %s", keyringErr.Error()))
+			}
+			if encoded, err = newStateKey(keyFile); err != nil {
+				return nil, err
+			}
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil || len(key) != stateKeySize {
+		return nil, fmt.Errorf("the state key is invalid, remove it to create a new one (this makes existing run history and sessions unreadable)")
+	}
+	stateKey = key
+	return stateKey, nil
+}
+
+// newStateKey generates a new state key, storing it in the OS keyring or (if that fails) the given key file
+func newStateKey(keyFile string) ([]byte, error) {
+	key := make([]byte, stateKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(key)
+
+	err := keyring.Set(config.KeyringService, stateKeyringUser, encoded)
+	if err == nil {
+		return []byte(encoded), nil
+	}
+	message.SLog.Debug(fmt.Sprintf("unable to store the state key in the keyring, using %s instead: %s", keyFile, err.Error()))
+
+	if err := helpers.CreateDirectory(filepath.Dir(keyFile), helpers.ReadWriteExecuteUser); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(keyFile, []byte(encoded), helpers.ReadWriteUser); err != nil {
+		return nil, err
+	}
+	return []byte(encoded), nil
+}
+
+// Seal encrypts data that maru stores between runs (i.e. run history and sessions) with AES-256-GCM using the state key
+func Seal(plaintext []byte) ([]byte, error) {
+	key, err := getStateKey()
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := append(bytes.Clone(sealedPrefix), nonce...)
+	return gcm.Seal(sealed, nonce, plaintext, sealedPrefix), nil
+}
+
+// Unseal decrypts data encrypted with Seal, returning data that was not encrypted (i.e. written by an older maru) as is
+func Unseal(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, sealedPrefix) {
+		return data, nil
+	}
+
+	key, err := getStateKey()
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data = data[len(sealedPrefix):]
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("unable to decrypt: the data is truncated")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], sealedPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt (the state key may have changed since this was written): %w", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
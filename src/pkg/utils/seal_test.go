@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023-Present the Maru Authors
+
+package utils
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+)
+
+func TestSeal(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	keyring.MockInit()
+	stateKey = nil
+	t.Cleanup(func() { stateKey = nil })
+
+	sealed, err := Seal([]byte(`{"SECRET":"value"}`))
+	require.NoError(t, err)
+	require.NotContains(t, string(sealed), "value")
+
+	plaintext, err := Unseal(sealed)
+	require.NoError(t, err)
+	require.Equal(t, `{"SECRET":"value"}`, string(plaintext))
+
+	// Data that was never sealed is returned as is
+	plaintext, err = Unseal([]byte(`{"SECRET":"value"}`))
+	require.NoError(t, err)
+	require.Equal(t, `{"SECRET":"value"}`, string(plaintext))
+
+	// Tampered data is rejected
+	sealed[len(sealed)-1] ^= 0xff
+	_, err = Unseal(sealed)
+	require.ErrorContains(t, err, "unable to decrypt")
+	_, err = Unseal(sealedPrefix)
+	require.ErrorContains(t, err, "truncated")
+
+	// The key is kept in the keyring rather than on disk when a keyring is available
+	keyFile, err := StateKeyFile()
+	require.NoError(t, err)
+	_, err = os.Stat(keyFile)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestSeal_keyFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	keyring.MockInitWithError(errors.New("no keyring"))
+	stateKey = nil
+	t.Cleanup(func() { stateKey = nil })
+
+	sealed, err := Seal([]byte("value"))
+	require.NoError(t, err)
+
+	keyFile, err := StateKeyFile()
+	require.NoError(t, err)
+	info, err := os.Stat(keyFile)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// A new process reads the key back from the file
+	stateKey = nil
+	plaintext, err := Unseal(sealed)
+	require.NoError(t, err)
+	require.Equal(t, "value", string(plaintext))
+
+	require.NoError(t, os.WriteFile(keyFile, []byte("not a key"), 0600))
+	stateKey = nil
+	_, err = Unseal(sealed)
+	require.ErrorContains(t, err, "the state key is invalid")
+}
@@ -4,12 +4,19 @@
 package utils
 
 import (
+	"encoding/base64"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/message"
 	"github.com/stretchr/testify/require"
 
 	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
+	"github.com/defenseunicorns/maru-runner/src/types"
 )
 
 func Test_TemplateString(t *testing.T) {
@@ -75,3 +82,80 @@ func Test_TemplateString(t *testing.T) {
 	}
 
 }
+
+func Test_TemplateTaskActionWithOptions_delims(t *testing.T) {
+	vars := variables.SetVariableMap[string]{"FOO": {Value: "bar"}}
+	withs := map[string]string{"name": "maru"}
+
+	tests := []struct {
+		name   string
+		cmd    string
+		delims *types.TemplateDelims
+		want   string
+	}{
+		{
+			name: "default delimiters",
+			cmd:  "echo ${{ .inputs.name }} ${{ .variables.FOO }}",
+			want: "echo maru bar",
+		},
+		{
+			name: "escaped default delimiter",
+			cmd:  "echo $${{ github.sha }} ${{ .inputs.name }}",
+			want: "echo ${{ github.sha }} maru",
+		},
+		{
+			name:   "custom delimiters leave the defaults alone",
+			cmd:    "echo [[ .inputs.name ]] ${{ github.sha }} {{ .Values.image }}",
+			delims: &types.TemplateDelims{Left: "[[", Right: "]]"},
+			want:   "echo maru ${{ github.sha }} {{ .Values.image }}",
+		},
+		{
+			name:   "escaped custom delimiter",
+			cmd:    "echo $[[ literal ]] [[ .variables.FOO ]]",
+			delims: &types.TemplateDelims{Left: "[[", Right: "]]"},
+			want:   "echo [[ literal ]] bar",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action := types.Action{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: tt.cmd}}
+			got, err := TemplateTaskActionWithOptions(action, withs, nil, vars, TemplateOptions{Delims: tt.delims})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.Cmd)
+		})
+	}
+}
+
+func Test_TemplateTaskAction_files(t *testing.T) {
+	t.Cleanup(message.ClearSensitive)
+	dir := t.TempDir()
+	token := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(token, []byte("s3cr3t-t0ken\n"), 0600))
+	cert := filepath.Join(dir, "tls.crt")
+	certContents := "-----BEGIN CERTIFICATE-----\nMIIBszCCAVmgAwIBAgIUQ\n-----END CERTIFICATE-----\n"
+	require.NoError(t, os.WriteFile(cert, []byte(certContents), 0600))
+
+	cmd := `echo ${{ fileContents .inputs.token }} && echo ${{ b64encFile "` + cert + `" }}`
+	action := types.Action{BaseAction: &types.BaseAction[variables.ExtraVariableInfo]{Cmd: cmd}}
+	got, err := TemplateTaskAction(action, map[string]string{"token": token}, nil, variables.SetVariableMap[string]{})
+	require.NoError(t, err)
+	encoded := base64.StdEncoding.EncodeToString([]byte(certContents))
+	require.Equal(t, "echo s3cr3t-t0ken && echo "+encoded, got.Cmd)
+
+	// What was read is masked from then on
+	require.Equal(t, "echo **sanitized** && echo **sanitized**", message.Mask(got.Cmd))
+	require.Equal(t, "**sanitized**", message.Mask("MIIBszCCAVmgAwIBAgIUQ"))
+
+	// A file that can't be read fails templating
+	action.Cmd = `echo ${{ fileContents "` + filepath.Join(dir, "missing") + `" }}`
+	_, err = TemplateTaskAction(action, nil, nil, variables.SetVariableMap[string]{})
+	var pathErr *fs.PathError
+	require.True(t, errors.As(err, &pathErr))
+
+	// Relative paths are read from the directory of the action
+	action.Cmd = `echo ${{ fileContents "token" }} && echo ${{ b64encFile "tls.crt" }}`
+	got, err = TemplateTaskActionWithOptions(action, nil, nil, variables.SetVariableMap[string]{}, TemplateOptions{Dir: dir})
+	require.NoError(t, err)
+	require.Equal(t, "echo s3cr3t-t0ken && echo "+encoded, got.Cmd)
+}
@@ -6,18 +6,92 @@
 package utils
 
 import (
+	"encoding/base64"
+	"maps"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"text/template"
 
 	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/message"
 	"github.com/defenseunicorns/maru-runner/src/pkg/variables"
 	"github.com/defenseunicorns/maru-runner/src/types"
 	goyaml "github.com/goccy/go-yaml"
 )
 
+const (
+	// DefaultLeftDelim is the opening delimiter of action templates when a tasks file does not set templateDelims
+	DefaultLeftDelim = "${{"
+	// DefaultRightDelim is the closing delimiter of action templates when a tasks file does not set templateDelims
+	DefaultRightDelim = "}}"
+	// escapedDelimPlaceholder temporarily stands in for escaped opening delimiters while an action is templated
+	escapedDelimPlaceholder = "__MARU_ESCAPED_LEFT_DELIM__"
+)
+
+// templateFuncs are the functions available to action templates on top of the Go template builtins
+var templateFuncs = template.FuncMap{
+	"platforms":    Platforms,
+	"hostPlatform": HostPlatform,
+	"binaryName":   BinaryName,
+	"fileContents": FileContents,
+	"b64encFile":   B64EncFile,
+}
+
+// FileContents returns the contents of a file (without a single trailing newline) for templating into an action. The
+// contents are marked as sensitive so they are masked in everything Maru prints.
+func FileContents(path string) (string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSuffix(strings.TrimSuffix(string(contents), "\n"), "\r")
+	message.AddSensitive(value)
+	return value, nil
+}
+
+// B64EncFile returns the base64 encoded contents of a file for templating into an action (i.e. the data of a
+// Kubernetes Secret). Both the contents and their encoding are marked as sensitive so they are masked in everything
+// Maru prints.
+func B64EncFile(path string) (string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.StdEncoding.EncodeToString(contents)
+	message.AddSensitive(string(contents))
+	message.AddSensitive(encoded)
+	return encoded, nil
+}
+
+// templatePath returns a path given to a template function relative to dir (unless it is absolute or dir is "")
+func templatePath(dir, path string) string {
+	if dir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// TemplateOptions changes how TemplateTaskActionWithOptions templates an action
+type TemplateOptions struct {
+	// Delims are the template delimiters of the tasks file the action is from (nil uses ${{ and }})
+	Delims *types.TemplateDelims
+	// Dir is the directory that relative paths given to fileContents and b64encFile are read from ("" for the directory
+	// maru is run from)
+	Dir string
+}
+
 // TemplateTaskAction templates a task's actions with the given inputs and variables
 func TemplateTaskAction[T any](action types.Action, withs map[string]string, inputs map[string]types.InputParameter, setVarMap variables.SetVariableMap[T]) (types.Action, error) {
+	return TemplateTaskActionWithOptions(action, withs, inputs, setVarMap, TemplateOptions{})
+}
+
+// TemplateTaskActionWithOptions templates a task's actions with the given inputs, variables and options
+//
+// Templates are surrounded by ${{ and }} unless other delimiters are given, and an opening delimiter that is
+// prefixed with a $ (i.e. $${{ github.sha }}) is output literally (without the $) rather than being templated.
+func TemplateTaskActionWithOptions[T any](action types.Action, withs map[string]string, inputs map[string]types.InputParameter, setVarMap variables.SetVariableMap[T], opts TemplateOptions) (types.Action, error) {
 	data := map[string]map[string]string{
 		"inputs":    {},
 		"variables": {},
@@ -45,7 +119,22 @@ func TemplateTaskAction[T any](action types.Action, withs map[string]string, inp
 		return action, err
 	}
 
-	t, err := template.New("template task actions").Option("missingkey=error").Delims("${{", "}}").Parse(string(b))
+	left, right := DefaultLeftDelim, DefaultRightDelim
+	if delims := opts.Delims; delims != nil && delims.Left != "" {
+		left = delims.Left
+	}
+	if delims := opts.Delims; delims != nil && delims.Right != "" {
+		right = delims.Right
+	}
+
+	escaped := strings.ReplaceAll(string(b), "$"+left, escapedDelimPlaceholder)
+
+	// Files are read relative to the directory of the action
+	funcs := maps.Clone(templateFuncs)
+	funcs["fileContents"] = func(path string) (string, error) { return FileContents(templatePath(opts.Dir, path)) }
+	funcs["b64encFile"] = func(path string) (string, error) { return B64EncFile(templatePath(opts.Dir, path)) }
+
+	t, err := template.New("template task actions").Funcs(funcs).Option("missingkey=error").Delims(left, right).Parse(escaped)
 	if err != nil {
 		return action, err
 	}
@@ -56,7 +145,7 @@ func TemplateTaskAction[T any](action types.Action, withs map[string]string, inp
 		return action, err
 	}
 
-	result := templated.String()
+	result := strings.ReplaceAll(templated.String(), escapedDelimPlaceholder, left)
 
 	var templatedAction types.Action
 	if err := goyaml.Unmarshal([]byte(result), &templatedAction); err != nil {
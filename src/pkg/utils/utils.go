@@ -13,8 +13,10 @@ import (
 	"path"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/defenseunicorns/maru-runner/src/config"
+	"github.com/defenseunicorns/maru-runner/src/config/lang"
 	"github.com/defenseunicorns/maru-runner/src/message"
 	"github.com/defenseunicorns/pkg/helpers/v2"
 	goyaml "github.com/goccy/go-yaml"
@@ -24,6 +26,8 @@ import (
 
 const (
 	tmpPathPrefix = "maru-"
+	// remoteCheckTimeout bounds how long checking that a remote file can be fetched takes
+	remoteCheckTimeout = 10 * time.Second
 )
 
 // Regex to match the GitLab repo files api, test: https://regex101.com/r/mBXuyM/1
@@ -84,19 +88,29 @@ func FormatEnvVar(name, value string) string {
 
 // ReadYaml reads a yaml file and unmarshals it into a given config.
 func ReadYaml(path string, destConfig any) error {
+	_, err := ReadYamlDigest(path, destConfig)
+	return err
+}
+
+// ReadYamlDigest reads a yaml file and unmarshals it into a given config, returning the digest of its contents
+func ReadYamlDigest(path string, destConfig any) (string, error) {
 	file, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("cannot %s", err.Error())
+		return "", fmt.Errorf("cannot %s", err.Error())
+	}
+
+	if err := CheckAliases(file); err != nil {
+		return "", fmt.Errorf("cannot unmarshal %s: %w", path, err)
 	}
 
 	err = goyaml.Unmarshal(file, destConfig)
 	if err != nil {
 		errStr := err.Error()
 		lines := strings.SplitN(errStr, "\n", 2)
-		return fmt.Errorf("cannot unmarshal %s: %s", path, lines[0])
+		return "", fmt.Errorf("cannot unmarshal %s: %s", path, lines[0])
 	}
 
-	return nil
+	return Digest(file), nil
 }
 
 // MakeTempDir creates a temp directory with the maru- prefix.
@@ -155,51 +169,188 @@ func JoinURLRepoPath(currentURL *url.URL, includeFilePath string) (*url.URL, err
 	return currentURL, nil
 }
 
-// ReadRemoteYaml makes a get request to retrieve a given file from a URL
+// ReadRemoteYaml makes a get request to retrieve a given file from a URL, using a local cache to avoid re-downloading
+// unchanged files
 func ReadRemoteYaml(location string, destConfig any, auth map[string]string) (err error) {
-	// Send an HTTP GET request to fetch the content of the remote file
-	req, err := http.NewRequest("GET", location, nil)
+	_, err = ReadRemoteYamlDigest(location, "", destConfig, auth)
+	return err
+}
+
+// ReadRemoteYamlDigest reads a yaml file from a URL in the same way as ReadRemoteYaml, verifying its contents against a
+// pinned digest (if one is provided) and returning the digest of its contents
+func ReadRemoteYamlDigest(location, digest string, destConfig any, auth map[string]string) (string, error) {
+	body, err := fetchRemoteFile(location, digest, auth)
 	if err != nil {
-		return fmt.Errorf("unable to initialize request for %s: %w", location, err)
+		return "", err
 	}
 
-	parsedLocation, err := url.Parse(location)
+	if err := CheckAliases(body); err != nil {
+		return "", fmt.Errorf("failed unmarshalling contents of %s: %w", location, err)
+	}
+
+	// Deserialize the content into the includedTasksFile
+	err = goyaml.Unmarshal(body, destConfig)
 	if err != nil {
-		return fmt.Errorf("failed parsing URL %s: %w", location, err)
+		return "", fmt.Errorf("failed unmarshalling contents of %s: %w", location, err)
 	}
-	if token, ok := auth[parsedLocation.Host]; ok {
-		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
-	} else {
-		token, err := keyring.Get(config.KeyringService, parsedLocation.Host)
+
+	return Digest(body), nil
+}
+
+// fetchRemoteFile retrieves the contents of a given URL, making the request conditional on any cached copy
+func fetchRemoteFile(location, digest string, auth map[string]string) ([]byte, error) {
+	token := remoteFileToken(location, auth)
+	cachedBody, cachedEntry, cacheErr := readIncludeCache(location, token, digest)
+	if cacheErr != nil && !os.IsNotExist(cacheErr) {
+		message.SLog.Debug(fmt.Sprintf("ignoring cached copy of %s: %s", location, cacheErr.Error()))
+	}
+
+	// A cached copy that matches a pinned digest can never change so there is no need to ask the server
+	if cacheErr == nil && digest != "" {
+		message.SLog.Debug(fmt.Sprintf("using cached copy of %s matching %s", location, digest))
+		return cachedBody, nil
+	}
+
+	// Offline, the cached copy is used without asking the server if it is still current
+	if config.Offline {
+		if cacheErr != nil {
+			return nil, fmt.Errorf("%s is not in the cache (run once without --offline to cache it): %w", location, lang.ErrOffline)
+		}
+		message.SLog.Debug(fmt.Sprintf("offline, using cached copy of %s", location))
+		return cachedBody, nil
+	}
+
+	var (
+		resp *http.Response
+		body []byte
+	)
+	for attempt := 1; ; attempt++ {
+		req, err := newRemoteFileRequest(location, token, cachedEntry)
 		if err != nil {
-			message.SLog.Debug(fmt.Sprintf("unable to lookup host %s in keyring: %s", parsedLocation.Host, err.Error()))
-		} else {
-			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+			return nil, err
+		}
+		// Pick up where any earlier (interrupted) download of the file left off
+		download, err := newPartialDownload(req, location, token)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			if cacheErr == nil {
+				message.SLog.Warn(fmt.Sprintf("Unable to reach %s, using cached copy: %s", location, err.Error()))
+				return cachedBody, nil
+			}
+			return nil, fmt.Errorf("unable to make request for %s: %w", location, err)
+		}
+
+		if resp.StatusCode == http.StatusNotModified && cacheErr == nil {
+			resp.Body.Close()
+			message.SLog.Debug(fmt.Sprintf("%s has not been modified, using cached copy", location))
+			return cachedBody, nil
+		}
+
+		// The partial download no longer lines up with the file so start over
+		if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && download.resumable() && attempt < maxDownloadAttempts {
+			resp.Body.Close()
+			download.discard()
+			continue
 		}
+
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed getting %s: %s", location, resp.Status)
+		}
+
+		// Read the content of the response body
+		body, err = download.read(resp)
+		resp.Body.Close()
+		if err == nil {
+			break
+		}
+		if !download.resumable() || attempt >= maxDownloadAttempts {
+			return nil, fmt.Errorf("failed reading contents of %s: %w", location, err)
+		}
+		message.SLog.Warn(fmt.Sprintf("Resuming download of %s (%s)", location, err.Error()))
+	}
+
+	if err := verifyDigest(location, digest, body); err != nil {
+		return nil, err
 	}
-	req.Header.Add("Accept", "application/vnd.github.raw+json")
 
-	resp, err := http.DefaultClient.Do(req)
+	entry := includeCacheEntry{
+		URL:          location,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Digest:       Digest(body),
+	}
+	if err := writeIncludeCache(entry, token, body); err != nil {
+		message.SLog.Debug(fmt.Sprintf("unable to cache %s: %s", location, err.Error()))
+	}
+
+	return body, nil
+}
+
+// CheckRemoteFile checks that a remote file can be fetched (with the same authentication as includes) without using
+// the cache
+func CheckRemoteFile(location string, auth map[string]string) error {
+	if config.Offline {
+		return fmt.Errorf("unable to check %s: %w", location, lang.ErrOffline)
+	}
+	req, err := newRemoteFileRequest(location, remoteFileToken(location, auth), nil)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: remoteCheckTimeout}
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("unable to make request for %s: %w", location, err)
 	}
 	defer resp.Body.Close()
-
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("failed getting %s: %s", location, resp.Status)
 	}
+	return nil
+}
 
-	// Read the content of the response body
-	body, err := io.ReadAll(resp.Body)
+// remoteFileToken returns the token a remote file is fetched with, from the auth for its host or the keyring ("" for none)
+func remoteFileToken(location string, auth map[string]string) string {
+	parsedLocation, err := url.Parse(location)
+	if err != nil {
+		return ""
+	}
+	if token, ok := auth[parsedLocation.Host]; ok {
+		return token
+	}
+	token, err := keyring.Get(config.KeyringService, parsedLocation.Host)
 	if err != nil {
-		return fmt.Errorf("failed reading contents of %s: %w", location, err)
+		message.SLog.Debug(fmt.Sprintf("unable to lookup host %s in keyring: %s", parsedLocation.Host, err.Error()))
+		return ""
 	}
+	return token
+}
 
-	// Deserialize the content into the includedTasksFile
-	err = goyaml.Unmarshal(body, destConfig)
+// newRemoteFileRequest returns the request for a remote file, authenticating it with the token (if there is one) and
+// making it conditional on the cached copy (if there is one)
+func newRemoteFileRequest(location, token string, cachedEntry *includeCacheEntry) (*http.Request, error) {
+	// Send an HTTP GET request to fetch the content of the remote file
+	req, err := http.NewRequest("GET", location, nil)
 	if err != nil {
-		return fmt.Errorf("failed unmarshalling contents of %s: %w", location, err)
+		return nil, fmt.Errorf("unable to initialize request for %s: %w", location, err)
 	}
 
-	return nil
+	if token != "" {
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+	req.Header.Add("Accept", "application/vnd.github.raw+json")
+
+	if cachedEntry != nil {
+		if cachedEntry.ETag != "" {
+			req.Header.Add("If-None-Match", cachedEntry.ETag)
+		}
+		if cachedEntry.LastModified != "" {
+			req.Header.Add("If-Modified-Since", cachedEntry.LastModified)
+		}
+	}
+	return req, nil
 }
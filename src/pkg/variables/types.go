@@ -36,4 +36,7 @@ type SetVariable[T any] struct {
 
 // ExtraVariableInfo carries any additional information that may be desired through variables passed and set by actions (available to library users).
 type ExtraVariableInfo struct {
+	Session   bool   `json:"session,omitempty" jsonschema:"description=Whether to remember the value of this variable between runs that use the same --session"`
+	Sensitive bool   `json:"sensitive,omitempty" jsonschema:"description=Whether to keep the value of this variable out of the run results and run history (always true for variables read from a Secret with fromK8s)"`
+	FromK8s   string `json:"fromK8s,omitempty" jsonschema:"description=Read the value of this variable at the start of a run from a key of a ConfigMap or Secret in the current Kubernetes context unless it is set with --set or an environment variable ([configmap/|secret/]<namespace>/<name>/<key>),example=platform/app-config/domain,example=secret/platform/app-db/password"`
 }
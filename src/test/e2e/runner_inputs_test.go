@@ -152,4 +152,40 @@ func TestRunnerInputs(t *testing.T) {
 		require.NoError(t, err, stdOut, stdErr)
 		require.Contains(t, stdErr, "default-value")
 	})
+
+	t.Run("test that validate reports every broken task reference", func(t *testing.T) {
+		t.Parallel()
+
+		stdOut, stdErr, err := e2e.Maru("validate", "--file", "src/test/tasks/inputs/tasks.yaml")
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "is missing required inputs: no-default-and-required")
+		require.Contains(t, stdErr, "does not have an input named extra")
+		require.Contains(t, stdErr, "Found 2 problems in the task file")
+	})
+
+	t.Run("test that validate reports unused includes", func(t *testing.T) {
+		t.Parallel()
+
+		stdOut, stdErr, err := e2e.Maru("validate", "--file", "src/test/tasks/unused-include.yaml")
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, `include "unused"`)
+		require.Contains(t, stdErr, "No problems found")
+
+		stdOut, stdErr, err = e2e.Maru("validate", "--file", "src/test/tasks/unused-include.yaml", "--unused-includes", "error")
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, `include "unused"`)
+		require.Contains(t, stdErr, "Found 1 problems in the task file")
+
+		stdOut, stdErr, err = e2e.Maru("validate", "--file", "src/test/tasks/unused-include.yaml", "--unused-includes", "ignore")
+		require.NoError(t, err, stdOut, stdErr)
+		require.NotContains(t, stdErr, `include "unused"`)
+	})
+
+	t.Run("test that validate succeeds for valid task references", func(t *testing.T) {
+		t.Parallel()
+
+		stdOut, stdErr, err := e2e.Maru("validate", "--file", "src/test/tasks/conditionals/tasks.yaml")
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "No problems found")
+	})
 }
@@ -5,9 +5,15 @@
 package test
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -187,6 +193,7 @@ func TestTaskRunner(t *testing.T) {
 		t.Parallel()
 		stdOut, stdErr, err := e2e.Maru("run", "foobar", "--file", "src/test/tasks/tasks.yaml")
 		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "Using include foo from")
 		require.Contains(t, stdErr, "echo foo")
 		require.Contains(t, stdErr, "echo bar")
 	})
@@ -360,6 +367,9 @@ func TestTaskRunner(t *testing.T) {
 		require.NoError(t, err, stdOut, stdErr)
 		require.Contains(t, stdErr, "echo foo")
 		require.Contains(t, stdErr, "echo bar")
+		// The actions of included tasks say which include they came from
+		require.Contains(t, stdErr, "[bar:")
+		require.NotContains(t, stdErr, "[foo:")
 	})
 
 	t.Run("test calling a remote included task directly", func(t *testing.T) {
@@ -403,6 +413,61 @@ func TestTaskRunner(t *testing.T) {
 		require.Contains(t, stdErr, "Skipping action included-task")
 	})
 
+	t.Run("test skipped actions are reported in the results", func(t *testing.T) {
+		t.Parallel()
+		resultsFile := filepath.Join(t.TempDir(), "results.json")
+		stdOut, stdErr, err := e2e.Maru("run", "false-conditional-nested-task-comp-var-inputs", "--file", "src/test/tasks/conditionals/tasks.yaml", "--results-file", resultsFile)
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "Ran 2 actions: 1 succeeded, 1 skipped, 0 failed")
+
+		b, err := os.ReadFile(resultsFile)
+		require.NoError(t, err)
+		var results struct {
+			Status  string
+			Actions []struct {
+				Task   string
+				Name   string
+				Status string
+			}
+		}
+		require.NoError(t, json.Unmarshal(b, &results))
+		require.Equal(t, "succeeded", results.Status)
+		require.Len(t, results.Actions, 2)
+		require.Equal(t, "included-task-with-inputs", results.Actions[0].Task)
+		require.Equal(t, "skipped", results.Actions[0].Status)
+		require.Equal(t, "false-conditional-nested-task-comp-var-inputs", results.Actions[1].Task)
+		require.Equal(t, "succeeded", results.Actions[1].Status)
+	})
+
+	t.Run("test writing a trace of a run", func(t *testing.T) {
+		t.Parallel()
+		traceFile := filepath.Join(t.TempDir(), "trace.json")
+		stdOut, stdErr, err := e2e.Maru("run", "false-conditional-nested-task-comp-var-inputs", "--file", "src/test/tasks/conditionals/tasks.yaml", "--trace-file", traceFile)
+		require.NoError(t, err, stdOut, stdErr)
+
+		b, err := os.ReadFile(traceFile)
+		require.NoError(t, err)
+		var trace struct {
+			TraceEvents []struct {
+				Name string
+				Cat  string
+				Ph   string
+				Dur  int64
+				Args map[string]any
+			}
+			OtherData map[string]string
+		}
+		require.NoError(t, json.Unmarshal(b, &trace))
+		require.Equal(t, "succeeded", trace.OtherData["status"])
+		tasks := []string{}
+		for _, event := range trace.TraceEvents {
+			if event.Ph == "X" && event.Cat == "task" {
+				tasks = append(tasks, event.Name)
+			}
+		}
+		require.Equal(t, []string{"included-task-with-inputs", "false-conditional-nested-task-comp-var-inputs"}, tasks)
+	})
+
 	t.Run("test calling a task with true conditional comparing variables", func(t *testing.T) {
 		t.Parallel()
 		stdOut, stdErr, err := e2e.Maru("run", "true-conditional-task", "--file", "src/test/tasks/conditionals/tasks.yaml")
@@ -502,4 +567,488 @@ func TestTaskRunner(t *testing.T) {
 		require.Error(t, err, stdOut, stdErr)
 		require.Contains(t, stdErr, "task include \"foo\" attempted to be redefined")
 	})
+
+	t.Run("cache ls and clean", func(t *testing.T) {
+		t.Parallel()
+
+		cacheDir := t.TempDir()
+		stdOut, stdErr, err := e2e.Maru("cache", "ls", "--cache-dir", cacheDir)
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "is empty")
+
+		stdOut, stdErr, err = e2e.Maru("cache", "clean", "--cache-dir", cacheDir)
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "Removed 0 cache entries")
+	})
+
+	t.Run("patch action", func(t *testing.T) {
+		t.Parallel()
+
+		outDir := t.TempDir()
+		stdOut, stdErr, err := e2e.Maru("run", "--file", "src/test/tasks/patch/tasks.yaml", "--set", "OUT_DIR="+outDir)
+		require.NoError(t, err, stdOut, stdErr)
+
+		values, err := os.ReadFile(filepath.Join(outDir, "values.yaml"))
+		require.NoError(t, err)
+		require.Equal(t, "image:\n  repository: ghcr.io/example/app\n  tag: 2.0.0\nreplicas: 3\n", string(values))
+
+		config, err := os.ReadFile(filepath.Join(outDir, "config.json"))
+		require.NoError(t, err)
+		require.Contains(t, string(config), "9090")
+
+		stdOut, stdErr, err = e2e.Maru("run", "failed-test", "--file", "src/test/tasks/patch/tasks.yaml", "--set", "OUT_DIR="+outDir)
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "testing value /replicas")
+	})
+
+	t.Run("until output", func(t *testing.T) {
+		t.Parallel()
+
+		stdOut, stdErr, err := e2e.Maru("run", "until-output", "--file", "src/test/tasks/tasks.yaml")
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "matched [Server started on port 8080]")
+	})
+
+	t.Run("expect", func(t *testing.T) {
+		t.Parallel()
+
+		stdOut, stdErr, err := e2e.Maru("run", "expect", "--file", "src/test/tasks/tasks.yaml", "--set", "PASSPHRASE=hunter2")
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "accepted [y] passphrase [hunter2]")
+	})
+
+	t.Run("session variables", func(t *testing.T) {
+		t.Parallel()
+		session := fmt.Sprintf("e2e-%d", time.Now().UnixNano())
+		t.Cleanup(func() {
+			_, _, _ = e2e.Maru("session", "rm", session)
+		})
+
+		stdOut, stdErr, err := e2e.Maru("run", "create", "--file", "src/test/tasks/session.yaml", "--session", session, "--set", "CLUSTER=mine", "--set", "OTHER=x")
+		require.NoError(t, err, stdOut, stdErr)
+
+		// Only the variables marked with session are remembered
+		stdOut, stdErr, err = e2e.Maru("run", "--file", "src/test/tasks/session.yaml", "--session", session)
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "cluster=mine other=other kube=/tmp/kubeconfig-mine")
+
+		// Variables set on the command line take precedence over the session
+		stdOut, stdErr, err = e2e.Maru("run", "--file", "src/test/tasks/session.yaml", "--session", session, "--set", "CLUSTER=theirs")
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "cluster=theirs other=other kube=/tmp/kubeconfig-mine")
+
+		stdOut, stdErr, err = e2e.Maru("run", "--file", "src/test/tasks/session.yaml")
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "cluster=default-cluster other=other kube=")
+	})
+
+	t.Run("diff runs", func(t *testing.T) {
+		t.Parallel()
+		greenFile := filepath.Join(t.TempDir(), "green.json")
+		redFile := filepath.Join(t.TempDir(), "red.json")
+
+		stdOut, stdErr, err := e2e.Maru("run", "--file", "src/test/tasks/diff-runs.yaml", "--results-file", greenFile, "--run-history", "0")
+		require.NoError(t, err, stdOut, stdErr)
+		stdOut, stdErr, err = e2e.Maru("run", "--file", "src/test/tasks/diff-runs.yaml", "--results-file", redFile, "--run-history", "0", "--set", "MODE=bad")
+		require.Error(t, err, stdOut, stdErr)
+
+		stdOut, stdErr, err = e2e.Maru("diff-runs", greenFile, redFile)
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "succeeded -> failed")
+		require.Contains(t, stdErr, "succeeded -> not reached")
+		require.Contains(t, stdErr, "mode is good")
+		require.Contains(t, stdErr, "mode is bad")
+		require.Contains(t, stdErr, "Variable")
+	})
+
+	t.Run("doctor", func(t *testing.T) {
+		t.Parallel()
+		stdOut, stdErr, err := e2e.Maru("doctor", "--file", "src/test/tasks/conditionals/tasks.yaml")
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdOut+stdErr, "tasks file")
+		require.Contains(t, stdErr, "checks passed")
+
+		stdOut, stdErr, err = e2e.Maru("doctor", "--file", "src/test/tasks/missing.yaml")
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "checks failed")
+	})
+
+	t.Run("vars", func(t *testing.T) {
+		t.Parallel()
+
+		stdOut, stdErr, err := e2e.Maru("vars", "--file", "src/test/tasks/vars.yaml")
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "input of greet")
+		require.Contains(t, stdErr, `variable "UNDEFINED" is never defined (referenced by default[0])`)
+		require.Contains(t, stdErr, `variable "UNUSED" is never used`)
+		require.NotContains(t, stdErr, `variable "USED"`)
+		require.NotContains(t, stdErr, `variable "OUTPUT"`)
+	})
+
+	t.Run("template delimiters", func(t *testing.T) {
+		t.Parallel()
+
+		stdOut, stdErr, err := e2e.Maru("run", "default", "--file", "src/test/tasks/template-delims/tasks.yaml")
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "default=hello escaped=${{ github.sha }}")
+
+		stdOut, stdErr, err = e2e.Maru("run", "custom", "--file", "src/test/tasks/template-delims/tasks.yaml")
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "image=nginx helm={{ .Values.image }} gha=${{ github.sha }} escaped=[[ literal ]]")
+	})
+
+	t.Run("ansi output", func(t *testing.T) {
+		t.Parallel()
+
+		stdOut, stdErr, err := e2e.Maru("run", "ansi-output", "--file", "src/test/tasks/tasks.yaml")
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "stripped=[green] preserved-length=[14]")
+	})
+
+	t.Run("run metadata env", func(t *testing.T) {
+		t.Parallel()
+
+		stdOut, stdErr, err := e2e.Maru("run", "run-metadata", "--file", "src/test/tasks/tasks.yaml")
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "run id set [yes]")
+		require.Contains(t, stdErr, "task=run-metadata index=1 attempt=1")
+		require.Contains(t, stdErr, "task=run-metadata index=1 attempt=2")
+
+		// Every attempt of the action shares one idempotency key
+		keys := regexp.MustCompile(`idempotency key \[([0-9a-f]{32})\]`).FindAllStringSubmatch(stdErr, -1)
+		require.Len(t, keys, 2)
+		require.Equal(t, keys[0][1], keys[1][1])
+	})
+
+	t.Run("task timeout", func(t *testing.T) {
+		t.Parallel()
+
+		stdOut, stdErr, err := e2e.Maru("run", "task-timeout", "--file", "src/test/tasks/tasks.yaml")
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "task task-timeout timed out after 1 seconds")
+	})
+
+	t.Run("run timeout", func(t *testing.T) {
+		t.Parallel()
+
+		stdOut, stdErr, err := e2e.Maru("run", "task-timeout", "--file", "src/test/tasks/tasks.yaml", "--timeout", "100ms")
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "run timed out after 100ms")
+	})
+
+	t.Run("pause", func(t *testing.T) {
+		t.Parallel()
+
+		stdOut, stdErr, err := e2e.Maru("run", "pause", "--file", "src/test/tasks/tasks.yaml")
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "Pause for 100ms (let things settle)")
+		require.Contains(t, stdErr, "after the pause")
+
+		stdOut, stdErr, err = e2e.Maru("run", "pause-timeout", "--file", "src/test/tasks/tasks.yaml")
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "task pause-timeout timed out after 1 seconds")
+	})
+
+	t.Run("artifacts", func(t *testing.T) {
+		t.Parallel()
+
+		workDir, artifactsDir := t.TempDir(), t.TempDir()
+		stdOut, stdErr, err := e2e.Maru("run", "artifacts", "--file", "src/test/tasks/tasks.yaml", "--set", "ARTIFACTS_WORK_DIR="+workDir, "--artifacts-dir", artifactsDir)
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "Collected 2 artifacts")
+
+		logs, err := filepath.Glob(filepath.Join(artifactsDir, "*", "artifacts-0", "logs", "app.log"))
+		require.NoError(t, err)
+		require.Len(t, logs, 1)
+		outputs, err := filepath.Glob(filepath.Join(artifactsDir, "*", "artifacts-0", "output.log"))
+		require.NoError(t, err)
+		require.Len(t, outputs, 1)
+		output, err := os.ReadFile(outputs[0])
+		require.NoError(t, err)
+		require.Contains(t, string(output), "failing on purpose")
+	})
+
+	t.Run("migrate string boolean conditions", func(t *testing.T) {
+		t.Parallel()
+
+		tasksFile := filepath.Join(t.TempDir(), "tasks.yaml")
+		contents := `tasks:
+  - name: default
+    actions:
+      - cmd: echo "skipped-$((40+2))"
+        if: "false" # keep the comment
+      - cmd: echo "typed action ran"
+        if: true
+`
+		require.NoError(t, os.WriteFile(tasksFile, []byte(contents), 0600))
+		stdOut, stdErr, err := e2e.Maru("run", "--file", tasksFile)
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "string-boolean-if")
+		require.Contains(t, stdErr, "typed action ran")
+		require.NotContains(t, stdErr, "skipped-42")
+
+		stdOut, stdErr, err = e2e.Maru("migrate", "--file", tasksFile, "--check")
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "string-boolean-if")
+
+		stdOut, stdErr, err = e2e.Maru("migrate", "--file", tasksFile)
+		require.NoError(t, err, stdOut, stdErr)
+		migrated, err := os.ReadFile(tasksFile)
+		require.NoError(t, err)
+		require.Contains(t, string(migrated), "if: false # keep the comment\n")
+
+		stdOut, stdErr, err = e2e.Maru("run", "--file", tasksFile)
+		require.NoError(t, err, stdOut, stdErr)
+		require.NotContains(t, stdErr, "string-boolean-if")
+		require.NotContains(t, stdErr, "skipped-42")
+	})
+
+	t.Run("tasks file changed during the run", func(t *testing.T) {
+		t.Parallel()
+
+		tasksFile := filepath.Join(t.TempDir(), "tasks.yaml")
+		contents := `tasks:
+  - name: default
+    actions:
+      - cmd: echo "# changed" >> ${TASKS_FILE}
+      - task: next
+  - name: next
+    actions:
+      - cmd: echo "next task ran"
+`
+		require.NoError(t, os.WriteFile(tasksFile, []byte(contents), 0600))
+		stdOut, stdErr, err := e2e.Maru("run", "--file", tasksFile, "--set", "TASKS_FILE="+tasksFile)
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "continues")
+		require.Contains(t, stdErr, "next task ran")
+
+		require.NoError(t, os.WriteFile(tasksFile, []byte(contents), 0600))
+		stdOut, stdErr, err = e2e.Maru("run", "--file", tasksFile, "--set", "TASKS_FILE="+tasksFile, "--on-change", "fail")
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "stopping")
+		require.NotContains(t, stdErr, "next task ran")
+	})
+
+	t.Run("shell strict", func(t *testing.T) {
+		t.Parallel()
+
+		stdOut, stdErr, err := e2e.Maru("run", "shell-strict", "--file", "src/test/tasks/tasks.yaml")
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "MARU_UNSET_IN_STRICT_MODE")
+		require.Contains(t, stdErr, "shellStrict: script exited with status")
+		require.NotContains(t, stdErr, "should not run")
+
+		stdOut, stdErr, err = e2e.Maru("run", "shell-not-strict", "--file", "src/test/tasks/tasks.yaml")
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "did run")
+	})
+
+	t.Run("failed line", func(t *testing.T) {
+		t.Parallel()
+
+		stdOut, stdErr, err := e2e.Maru("run", "failed-line", "--file", "src/test/tasks/tasks.yaml")
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "line 2 exited with status 1")
+		require.NotContains(t, stdErr, "should not run")
+	})
+
+	t.Run("task metadata", func(t *testing.T) {
+		t.Parallel()
+
+		stdOut, stdErr, err := e2e.Maru("run", "--list=md", "--file", "src/test/tasks/metadata.yaml")
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdOut, "| Name | Description | Owner | Links |")
+		require.Contains(t, stdOut, "| **deploy** | Deploys the app | @jane (platform) | docs: https://example.com/docs/deploy, runbook: https://example.com/runbooks/deploy |")
+		require.Contains(t, stdOut, "| **unowned** |  |  |  |")
+
+		// Only the innermost failing task with metadata is reported
+		stdOut, stdErr, err = e2e.Maru("run", "deploy", "--file", "src/test/tasks/metadata.yaml")
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "Task migrate failed, it is owned by data, runbook: https://example.com/runbooks/migrate")
+		require.NotContains(t, stdErr, "Task deploy failed")
+	})
+
+	t.Run("cross-compilation helpers", func(t *testing.T) {
+		t.Parallel()
+
+		stdOut, stdErr, err := e2e.Maru("run", "--file", "src/test/tasks/platforms.yaml")
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "app_linux_amd64")
+		require.Contains(t, stdErr, "app_linux_arm64")
+		require.Contains(t, stdErr, "app_windows_amd64.exe")
+	})
+
+	t.Run("file helpers mask what they read", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		secret := filepath.Join(dir, "password")
+		require.NoError(t, os.WriteFile(secret, []byte("correct-horse-battery\n"), 0600))
+		// Relative paths are read from the directory of the tasks file rather than where maru is run from
+		tasks := "tasks:\n  - name: default\n    actions:\n      - cmd: echo 'pw ${{ fileContents \"password\" }}' && echo '${{ b64encFile \"" + secret + "\" }}'\n"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "tasks.yaml"), []byte(tasks), 0600))
+
+		stdOut, stdErr, err := e2e.Maru("run", "--file", filepath.Join(dir, "tasks.yaml"))
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "pw **sanitized**")
+		require.NotContains(t, stdErr, "correct-horse-battery")
+		require.NotContains(t, stdErr, "Y29ycmVjdC1ob3JzZS1iYXR0ZXJ5Cg==")
+	})
+
+	t.Run("environment too large", func(t *testing.T) {
+		t.Parallel()
+		if runtime.GOOS != "linux" {
+			t.Skip("the size of a single variable is only limited on linux")
+		}
+
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("HUGE="+strings.Repeat("x", 200*1024)+"\n"), 0600))
+		tasks := "tasks:\n  - name: default\n    envPath: .env\n    actions:\n      - cmd: echo never runs\n        maxRetries: 3\n"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "tasks.yaml"), []byte(tasks), 0600))
+
+		stdOut, stdErr, err := e2e.Maru("run", "--file", filepath.Join(dir, "tasks.yaml"))
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "HUGE")
+		require.Contains(t, stdErr, "(env")
+		require.NotContains(t, stdErr, "retries")
+	})
+
+	t.Run("output files", func(t *testing.T) {
+		t.Parallel()
+
+		stdOut, stdErr, err := e2e.Maru("run", "--file", "src/test/tasks/outputs.yaml")
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "built-app-1.2.3")
+
+		stdOut, stdErr, err = e2e.Maru("run", "invalid", "--file", "src/test/tasks/outputs.yaml")
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "NAME=value")
+	})
+
+	t.Run("generated tasks", func(t *testing.T) {
+		t.Parallel()
+
+		stdOut, stdErr, err := e2e.Maru("run", "--file", "src/test/tasks/generate.yaml")
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "testing-api")
+		require.Contains(t, stdErr, "testing-web")
+		require.Contains(t, stdErr, "all-modules-tested")
+
+		stdOut, stdErr, err = e2e.Maru("run", "invalid", "--file", "src/test/tasks/generate.yaml")
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "MARU_TASKS of action 0")
+	})
+
+	t.Run("offline", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		tasks := `includes:
+  - remote: https://maru-offline-test.invalid/tasks.yaml
+tasks:
+  - name: default
+    actions:
+      - task: remote:default
+  - name: wait
+    actions:
+      - wait:
+          network:
+            protocol: https
+            address: maru-offline-test.invalid/healthz
+`
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "tasks.yaml"), []byte(tasks), 0600))
+
+		// Includes that aren't cached and waits for hosts that can't be resolved fail right away
+		stdOut, stdErr, err := e2e.Maru("run", "--offline", "--cache-dir", filepath.Join(dir, "cache"), "--file", filepath.Join(dir, "tasks.yaml"))
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "--offline")
+
+		stdOut, stdErr, err = e2e.Maru("run", "wait", "--offline", "--file", filepath.Join(dir, "tasks.yaml"))
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "not waiting for")
+	})
+
+	t.Run("failure hints", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		tasks := `tasks:
+  - name: default
+    actions:
+      - cmd: |
+          echo "curl: (6) Could not resolve host: registry.example.com" >&2
+          exit 6
+        maxRetries: 1
+`
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "tasks.yaml"), []byte(tasks), 0600))
+		resultsFile := filepath.Join(dir, "results.json")
+
+		stdOut, stdErr, err := e2e.Maru("run", "--file", filepath.Join(dir, "tasks.yaml"), "--results-file", resultsFile)
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "All 2 attempts")
+		require.Contains(t, stdErr, "Hint:")
+		results, err := os.ReadFile(resultsFile)
+		require.NoError(t, err)
+		require.Contains(t, string(results), `"cause": "dns"`)
+	})
+
+	t.Run("uses", func(t *testing.T) {
+		t.Parallel()
+
+		stdOut, stdErr, err := e2e.Maru("run", "--file", "src/test/tasks/uses/tasks.yaml")
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "compiling for [linux]")
+		require.Contains(t, stdErr, "compiling for [darwin]")
+		require.Contains(t, stdErr, "uploading from [deploy.yaml:upload]")
+		require.Contains(t, stdErr, "included as build.yaml")
+		require.NotContains(t, stdErr, "should not run")
+
+		stdOut, stdErr, err = e2e.Maru("run", "missing", "--file", "src/test/tasks/uses/tasks.yaml")
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, `task "missing": actions[0]: task nope`)
+	})
+
+	t.Run("invalid task names", func(t *testing.T) {
+		t.Parallel()
+
+		stdOut, stdErr, err := e2e.Maru("run", "--file", "src/test/tasks/invalid-task-names.yaml")
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, `tasks[1]: task name "lib:build"`)
+		require.Contains(t, stdErr, `"build all"`)
+		require.NotContains(t, stdErr, "should not run")
+	})
+
+	t.Run("yaml anchors", func(t *testing.T) {
+		t.Parallel()
+
+		stdOut, stdErr, err := e2e.Maru("run", "--file", "src/test/tasks/anchors/tasks.yaml")
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "[hello anchors]")
+		require.Contains(t, stdErr, "[hi merged]")
+		require.Equal(t, 2, strings.Count(stdErr, "[repeated]\n"))
+
+		stdOut, stdErr, err = e2e.Maru("validate", "--file", "src/test/tasks/anchors/unresolved.yaml")
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "line 4: alias *missing")
+		require.NotContains(t, stdErr, "should not run")
+	})
+
+	t.Run("new task-lib", func(t *testing.T) {
+		t.Parallel()
+		dir := filepath.Join(t.TempDir(), "shared-tasks")
+
+		stdOut, stdErr, err := e2e.Maru("new", "task-lib", dir)
+		require.NoError(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "Created task library shared-tasks")
+
+		// The generated tests pass and the library validates
+		stdOut, stdErr, err = e2e.Maru("run", "--file", filepath.Join(dir, "tests", "tasks.yaml"))
+		require.NoError(t, err, stdOut, stdErr)
+		stdOut, stdErr, err = e2e.Maru("validate", "--file", filepath.Join(dir, "tasks.yaml"), "--unused-includes", "error")
+		require.NoError(t, err, stdOut, stdErr)
+
+		stdOut, stdErr, err = e2e.Maru("new", "task-lib", dir)
+		require.Error(t, err, stdOut, stdErr)
+		require.Contains(t, stdErr, "refusing to overwrite existing files")
+	})
 }
@@ -15,10 +15,23 @@ type ActionDefaults struct {
 	Mute            bool                 `json:"mute,omitempty" jsonschema:"description=Hide the output of commands during execution (default false)"`
 	MaxTotalSeconds int                  `json:"maxTotalSeconds,omitempty" jsonschema:"description=Default timeout in seconds for commands (default to 0, no timeout)"`
 	MaxRetries      int                  `json:"maxRetries,omitempty" jsonschema:"description=Retry commands given number of times if they fail (default 0)"`
+	MaxOutputBytes  int                  `json:"maxOutputBytes,omitempty" jsonschema:"description=Default limit on the combined stdout and stderr bytes of commands before they are killed (defaults to 0\\, no limit)"`
+	ANSI            ANSIMode             `json:"ansi,omitempty" jsonschema:"description=Default handling of ANSI escape sequences in captured command output (default preserve),enum=strip,enum=preserve"`
 	Dir             string               `json:"dir,omitempty" jsonschema:"description=Working directory for commands (default CWD)"`
 	Shell           exec.ShellPreference `json:"shell,omitempty" jsonschema:"description=(cmd only) Indicates a preference for a shell for the provided cmd to be executed in on supported operating systems"`
+	ShellStrict     bool                 `json:"shellStrict,omitempty" jsonschema:"description=(cmd only) Run commands in strict mode so they stop at the first failing command or unset variable (default false)"`
 }
 
+// ANSIMode controls how ANSI escape sequences in captured command output are handled
+type ANSIMode string
+
+const (
+	// ANSIStrip removes ANSI escape sequences and progress bar redraws from captured output (and replaces invalid UTF-8)
+	ANSIStrip ANSIMode = "strip"
+	// ANSIPreserve keeps captured output exactly as the command wrote it (the default)
+	ANSIPreserve ANSIMode = "preserve"
+)
+
 // BaseAction represents a single action to run and represents an interface shared with Zarf
 type BaseAction[T any] struct {
 	Description     string                  `json:"description,omitempty" jsonschema:"description=Description of the action to be displayed during package execution instead of the command"`
@@ -28,11 +41,23 @@ type BaseAction[T any] struct {
 	Mute            *bool                   `json:"mute,omitempty" jsonschema:"description=Hide the output of the command during package deployment (default false)"`
 	MaxTotalSeconds *int                    `json:"maxTotalSeconds,omitempty" jsonschema:"description=Timeout in seconds for the command (default to 0, no timeout for cmd actions and 300, 5 minutes for wait actions)"`
 	MaxRetries      *int                    `json:"maxRetries,omitempty" jsonschema:"description=Retry the command if it fails up to given number of times (default 0)"`
+	MaxOutputBytes  *int                    `json:"maxOutputBytes,omitempty" jsonschema:"description=Kill the command once its combined stdout and stderr exceeds the given number of bytes (defaults to 0\\, no limit)"`
+	ANSI            *ANSIMode               `json:"ansi,omitempty" jsonschema:"description=Whether to strip or preserve ANSI escape sequences (colors\\, progress bars) in the captured output used for setVariables and logs (default preserve),enum=strip,enum=preserve"`
 	Dir             *string                 `json:"dir,omitempty" jsonschema:"description=The working directory to run the command in (default is CWD)"`
 	Shell           *exec.ShellPreference   `json:"shell,omitempty" jsonschema:"description=(cmd only) Indicates a preference for a shell for the provided cmd to be executed in on supported operating systems"`
+	ShellStrict     *bool                   `json:"shellStrict,omitempty" jsonschema:"description=(cmd only) Run the cmd in strict mode: POSIX shells stop at the first failing command (including within a pipeline where the shell supports pipefail) or unset variable and report the exit status while PowerShell enables strict mode and stops on failing native commands (default false)"`
+	ReportLine      *bool                   `json:"reportLine,omitempty" jsonschema:"description=(cmd only) Report the line of a multi-line POSIX shell cmd that failed by recording the line before each one runs (default true)"`
+	UntilOutput     string                  `json:"untilOutput,omitempty" jsonschema:"description=(cmd only) A regular expression checked against each line of output as it streams. Once a line matches the command is stopped and the action succeeds (with the matching line as its output)"`
+	Expect          []ActionExpect          `json:"expect,omitempty" jsonschema:"description=(cmd only) Prompts to answer by sending input to the command when its output matches a pattern (i.e. to accept a license or enter a passphrase)"`
 	SetVariables    []variables.Variable[T] `json:"setVariables,omitempty" jsonschema:"description=(onDeploy/cmd only) An array of variables to update with the output of the command. These variables will be available to all remaining actions and components in the package."`
 }
 
+// ActionExpect answers a prompt by sending input to a command whenever its output matches a pattern
+type ActionExpect struct {
+	Pattern string `json:"pattern" jsonschema:"description=A regular expression checked against the output of the command as it streams (prompts do not need to end in a newline to match),example=Passphrase:,example=Accept the license\\? \\[y/N\\]"`
+	Send    string `json:"send" jsonschema:"description=The input to send (followed by a newline) each time the pattern matches. Can reference variables (i.e. ${PASSPHRASE}) so secrets are not embedded in the task file"`
+}
+
 // ActionWait specifies a condition to wait for before continuing
 type ActionWait struct {
 	Cluster *ActionWaitCluster `json:"cluster,omitempty" jsonschema:"description=Wait for a condition to be met in the cluster before continuing. Only one of cluster or network can be specified."`
@@ -49,7 +74,17 @@ type ActionWaitCluster struct {
 
 // ActionWaitNetwork specifies a condition to wait for before continuing
 type ActionWaitNetwork struct {
-	Protocol string `json:"protocol" jsonschema:"description=The protocol to wait for,enum=tcp,enum=http,enum=https"`
-	Address  string `json:"address" jsonschema:"description=The address to wait for,example=localhost:8080,example=1.1.1.1"`
-	Code     int    `json:"code,omitempty" jsonschema:"description=The HTTP status code to wait for if using http or https,example=200,example=404"`
+	Protocol string             `json:"protocol" jsonschema:"description=The protocol to wait for,enum=tcp,enum=http,enum=https"`
+	Address  string             `json:"address" jsonschema:"description=The address to wait for,example=localhost:8080,example=1.1.1.1"`
+	Code     int                `json:"code,omitempty" jsonschema:"description=The HTTP status code to wait for if using http or https,example=200,example=404"`
+	Headers  map[string]string  `json:"headers,omitempty" jsonschema:"description=(http/https only) Headers to send with each request. Values can reference variables (i.e. ${TOKEN}) so secrets are not embedded in the task file"`
+	Body     string             `json:"body,omitempty" jsonschema:"description=(http/https only) A substring that the response body must contain"`
+	Backoff  *ActionWaitBackoff `json:"backoff,omitempty" jsonschema:"description=(http/https only) How long to wait between requests"`
+}
+
+// ActionWaitBackoff specifies how long to wait between the requests of an HTTP wait
+type ActionWaitBackoff struct {
+	Initial string  `json:"initial,omitempty" jsonschema:"description=How long to wait after the first request (default 1s),example=500ms,example=2s"`
+	Max     string  `json:"max,omitempty" jsonschema:"description=The longest to wait between requests (default 30s),example=10s,example=1m"`
+	Factor  float64 `json:"factor,omitempty" jsonschema:"description=What to multiply the time between requests by after each request (default 2)"`
 }
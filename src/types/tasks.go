@@ -10,18 +10,35 @@ import (
 
 // TasksFile represents the contents of a tasks file
 type TasksFile struct {
-	Includes  []map[string]string                                          `json:"includes,omitempty" jsonschema:"description=List of local task files to include"`
-	Variables []variables.InteractiveVariable[variables.ExtraVariableInfo] `json:"variables,omitempty" jsonschema:"description=Definitions and default values for variables used in run.yaml"`
-	Tasks     []Task                                                       `json:"tasks" jsonschema:"description=The list of tasks that can be run"`
+	Includes       []map[string]string                                          `json:"includes,omitempty" jsonschema:"description=List of local task files to include"`
+	Variables      []variables.InteractiveVariable[variables.ExtraVariableInfo] `json:"variables,omitempty" jsonschema:"description=Definitions and default values for variables used in run.yaml"`
+	Tasks          []Task                                                       `json:"tasks" jsonschema:"description=The list of tasks that can be run"`
+	TemplateDelims *TemplateDelims                                              `json:"templateDelims,omitempty" jsonschema:"description=Delimiters to use when templating inputs and variables into the actions of this file's tasks (default ${{ and }})"`
+}
+
+// TemplateDelims are the left and right delimiters that surround templates in task actions
+type TemplateDelims struct {
+	Left  string `json:"left" jsonschema:"description=The opening delimiter (prefix it with $ in an action to output it literally),example=[[,required"`
+	Right string `json:"right" jsonschema:"description=The closing delimiter,example=]],required"`
 }
 
 // Task represents a single task
 type Task struct {
-	Name        string                    `json:"name" jsonschema:"description=Name of the task"`
-	Description string                    `json:"description,omitempty" jsonschema:"description=Description of the task"`
-	Actions     []Action                  `json:"actions,omitempty" jsonschema:"description=Actions to take when running the task"`
-	Inputs      map[string]InputParameter `json:"inputs,omitempty" jsonschema:"description=Input parameters for the task"`
-	EnvPath     string                    `json:"envPath,omitempty" jsonschema:"description=Path to file containing environment variables"`
+	Name            string                    `json:"name" jsonschema:"description=Name of the task"`
+	Description     string                    `json:"description,omitempty" jsonschema:"description=Description of the task"`
+	Actions         []Action                  `json:"actions,omitempty" jsonschema:"description=Actions to take when running the task"`
+	Inputs          map[string]InputParameter `json:"inputs,omitempty" jsonschema:"description=Input parameters for the task"`
+	EnvPath         string                    `json:"envPath,omitempty" jsonschema:"description=Path to file containing environment variables"`
+	MaxTotalSeconds int                       `json:"maxTotalSeconds,omitempty" jsonschema:"description=Timeout in seconds for the task including any tasks it references (defaults to 0\\, no timeout)"`
+	Metadata        *TaskMetadata             `json:"metadata,omitempty" jsonschema:"description=Who owns the task and where to find help with it (shown when listing tasks and when the task fails)"`
+}
+
+// TaskMetadata describes who owns a task and where its documentation lives
+type TaskMetadata struct {
+	Owner   string `json:"owner,omitempty" jsonschema:"description=The person (or handle) that owns the task,example=@jane"`
+	Team    string `json:"team,omitempty" jsonschema:"description=The team that owns the task,example=platform"`
+	DocsURL string `json:"docsURL,omitempty" jsonschema:"description=Where the task is documented"`
+	Runbook string `json:"runbook,omitempty" jsonschema:"description=Where to find the runbook for when the task fails"`
 }
 
 // InputParameter represents a single input parameter for a task, to be used w/ `with`
@@ -36,8 +53,43 @@ type InputParameter struct {
 type Action struct {
 	*BaseAction[variables.ExtraVariableInfo] `json:",inline"`
 	TaskReference                            string            `json:"task,omitempty" jsonschema:"description=The task to run, mutually exclusive with cmd and wait"`
+	Uses                                     string            `json:"uses,omitempty" jsonschema:"description=Run a task from another file given as <location>:<task> (mutually exclusive with task and cmd) where the location is a file:// path relative to this file or an http(s) URL and the task can be followed by @sha256:<digest> to pin the file,example=file://./build.yaml:compile,example=https://example.com/tasks.yaml:deploy"`
 	With                                     map[string]string `json:"with,omitempty" jsonschema:"description=Input parameters to pass to the task,type=object"`
-	If                                       string            `json:"if,omitempty" jsonschema:"description=Conditional to determine if the action should run"`
+	If                                       string            `json:"if,omitempty" jsonschema:"description=Conditional to determine if the action should run: a boolean or a template expression that evaluates to true or false (i.e. ${{ eq .variables.ENV \"prod\" }}),oneof_type=boolean;string"`
+	Patch                                    *ActionPatch      `json:"patch,omitempty" jsonschema:"description=Merge or patch values into a YAML or JSON file\\, mutually exclusive with cmd\\, wait and task"`
+	Tunnel                                   *ActionTunnel     `json:"tunnel,omitempty" jsonschema:"description=Open an SSH tunnel (or SOCKS proxy) that stays open for the rest of the task\\, mutually exclusive with cmd\\, wait and task"`
+	Pause                                    *ActionPause      `json:"pause,omitempty" jsonschema:"description=Pause for a duration or until a time before moving on to the next action\\, mutually exclusive with cmd\\, wait and task"`
+	Artifacts                                []string          `json:"artifacts,omitempty" jsonschema:"description=Paths (or globs) of logs and other files to collect into the artifacts directory if the action fails so they are kept for debugging (relative to the action's dir),example=logs/*.log"`
+}
+
+// ActionPause describes a pause between actions (i.e. to give a system time to settle)
+type ActionPause struct {
+	Duration string `json:"duration,omitempty" jsonschema:"description=How long to pause for as a Go duration (mutually exclusive with until),example=30s,example=5m"`
+	Until    string `json:"until,omitempty" jsonschema:"description=An RFC 3339 timestamp to pause until (mutually exclusive with duration),example=2024-06-01T12:00:00Z"`
+	Reason   string `json:"reason,omitempty" jsonschema:"description=Why the pause is needed (logged when the pause starts)"`
+}
+
+// ActionTunnel describes an SSH tunnel that is opened through a host for the remainder of a task
+type ActionTunnel struct {
+	Via    string `json:"via" jsonschema:"description=The SSH destination to tunnel through (i.e. user@bastion or a Host from your SSH config),required"`
+	Local  int    `json:"local" jsonschema:"description=The local port to listen on (bound to 127.0.0.1),required"`
+	Remote string `json:"remote,omitempty" jsonschema:"description=The host:port to forward the local port to as seen from the via host (if empty a SOCKS proxy is opened on the local port instead),example=db:5432"`
+}
+
+// ActionPatch describes changes to apply to a YAML or JSON file
+type ActionPatch struct {
+	File   string               `json:"file" jsonschema:"description=The YAML or JSON file to patch (JSON if it ends in .json) relative to the action's dir,required"`
+	Output string               `json:"output,omitempty" jsonschema:"description=Where to write the patched file (default is to patch the file in place)"`
+	Merge  any                  `json:"merge,omitempty" jsonschema:"description=Values to deep merge into the file following RFC 7386 (JSON Merge Patch) where null removes a key"`
+	Ops    []JSONPatchOperation `json:"ops,omitempty" jsonschema:"description=RFC 6902 (JSON Patch) operations to apply to the file after any merge"`
+}
+
+// JSONPatchOperation is a single RFC 6902 (JSON Patch) operation
+type JSONPatchOperation struct {
+	Op    string `json:"op" jsonschema:"description=The operation to perform,enum=add,enum=remove,enum=replace,enum=move,enum=copy,enum=test"`
+	Path  string `json:"path" jsonschema:"description=The JSON pointer to operate on,example=/spec/replicas"`
+	From  string `json:"from,omitempty" jsonschema:"description=The JSON pointer to move or copy from"`
+	Value any    `json:"value" jsonschema:"description=The value to add\\, replace or test against (which can be null)"`
 }
 
 // TaskReference references the name of a task